@@ -36,13 +36,27 @@ func (f *MultiLineFormatter) Format(entry *log.Entry) ([]byte, error) {
 	if ok {
 		delete(entry.Data, "multiline")
 	}
+	redactEntry(entry)
 	res, err := f.TextFormatter.Format(entry)
 	if multiline, ok := multiline.(string); ok && multiline != "" {
-		res = append(res, []byte(multiline)...)
+		res = append(res, []byte(Redact(multiline))...)
 	}
 	return res, err
 }
 
+// redactEntry replaces the secrets in the message and the string or error fields of the log entry
+func redactEntry(entry *log.Entry) {
+	entry.Message = Redact(entry.Message)
+	for key, val := range entry.Data {
+		switch typedVal := val.(type) {
+		case string:
+			entry.Data[key] = Redact(typedVal)
+		case error:
+			entry.Data[key] = Redact(typedVal.Error())
+		}
+	}
+}
+
 type BufferedLogs struct {
 	Messages []string
 }
@@ -0,0 +1,69 @@
+package applog
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RedactedValue replaces the secrets in the logs, task results and errors
+const RedactedValue = "***"
+
+// MinSecretLength is the length of the shortest secret which is redacted, shorter values
+// e.g. "1" or "on" would garble unrelated output
+const MinSecretLength = 4
+
+var (
+	secretsMx sync.RWMutex
+	secrets   = map[string]bool{}
+)
+
+// AddSecrets registers the values which are redacted by Redact and in the log output,
+// the values shorter than MinSecretLength are ignored with a warning
+func AddSecrets(values ...string) {
+	shortSecrets := 0
+
+	secretsMx.Lock()
+	for _, value := range values {
+		switch {
+		case value == "":
+		case len(value) < MinSecretLength:
+			shortSecrets++
+		default:
+			secrets[value] = true
+		}
+	}
+	secretsMx.Unlock()
+
+	// the warning is logged after unlocking since the log formatter redacts the secrets too
+	if shortSecrets > 0 {
+		log.Warnf("%d secret values are shorter than %d characters, they won't be redacted", shortSecrets, MinSecretLength)
+	}
+}
+
+// Redact replaces the registered secrets in the text with RedactedValue, longer secrets are replaced first
+// so a secret which contains another one is not redacted partially
+func Redact(text string) string {
+	secretsMx.RLock()
+	defer secretsMx.RUnlock()
+
+	if len(secrets) == 0 || text == "" {
+		return text
+	}
+
+	sortedSecrets := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		sortedSecrets = append(sortedSecrets, secret)
+	}
+	sort.Slice(sortedSecrets, func(i, j int) bool {
+		return len(sortedSecrets[i]) > len(sortedSecrets[j])
+	})
+
+	for _, secret := range sortedSecrets {
+		text = strings.ReplaceAll(text, secret, RedactedValue)
+	}
+
+	return text
+}
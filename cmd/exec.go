@@ -6,7 +6,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	varsFiles  []string
+	rawVars    []string
+	secretVars []string
+)
+
 func init() {
+	addVarsFlags(exeCmd)
+	addVarsFlags(rootCmd)
 	rootCmd.AddCommand(exeCmd)
 }
 
@@ -21,8 +29,39 @@ var exeCmd = &cobra.Command{
 			args = []string{DefaultPath}
 		}
 
+		variables, err := loadVariables()
+		if err != nil {
+			return err
+		}
+
 		logrus.Debugf("will execute script %s", args[0])
 
-		return script.RunScript(args[0])
+		return script.RunScript(args[0], LocalFactsDir, variables, secretVars)
 	},
 }
+
+// addVarsFlags registers the variables flags, they are needed in the root command too since it runs scripts as exec does
+func addVarsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&varsFiles, "vars-file", nil, "yaml file with template variables, can be repeated, later files override former ones")
+	cmd.Flags().StringArrayVar(&rawVars, "var", nil, "template variable in key=value format, can be repeated, overrides values from vars files")
+	cmd.Flags().StringArrayVar(&secretVars, "secret-var", nil, "template variable which value is redacted in logs and results, can be repeated")
+}
+
+// loadVariables merges variables from vars files and command line, command line values win
+func loadVariables() (map[string]interface{}, error) {
+	variables, err := script.LoadVarsFiles(varsFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	cliVariables, err := script.ParseVarFlags(rawVars)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, val := range cliVariables {
+		variables[key] = val
+	}
+
+	return variables, nil
+}
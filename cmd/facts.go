@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var factsFormat = "yaml"
+
+func init() {
+	factsCmd.Flags().StringVarP(&factsFormat, "format", "f", factsFormat, "output format: yaml or json")
+	rootCmd.AddCommand(factsCmd)
+}
+
+var factsCmd = &cobra.Command{
+	Use:   "facts",
+	Short: "Prints system facts which are available as variables in script templates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		facts, err := utils.OSDataProvider{LocalFactsDir: LocalFactsDir}.GetTemplateVariables()
+		if err != nil {
+			return err
+		}
+
+		var output []byte
+		switch factsFormat {
+		case "yaml":
+			output, err = yaml.Marshal(facts)
+		case "json":
+			output, err = json.MarshalIndent(facts, "", "  ")
+		default:
+			return fmt.Errorf("unknown output format '%s', expected yaml or json", factsFormat)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(output))
+
+		return nil
+	},
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudradar-monitoring/tacoscript/applog"
+	"github.com/cloudradar-monitoring/tacoscript/script"
+	"github.com/spf13/cobra"
+)
+
+var graphFormat = script.GraphFormatDOT
+
+func init() {
+	addVarsFlags(graphCmd)
+	graphCmd.Flags().StringVarP(&graphFormat, "format", "f", graphFormat, "output format: dot, mermaid or json")
+	rootCmd.AddCommand(graphCmd)
+}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph {{PATH_TO_SCRIPT}}",
+	Short: "Prints the graph of requirements between scripts and their execution order",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if graphFormat != script.GraphFormatDOT && graphFormat != script.GraphFormatMermaid && graphFormat != script.GraphFormatJSON {
+			return fmt.Errorf("unknown output format '%s', expected dot, mermaid or json", graphFormat)
+		}
+
+		variables, err := loadVariables()
+		if err != nil {
+			return err
+		}
+
+		graph, err := script.BuildScriptGraph(args[0], LocalFactsDir, variables, secretVars)
+		if err != nil {
+			return err
+		}
+
+		switch graphFormat {
+		case script.GraphFormatMermaid:
+			fmt.Print(applog.Redact(graph.ToMermaid()))
+		case script.GraphFormatJSON:
+			output, err := json.MarshalIndent(graph, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(applog.Redact(string(output)))
+		default:
+			fmt.Print(applog.Redact(graph.ToDOT()))
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cloudradar-monitoring/tacoscript/applog"
+	"github.com/cloudradar-monitoring/tacoscript/script"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addVarsFlags(renderCmd)
+	rootCmd.AddCommand(renderCmd)
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render {{PATH_TO_SCRIPT}}",
+	Short: "Prints the script rendered with the template variables without executing it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		variables, err := loadVariables()
+		if err != nil {
+			return err
+		}
+
+		renderedScript, err := script.RenderScript(args[0], LocalFactsDir, variables, secretVars)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(applog.Redact(string(renderedScript)))
+
+		return nil
+	},
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/cloudradar-monitoring/tacoscript/applog"
+	io2 "github.com/cloudradar-monitoring/tacoscript/io"
+	"github.com/cloudradar-monitoring/tacoscript/utils"
 	"github.com/spf13/cobra"
 )
 
 var (
-	Verbose = false
-	rootCmd = &cobra.Command{
+	Verbose       = false
+	LocalFactsDir = utils.DefaultLocalFactsDir
+	rootCmd       = &cobra.Command{
 		Use:          "tacoscript",
 		Short:        "Tacoscript is a state-driven scripted task executor",
 		Args:         cobra.ArbitraryArgs,
@@ -20,6 +25,15 @@ var (
 func init() {
 	cobra.OnInitialize(initLog)
 	rootCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&LocalFactsDir, "facts-dir", LocalFactsDir, "directory with custom facts files, they are available in templates as .taco_local")
+
+	// the errors are printed by cobra, so the secrets in them are redacted here as in the logs
+	rootCmd.SetErr(io2.FuncWriter{
+		Callback: func(p []byte) (n int, err error) {
+			_, err = os.Stderr.Write([]byte(applog.Redact(string(p))))
+			return len(p), err
+		},
+	})
 }
 
 func initLog() {
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudradar-monitoring/tacoscript/applog"
+	"github.com/cloudradar-monitoring/tacoscript/script"
+	"github.com/spf13/cobra"
+)
+
+var validateFactsPath string
+
+func init() {
+	addVarsFlags(validateCmd)
+	validateCmd.Flags().StringVar(
+		&validateFactsPath,
+		"facts",
+		"",
+		"yaml or json file with facts to render the scripts with instead of the current host facts, e.g. the saved output of 'tacoscript facts'",
+	)
+	rootCmd.AddCommand(validateCmd)
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate {{PATH_TO_SCRIPT}}...",
+	Short: "Checks scripts for errors without executing them",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		variables, err := loadVariables()
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, scriptPath := range args {
+			err = script.ValidateScript(scriptPath, LocalFactsDir, validateFactsPath, variables, secretVars)
+			if err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "%s: %s\n", scriptPath, applog.Redact(err.Error()))
+				continue
+			}
+
+			fmt.Printf("%s: OK\n", scriptPath)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("validation failed for %d of %d scripts", failed, len(args))
+		}
+
+		return nil
+	},
+}
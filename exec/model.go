@@ -2,7 +2,9 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"io"
+	"os/exec"
 
 	"github.com/cloudradar-monitoring/tacoscript/conv"
 )
@@ -18,6 +20,8 @@ type Context struct {
 	Cmds         []string
 	Pids         []int
 	Shell        string
+	// HideOutput disables the debug logs of the commands output
+	HideOutput bool
 }
 
 func (c *Context) Copy() Context {
@@ -31,6 +35,7 @@ func (c *Context) Copy() Context {
 		Envs:         c.Envs,
 		Cmds:         c.Cmds,
 		Shell:        c.Shell,
+		HideOutput:   c.HideOutput,
 	}
 }
 
@@ -55,3 +60,9 @@ type RunError struct {
 func (re RunError) Error() string {
 	return re.Err.Error()
 }
+
+// HasExited is true if the command has run and exited with a non zero code, unlike a failure to start it
+func (re RunError) HasExited() bool {
+	var exitErr *exec.ExitError
+	return errors.As(re.Err, &exitErr)
+}
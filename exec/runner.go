@@ -171,7 +171,7 @@ func (sr SystemRunner) createCmd(rawCmd string, shellParam ShellParam, execConte
 	}
 
 	sr.setEnvs(cmd, execContext)
-	sr.setIO(cmd, execContext.StdoutWriter, execContext.StderrWriter)
+	sr.setIO(cmd, execContext)
 
 	return cmd, nil
 }
@@ -181,9 +181,13 @@ func (sr SystemRunner) setEnvs(cmd *exec.Cmd, execContext *Context) {
 		return
 	}
 
-	envs := execContext.Envs.ToEqualSignStrings()
-	logrus.Debugf("will set %d env variables: %s to command '%s'", len(envs), envs, cmd)
-	cmd.Env = append(os.Environ(), envs...)
+	envKeys := make([]string, 0, len(execContext.Envs))
+	for _, env := range execContext.Envs {
+		envKeys = append(envKeys, env.Key)
+	}
+	// only the keys are logged since the values might be secrets
+	logrus.Debugf("will set %d env variables: %s to command '%s'", len(envKeys), envKeys, cmd)
+	cmd.Env = append(os.Environ(), execContext.Envs.ToEqualSignStrings()...)
 }
 
 func (sr SystemRunner) setUser(cmd *exec.Cmd, execContext *Context) error {
@@ -258,8 +262,14 @@ func (sr SystemRunner) addCShellParamIfNeeded(shellParam *ShellParam) {
 	}
 }
 
-func (sr SystemRunner) setIO(cmd *exec.Cmd, stdOutWriter, stdErrWriter io.Writer) {
+func (sr SystemRunner) setIO(cmd *exec.Cmd, execContext *Context) {
 	logrus.Debugf("will set stdout and stderr to cmd '%s'", cmd)
+	if execContext.HideOutput {
+		cmd.Stdout = execContext.StdoutWriter
+		cmd.Stderr = execContext.StderrWriter
+		return
+	}
+
 	stdOutLoggedWriter := io2.FuncWriter{
 		Callback: func(p []byte) (n int, err error) {
 			logrus.Debugf("stdout capture: %s", string(p))
@@ -272,8 +282,8 @@ func (sr SystemRunner) setIO(cmd *exec.Cmd, stdOutWriter, stdErrWriter io.Writer
 			return len(p), nil
 		},
 	}
-	cmd.Stdout = io.MultiWriter(stdOutLoggedWriter, stdOutWriter)
-	cmd.Stderr = io.MultiWriter(stdErrLoggedWriter, stdErrWriter)
+	cmd.Stdout = io.MultiWriter(stdOutLoggedWriter, execContext.StdoutWriter)
+	cmd.Stderr = io.MultiWriter(stdErrLoggedWriter, execContext.StderrWriter)
 }
 
 func (sr SystemRunner) parseShellParam(rawShell string) ShellParam {
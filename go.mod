@@ -4,7 +4,6 @@ go 1.14
 
 require (
 	github.com/elastic/go-sysinfo v1.7.0
-	github.com/elliotchance/orderedmap v1.3.0
 	github.com/goftp/file-driver v0.0.0-20180502053751-5d604a0fc0c9
 	github.com/goftp/server v0.0.0-20200708154336-f64f7c2d8a42
 	github.com/jlaffaye/ftp v0.0.0-20200812143550-39e3779af0db // indirect
@@ -30,10 +30,29 @@ func (ecb OsPackageManagerCmdProvider) GetManagementCmds(t *tasks.PkgTask) (*Man
 	}
 
 	return &ManagementCmds{
-		VersionCmd:    "brew --version",
-		UpgradeCmd:    "brew update",
-		InstallCmds:   []string{fmt.Sprintf("brew install %s", strings.Join(rawInstallCmds, " "))},
-		UninstallCmds: []string{fmt.Sprintf("brew uninstall %s", strings.Join(rawCmds, " "))},
-		UpgradeCmds:   []string{fmt.Sprintf("brew upgrade %s", strings.Join(rawCmds, " "))},
+		VersionCmd:       "brew --version",
+		RefreshCmd:       "brew update",
+		ListInstalledCmd: "brew list --versions",
+		InstallCmds:      []string{fmt.Sprintf("brew install %s", strings.Join(rawInstallCmds, " "))},
+		UninstallCmds:    []string{fmt.Sprintf("brew uninstall %s", strings.Join(rawCmds, " "))},
+		UpgradeCmds:      []string{fmt.Sprintf("brew upgrade %s", strings.Join(rawCmds, " "))},
+	}, nil
+}
+
+func BuildRepoDefinitionProviders() ([]RepoDefinitionProvider, error) {
+	return []RepoDefinitionProvider{
+		BrewTapProvider{},
+	}, nil
+}
+
+type BrewTapProvider struct{}
+
+func (btp BrewTapProvider) GetRepoDefinition(t *tasks.PkgRepoTask) (*RepoDefinition, error) {
+	return &RepoDefinition{
+		VersionCmd: "brew --version",
+		RefreshCmd: "brew update",
+		ListCmd:    "brew tap",
+		AddCmds:    []string{strings.TrimSpace(fmt.Sprintf("brew tap %s %s", t.Name, t.Repo))},
+		RemoveCmds: []string{fmt.Sprintf("brew untap %s", t.Name)},
 	}, nil
 }
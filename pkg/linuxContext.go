@@ -28,9 +28,22 @@ var linuxOSBuilderMap = map[string][]ManagementCmdsProvider{
 	},
 }
 
+var linuxRepoProvidersMap = map[string][]RepoDefinitionProvider{
+	"ubuntu": {
+		AptRepoProvider{},
+	},
+	"debian": {
+		AptRepoProvider{},
+	},
+	"centos": {
+		DnfRepoProvider{},
+		YumRepoProvider{},
+	},
+}
+
 func init() {
 	osDataProvider := utils.OSDataProvider{}
-	templateVariables, err := osDataProvider.GetTemplateVariables()
+	templateVariables, err := osDataProvider.GetOSVariables()
 	if err != nil {
 		logrus.Error(err)
 		return
@@ -47,6 +60,15 @@ func BuildManagementCmdsProviders() ([]ManagementCmdsProvider, error) {
 	return linuxSpecificProviders, nil
 }
 
+func BuildRepoDefinitionProviders() ([]RepoDefinitionProvider, error) {
+	linuxSpecificProviders, ok := linuxRepoProvidersMap[osPlatform]
+	if !ok {
+		return []RepoDefinitionProvider{}, fmt.Errorf("unsupported linux version %s for package repository management", osPlatform)
+	}
+
+	return linuxSpecificProviders, nil
+}
+
 type AptCmdsProvider struct{}
 
 func (ecb AptCmdsProvider) GetManagementCmds(t *tasks.PkgTask) (*ManagementCmds, error) {
@@ -54,11 +76,12 @@ func (ecb AptCmdsProvider) GetManagementCmds(t *tasks.PkgTask) (*ManagementCmds,
 	rawInstallCmds := buildInstallCmds(rawCmds, t.Version)
 
 	return &ManagementCmds{
-		VersionCmd:    "apt --version",
-		UpgradeCmd:    "apt update",
-		InstallCmds:   []string{fmt.Sprintf("apt install -y %s", strings.Join(rawInstallCmds, " "))},
-		UninstallCmds: []string{fmt.Sprintf("apt remove -y %s", strings.Join(rawCmds, " "))},
-		UpgradeCmds:   []string{fmt.Sprintf("apt upgrade -y %s", strings.Join(rawCmds, " "))},
+		VersionCmd:       "apt --version",
+		RefreshCmd:       "apt update",
+		ListInstalledCmd: `dpkg-query -W -f=${Package}\t${Version}\n`,
+		InstallCmds:      []string{fmt.Sprintf("apt install -y %s", strings.Join(rawInstallCmds, " "))},
+		UninstallCmds:    []string{fmt.Sprintf("apt remove -y %s", strings.Join(rawCmds, " "))},
+		UpgradeCmds:      []string{fmt.Sprintf("apt upgrade -y %s", strings.Join(rawCmds, " "))},
 	}, nil
 }
 
@@ -69,11 +92,12 @@ func (ecb AptGetCmdsProvider) GetManagementCmds(t *tasks.PkgTask) (*ManagementCm
 	rawInstallCmds := buildInstallCmds(rawCmds, t.Version)
 
 	return &ManagementCmds{
-		VersionCmd:    "apt-get --version",
-		UpgradeCmd:    "apt-get update",
-		InstallCmds:   []string{fmt.Sprintf("apt-get install -y %s", strings.Join(rawInstallCmds, " "))},
-		UninstallCmds: []string{fmt.Sprintf("apt-get remove -y %s", strings.Join(rawCmds, " "))},
-		UpgradeCmds:   []string{fmt.Sprintf("apt-get upgrade -y %s", strings.Join(rawCmds, " "))},
+		VersionCmd:       "apt-get --version",
+		RefreshCmd:       "apt-get update",
+		ListInstalledCmd: `dpkg-query -W -f=${Package}\t${Version}\n`,
+		InstallCmds:      []string{fmt.Sprintf("apt-get install -y %s", strings.Join(rawInstallCmds, " "))},
+		UninstallCmds:    []string{fmt.Sprintf("apt-get remove -y %s", strings.Join(rawCmds, " "))},
+		UpgradeCmds:      []string{fmt.Sprintf("apt-get upgrade -y %s", strings.Join(rawCmds, " "))},
 	}, nil
 }
 
@@ -84,11 +108,12 @@ func (ecb YumCmdsProvider) GetManagementCmds(t *tasks.PkgTask) (*ManagementCmds,
 	rawInstallCmds := buildInstallCmds(rawCmds, t.Version)
 
 	return &ManagementCmds{
-		VersionCmd:    "yum --version",
-		UpgradeCmd:    "yum update -y",
-		InstallCmds:   []string{fmt.Sprintf("yum install -y %s", strings.Join(rawInstallCmds, " "))},
-		UninstallCmds: []string{fmt.Sprintf("yum remove -y %s", strings.Join(rawCmds, " "))},
-		UpgradeCmds:   []string{fmt.Sprintf("yum upgrade -y %s", strings.Join(rawCmds, " "))},
+		VersionCmd:       "yum --version",
+		RefreshCmd:       "yum makecache",
+		ListInstalledCmd: `rpm -qa --queryformat %{NAME}\t%{VERSION}-%{RELEASE}\n`,
+		InstallCmds:      []string{fmt.Sprintf("yum install -y %s", strings.Join(rawInstallCmds, " "))},
+		UninstallCmds:    []string{fmt.Sprintf("yum remove -y %s", strings.Join(rawCmds, " "))},
+		UpgradeCmds:      []string{fmt.Sprintf("yum upgrade -y %s", strings.Join(rawCmds, " "))},
 	}, nil
 }
 
@@ -99,14 +124,33 @@ func (ecb DnfCmdsProvider) GetManagementCmds(t *tasks.PkgTask) (*ManagementCmds,
 	rawInstallCmds := buildInstallCmds(rawCmds, t.Version)
 
 	return &ManagementCmds{
-		VersionCmd:    "dnf --version",
-		UpgradeCmd:    "dnf update -y",
-		InstallCmds:   []string{fmt.Sprintf("dnf install -y %s", strings.Join(rawInstallCmds, " "))},
-		UninstallCmds: []string{fmt.Sprintf("dnf remove -y %s", strings.Join(rawCmds, " "))},
-		UpgradeCmds:   []string{fmt.Sprintf("dnf upgrade -y %s", strings.Join(rawCmds, " "))},
+		VersionCmd:       "dnf --version",
+		RefreshCmd:       "dnf makecache",
+		ListInstalledCmd: `rpm -qa --queryformat %{NAME}\t%{VERSION}-%{RELEASE}\n`,
+		InstallCmds:      []string{fmt.Sprintf("dnf install -y %s", strings.Join(rawInstallCmds, " "))},
+		UninstallCmds:    []string{fmt.Sprintf("dnf remove -y %s", strings.Join(rawCmds, " "))},
+		UpgradeCmds:      []string{fmt.Sprintf("dnf upgrade -y %s", strings.Join(rawCmds, " "))},
 	}, nil
 }
 
+type AptRepoProvider struct{}
+
+func (arp AptRepoProvider) GetRepoDefinition(t *tasks.PkgRepoTask) (*RepoDefinition, error) {
+	return buildAptRepoDefinition(t, "apt-get --version", "apt-get update")
+}
+
+type YumRepoProvider struct{}
+
+func (yrp YumRepoProvider) GetRepoDefinition(t *tasks.PkgRepoTask) (*RepoDefinition, error) {
+	return buildYumRepoDefinition(t, "yum --version", "yum makecache")
+}
+
+type DnfRepoProvider struct{}
+
+func (drp DnfRepoProvider) GetRepoDefinition(t *tasks.PkgRepoTask) (*RepoDefinition, error) {
+	return buildYumRepoDefinition(t, "dnf --version", "dnf makecache")
+}
+
 func buildInstallCmds(rawCmds []string, version string) []string {
 	rawInstallCmds := make([]string, 0, len(rawCmds))
 	if version == "" {
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
 
 	"github.com/cloudradar-monitoring/tacoscript/conv"
 	"github.com/cloudradar-monitoring/tacoscript/exec"
@@ -12,11 +15,12 @@ import (
 )
 
 type ManagementCmds struct {
-	VersionCmd    string
-	UpgradeCmd    string
-	InstallCmds   []string
-	UninstallCmds []string
-	UpgradeCmds   []string
+	VersionCmd       string
+	RefreshCmd       string
+	ListInstalledCmd string
+	InstallCmds      []string
+	UninstallCmds    []string
+	UpgradeCmds      []string
 }
 
 type ManagementCmdsProvider interface {
@@ -26,9 +30,12 @@ type ManagementCmdsProvider interface {
 type PackageTaskManager struct {
 	Runner                     exec.Runner
 	PackageManagerCmdProviders []ManagementCmdsProvider
+	RefreshTracker             *RefreshTracker
 }
 
-func (pm PackageTaskManager) ExecuteTask(ctx context.Context, t *tasks.PkgTask) (output string, err error) {
+// ExecuteTask installs, removes or upgrades the packages of the task, isChanged is true if the installed packages
+// or their versions differ after the task, if they cannot be listed the successful task is considered as changed
+func (pm PackageTaskManager) ExecuteTask(ctx context.Context, t *tasks.PkgTask) (isChanged bool, output string, err error) {
 	if len(pm.PackageManagerCmdProviders) == 0 {
 		err = fmt.Errorf("no package manager providers for the current OS ")
 		return
@@ -38,7 +45,7 @@ func (pm PackageTaskManager) ExecuteTask(ctx context.Context, t *tasks.PkgTask)
 	for _, managementCmdProvider := range pm.PackageManagerCmdProviders {
 		managementCmds, err = managementCmdProvider.GetManagementCmds(t)
 		if err != nil {
-			return "", err
+			return false, "", err
 		}
 
 		logrus.Debugf("will execute version command %s to check if package manager is installed", managementCmds.VersionCmd)
@@ -58,20 +65,89 @@ func (pm PackageTaskManager) ExecuteTask(ctx context.Context, t *tasks.PkgTask)
 		return
 	}
 
+	installedBefore, listErr := pm.listInstalledPackages(ctx, t, managementCmds)
+
 	switch t.ActionType {
 	case tasks.ActionInstall:
 		output, err = pm.installPackages(ctx, t, managementCmds)
-		return
 	case tasks.ActionUninstall:
 		output, err = pm.uninstallPackages(ctx, t, managementCmds)
-		return
 	case tasks.ActionUpdate:
 		output, err = pm.updatePackages(ctx, t, managementCmds)
-		return
 	default:
 		err = fmt.Errorf("unknown action type '%v' for task %s", t.ActionType, t.TypeName)
-		return
 	}
+	if err != nil {
+		return false, output, err
+	}
+
+	var installedAfter map[string]string
+	if listErr == nil {
+		installedAfter, listErr = pm.listInstalledPackages(ctx, t, managementCmds)
+	}
+	if listErr != nil {
+		logrus.Debugf("cannot compare installed packages: %v, will consider the task '%s' as changed", listErr, t.Path)
+		return true, output, nil
+	}
+
+	return !reflect.DeepEqual(installedBefore, installedAfter), output, nil
+}
+
+// GetInstalledPackages gives installed packages with their versions using the first available package manager
+func (pm PackageTaskManager) GetInstalledPackages(ctx context.Context) (map[string]string, error) {
+	if len(pm.PackageManagerCmdProviders) == 0 {
+		return nil, fmt.Errorf("no package manager providers for the current OS ")
+	}
+
+	t := &tasks.PkgTask{}
+	var err error
+	for _, managementCmdProvider := range pm.PackageManagerCmdProviders {
+		var managementCmds *ManagementCmds
+		managementCmds, err = managementCmdProvider.GetManagementCmds(t)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = pm.run(ctx, t, managementCmds.VersionCmd)
+		if err != nil {
+			continue
+		}
+
+		return pm.listInstalledPackages(ctx, t, managementCmds)
+	}
+
+	return nil, err
+}
+
+func (pm PackageTaskManager) listInstalledPackages(ctx context.Context, t *tasks.PkgTask, mngtCmds *ManagementCmds) (map[string]string, error) {
+	if mngtCmds.ListInstalledCmd == "" {
+		return nil, fmt.Errorf("listing of installed packages is not supported by '%s'", mngtCmds.VersionCmd)
+	}
+
+	logrus.Debugf("will list installed packages by executing %s", mngtCmds.ListInstalledCmd)
+	output, err := pm.run(ctx, t, mngtCmds.ListInstalledCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseInstalledPackages(output), nil
+}
+
+// parseInstalledPackages reads lines where the package name is followed by its version,
+// separated by spaces, tabs or the '|' sign
+func parseInstalledPackages(output string) map[string]string {
+	res := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '|'
+		})
+		if len(fields) < 2 {
+			continue
+		}
+		res[fields[0]] = fields[1]
+	}
+
+	return res
 }
 
 func (pm PackageTaskManager) installPackages(ctx context.Context, t *tasks.PkgTask, mngtCmds *ManagementCmds) (output string, err error) {
@@ -107,8 +183,22 @@ func (pm PackageTaskManager) updatePkgManagerIfNeeded(
 		return
 	}
 
-	logrus.Debugf("will update package manager: %s", mngtCmds.UpgradeCmd)
-	output, err = pm.run(ctx, t, mngtCmds.UpgradeCmd)
+	if mngtCmds.RefreshCmd == "" {
+		logrus.Debug("package manager has no update command, will skip it")
+		return
+	}
+
+	if pm.RefreshTracker != nil && !pm.RefreshTracker.ShouldRefresh(mngtCmds.RefreshCmd, t.RefreshInterval) {
+		logrus.Debugf("will skip package manager update: %s", mngtCmds.RefreshCmd)
+		return
+	}
+
+	logrus.Debugf("will update package manager: %s", mngtCmds.RefreshCmd)
+	output, err = pm.run(ctx, t, mngtCmds.RefreshCmd)
+
+	if err == nil && pm.RefreshTracker != nil {
+		pm.RefreshTracker.MarkRefreshed(mngtCmds.RefreshCmd)
+	}
 
 	return
 }
@@ -119,9 +209,13 @@ func (pm PackageTaskManager) run(ctx context.Context, t *tasks.PkgTask, rawCmds
 		Ctx:          ctx,
 		StdoutWriter: &stdoutBuf,
 		StderrWriter: &stderrBuf,
+		WorkingDir:   t.WorkingDir,
+		User:         t.User,
 		Path:         t.Path,
+		Envs:         t.Envs,
 		Cmds:         rawCmds,
 		Shell:        t.Shell,
+		HideOutput:   t.IsOutputHidden(),
 	}
 
 	err = pm.Runner.Run(execCtx)
@@ -130,11 +224,13 @@ func (pm PackageTaskManager) run(ctx context.Context, t *tasks.PkgTask, rawCmds
 		logrus.Debugf("Cmds %s success", conv.ConvertSourceToJSONStrIfPossible(rawCmds))
 	}
 
-	logrus.Debugf(
-		"stdOut: %s, stdErr: %s",
-		stderrBuf.String(),
-		stdoutBuf.String(),
-	)
+	if !t.IsOutputHidden() {
+		logrus.Debugf(
+			"stdOut: %s, stdErr: %s",
+			stderrBuf.String(),
+			stdoutBuf.String(),
+		)
+	}
 
 	output = stderrBuf.String() + stdoutBuf.String()
 
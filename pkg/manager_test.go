@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/cloudradar-monitoring/tacoscript/conv"
 	"github.com/cloudradar-monitoring/tacoscript/exec"
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
 	"github.com/stretchr/testify/assert"
@@ -26,11 +27,12 @@ func (ecb MockedOsPackageManagerCmdProvider) GetManagementCmds(t *tasks.PkgTask)
 	}
 
 	return &ManagementCmds{
-		VersionCmd:    "mpmb --version",
-		UpgradeCmd:    "mpmb upgrade",
-		InstallCmds:   []string{fmt.Sprintf("mpmb install %s%s", versionStr, strings.Join(rawCmds, " "))},
-		UninstallCmds: []string{fmt.Sprintf("mpmb uninstall %s", strings.Join(rawCmds, " "))},
-		UpgradeCmds:   []string{fmt.Sprintf("mpmb update %s", strings.Join(rawCmds, " "))},
+		VersionCmd:       "mpmb --version",
+		RefreshCmd:       "mpmb refresh",
+		ListInstalledCmd: "mpmb list",
+		InstallCmds:      []string{fmt.Sprintf("mpmb install %s%s", versionStr, strings.Join(rawCmds, " "))},
+		UninstallCmds:    []string{fmt.Sprintf("mpmb uninstall %s", strings.Join(rawCmds, " "))},
+		UpgradeCmds:      []string{fmt.Sprintf("mpmb update %s", strings.Join(rawCmds, " "))},
 	}, ecb.ErrToGive
 }
 
@@ -57,10 +59,14 @@ func TestTaskExecution(t *testing.T) {
 				ActionType: tasks.ActionInstall,
 				NamedTask:  tasks.NamedTask{Name: "vim"},
 				Path:       "somePath",
-				Shell:      "sh",
+				CmdContext: tasks.CmdContext{
+					Shell:      "sh",
+					WorkingDir: "/tmp",
+					Envs:       conv.KeyValues{{Key: "LANG", Value: "C"}},
+				},
 			},
 			ExpectedOutput: "some stdout",
-			ExpectedCmds:   []string{"mpmb --version", "mpmb install vim"},
+			ExpectedCmds:   []string{"mpmb --version", "mpmb list", "mpmb install vim", "mpmb list"},
 		},
 		{
 			Name: "single_name_install_version_success",
@@ -77,7 +83,7 @@ func TestTaskExecution(t *testing.T) {
 				Version:    "1.1.0",
 			},
 			ExpectedOutput: "some stderr",
-			ExpectedCmds:   []string{"mpmb --version", "mpmb install --version 1.1.0 vim"},
+			ExpectedCmds:   []string{"mpmb --version", "mpmb list", "mpmb install --version 1.1.0 vim", "mpmb list"},
 		},
 		{
 			Name: "multiple_name_install_success",
@@ -88,7 +94,7 @@ func TestTaskExecution(t *testing.T) {
 				ActionType: tasks.ActionInstall,
 				NamedTask:  tasks.NamedTask{Names: []string{"vim", "nano"}},
 			},
-			ExpectedCmds: []string{"mpmb --version", "mpmb install vim nano"},
+			ExpectedCmds: []string{"mpmb --version", "mpmb list", "mpmb install vim nano", "mpmb list"},
 		},
 		{
 			Name: "multiple_name_uninstall_success",
@@ -99,7 +105,7 @@ func TestTaskExecution(t *testing.T) {
 				ActionType: tasks.ActionUninstall,
 				NamedTask:  tasks.NamedTask{Names: []string{"vim", "nano"}, Name: "mc"},
 			},
-			ExpectedCmds: []string{"mpmb --version", "mpmb uninstall mc vim nano"},
+			ExpectedCmds: []string{"mpmb --version", "mpmb list", "mpmb uninstall mc vim nano", "mpmb list"},
 		},
 		{
 			Name: "multiple_name_update_success",
@@ -110,7 +116,7 @@ func TestTaskExecution(t *testing.T) {
 				ActionType: tasks.ActionUpdate,
 				NamedTask:  tasks.NamedTask{Names: []string{"vim", "nano"}},
 			},
-			ExpectedCmds: []string{"mpmb --version", "mpmb update vim nano"},
+			ExpectedCmds: []string{"mpmb --version", "mpmb list", "mpmb update vim nano", "mpmb list"},
 		},
 		{
 			Name: "non_existing_pkg_manager",
@@ -135,7 +141,7 @@ func TestTaskExecution(t *testing.T) {
 				ActionType:    tasks.ActionUpdate,
 				NamedTask:     tasks.NamedTask{Names: []string{"vim", "nano"}},
 			},
-			ExpectedCmds: []string{"mpmb --version", "mpmb upgrade", "mpmb update vim nano"},
+			ExpectedCmds: []string{"mpmb --version", "mpmb refresh", "mpmb list", "mpmb update vim nano", "mpmb list"},
 		},
 		{
 			Name: "invalid_pkg_action_type",
@@ -147,7 +153,7 @@ func TestTaskExecution(t *testing.T) {
 				ActionType: 0,
 			},
 			ExpectedErrStr: "unknown action type '0' for task some uknown type name",
-			ExpectedCmds:   []string{"mpmb --version"},
+			ExpectedCmds:   []string{"mpmb --version", "mpmb list"},
 		},
 		{
 			Name: "build_cmd_error",
@@ -180,9 +186,11 @@ func TestTaskExecution(t *testing.T) {
 				},
 			}
 
-			output, err := mngr.ExecuteTask(context.Background(), tc.Task)
+			isChanged, output, err := mngr.ExecuteTask(context.Background(), tc.Task)
 
 			assert.Equal(t, tc.ExpectedOutput, output)
+			// the installed packages are listed by the same mock, so they never differ
+			assert.False(t, isChanged)
 
 			if tc.ExpectedErrStr != "" {
 				assert.EqualError(t, err, tc.ExpectedErrStr)
@@ -194,9 +202,130 @@ func TestTaskExecution(t *testing.T) {
 			for _, execContext := range tc.Runner.GivenExecContexts {
 				assert.Equal(t, tc.Task.Path, execContext.Path)
 				assert.Equal(t, tc.Task.Shell, execContext.Shell)
+				assert.Equal(t, tc.Task.WorkingDir, execContext.WorkingDir)
+				assert.Equal(t, tc.Task.User, execContext.User)
+				assert.Equal(t, tc.Task.Envs, execContext.Envs)
 				actualCmds = append(actualCmds, execContext.Cmds...)
 			}
 			assert.Equal(t, tc.ExpectedCmds, actualCmds)
 		})
 	}
 }
+
+func TestGetInstalledPackages(t *testing.T) {
+	runner := &exec.RunnerMock{
+		RunOutputCallback: func(stdOutWriter, stdErrWriter io.Writer) {
+			_, err := stdOutWriter.Write([]byte("vim\t2:8.1.2269\nnginx 1.18.0 1.17.0\ngit|2.25.1\n\n"))
+			assert.NoError(t, err)
+		},
+	}
+
+	mngr := PackageTaskManager{
+		Runner: runner,
+		PackageManagerCmdProviders: []ManagementCmdsProvider{
+			&MockedOsPackageManagerCmdProvider{},
+		},
+	}
+
+	pkgs, err := mngr.GetInstalledPackages(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"vim":   "2:8.1.2269",
+		"nginx": "1.18.0",
+		"git":   "2.25.1",
+	}, pkgs)
+
+	actualCmds := make([]string, 0, len(runner.GivenExecContexts))
+	for _, execContext := range runner.GivenExecContexts {
+		actualCmds = append(actualCmds, execContext.Cmds...)
+	}
+	assert.Equal(t, []string{"mpmb --version", "mpmb list"}, actualCmds)
+}
+
+// cmdOutputsRunnerMock gives the next output from Outputs for each executed command
+type cmdOutputsRunnerMock struct {
+	Outputs   map[string][]string
+	GivenCmds []string
+}
+
+func (rm *cmdOutputsRunnerMock) Run(execContext *exec.Context) error {
+	for _, cmd := range execContext.Cmds {
+		rm.GivenCmds = append(rm.GivenCmds, cmd)
+		outputs := rm.Outputs[cmd]
+		if len(outputs) == 0 {
+			continue
+		}
+		if _, err := execContext.StdoutWriter.Write([]byte(outputs[0])); err != nil {
+			return err
+		}
+		rm.Outputs[cmd] = outputs[1:]
+	}
+
+	return nil
+}
+
+func TestTaskExecutionChanges(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		Task            *tasks.PkgTask
+		ListOutputs     []string
+		ExpectedChanged bool
+	}{
+		{
+			Name: "already_installed_package",
+			Task: &tasks.PkgTask{
+				ActionType: tasks.ActionInstall,
+				NamedTask:  tasks.NamedTask{Name: "vim"},
+			},
+			ListOutputs:     []string{"vim\t2:8.1.2269\n", "vim\t2:8.1.2269\n"},
+			ExpectedChanged: false,
+		},
+		{
+			Name: "installed_package",
+			Task: &tasks.PkgTask{
+				ActionType: tasks.ActionInstall,
+				NamedTask:  tasks.NamedTask{Name: "vim"},
+			},
+			ListOutputs:     []string{"git\t2.25.1\n", "git\t2.25.1\nvim\t2:8.1.2269\n"},
+			ExpectedChanged: true,
+		},
+		{
+			Name: "upgraded_package",
+			Task: &tasks.PkgTask{
+				ActionType: tasks.ActionUpdate,
+				NamedTask:  tasks.NamedTask{Name: "vim"},
+			},
+			ListOutputs:     []string{"vim\t2:8.1.2269\n", "vim\t2:8.2.0716\n"},
+			ExpectedChanged: true,
+		},
+		{
+			Name: "missing_package_is_not_removed",
+			Task: &tasks.PkgTask{
+				ActionType: tasks.ActionUninstall,
+				NamedTask:  tasks.NamedTask{Name: "vim"},
+			},
+			ListOutputs:     []string{"git\t2.25.1\n", "git\t2.25.1\n"},
+			ExpectedChanged: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			runner := &cmdOutputsRunnerMock{
+				Outputs: map[string][]string{"mpmb list": tc.ListOutputs},
+			}
+			mngr := PackageTaskManager{
+				Runner: runner,
+				PackageManagerCmdProviders: []ManagementCmdsProvider{
+					&MockedOsPackageManagerCmdProvider{},
+				},
+			}
+
+			isChanged, _, err := mngr.ExecuteTask(context.Background(), tc.Task)
+			assert.NoError(tt, err)
+			assert.Equal(tt, tc.ExpectedChanged, isChanged)
+			assert.Len(tt, runner.GivenCmds, 4)
+		})
+	}
+}
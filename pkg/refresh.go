@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var nonFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// sameIndexRefreshCmds maps the refresh commands which update the same package index to one of them,
+// e.g. the apt package tasks refresh with apt while the apt repositories refresh with apt-get
+var sameIndexRefreshCmds = map[string]string{
+	"apt-get update": "apt update",
+}
+
+// RefreshTracker makes sure that the package index of a package manager is refreshed only once per run,
+// optionally it remembers the time of the last refresh in the state dir, so that refresh intervals work across runs
+type RefreshTracker struct {
+	StateDir string
+
+	mu            sync.Mutex
+	refreshedCmds map[string]bool
+}
+
+func NewRefreshTracker(stateDir string) *RefreshTracker {
+	return &RefreshTracker{
+		StateDir:      stateDir,
+		refreshedCmds: map[string]bool{},
+	}
+}
+
+// DefaultRefreshStateDir gives a folder where the refresh timestamps are stored between runs
+func DefaultRefreshStateDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+
+	return filepath.Join(cacheDir, "tacoscript", "pkg-refresh")
+}
+
+func (rt *RefreshTracker) ShouldRefresh(refreshCmd string, interval time.Duration) bool {
+	refreshCmd = indexRefreshCmd(refreshCmd)
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if rt.refreshedCmds[refreshCmd] {
+		logrus.Debugf("'%s' was already executed during this run", refreshCmd)
+		return false
+	}
+
+	if interval <= 0 || rt.StateDir == "" {
+		return true
+	}
+
+	lastRefresh, ok := rt.readLastRefreshTime(refreshCmd)
+	if !ok {
+		return true
+	}
+
+	if time.Since(lastRefresh) < interval {
+		logrus.Debugf("'%s' was executed at %s which is within the refresh interval of %v", refreshCmd, lastRefresh, interval)
+		return false
+	}
+
+	return true
+}
+
+func (rt *RefreshTracker) MarkRefreshed(refreshCmd string) {
+	refreshCmd = indexRefreshCmd(refreshCmd)
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.refreshedCmds[refreshCmd] = true
+
+	if rt.StateDir == "" {
+		return
+	}
+
+	err := os.MkdirAll(rt.StateDir, 0700)
+	if err != nil {
+		logrus.Warnf("failed to create refresh state dir '%s': %v", rt.StateDir, err)
+		return
+	}
+
+	stateFile := rt.stateFilePath(refreshCmd)
+	err = ioutil.WriteFile(stateFile, []byte(time.Now().Format(time.RFC3339)), 0600)
+	if err != nil {
+		logrus.Warnf("failed to write refresh state to '%s': %v", stateFile, err)
+	}
+}
+
+func (rt *RefreshTracker) readLastRefreshTime(refreshCmd string) (time.Time, bool) {
+	stateFile := rt.stateFilePath(refreshCmd)
+	data, err := ioutil.ReadFile(stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Warnf("failed to read refresh state from '%s': %v", stateFile, err)
+		}
+		return time.Time{}, false
+	}
+
+	lastRefresh, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		logrus.Warnf("invalid refresh state in '%s': %v", stateFile, err)
+		return time.Time{}, false
+	}
+
+	return lastRefresh, true
+}
+
+func (rt *RefreshTracker) stateFilePath(refreshCmd string) string {
+	return filepath.Join(rt.StateDir, nonFileNameChars.ReplaceAllString(refreshCmd, "_"))
+}
+
+func indexRefreshCmd(refreshCmd string) string {
+	if sameIndexCmd, ok := sameIndexRefreshCmds[refreshCmd]; ok {
+		return sameIndexCmd
+	}
+
+	return refreshCmd
+}
@@ -0,0 +1,190 @@
+package pkg
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudradar-monitoring/tacoscript/exec"
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTrackerWithinRun(t *testing.T) {
+	tracker := NewRefreshTracker("")
+
+	assert.True(t, tracker.ShouldRefresh("apt update", 0))
+	tracker.MarkRefreshed("apt update")
+	assert.False(t, tracker.ShouldRefresh("apt update", 0))
+	assert.True(t, tracker.ShouldRefresh("yum makecache", 0))
+}
+
+func TestRefreshTrackerInterval(t *testing.T) {
+	stateDir, err := ioutil.TempDir("", "tacoscript-refresh")
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(stateDir)
+
+	NewRefreshTracker(stateDir).MarkRefreshed("apt update")
+
+	nextRunTracker := NewRefreshTracker(stateDir)
+	assert.False(t, nextRunTracker.ShouldRefresh("apt update", time.Hour))
+	assert.True(t, nextRunTracker.ShouldRefresh("apt update", 0))
+	assert.True(t, nextRunTracker.ShouldRefresh("dnf makecache", time.Hour))
+
+	oldRefreshTime := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	err = ioutil.WriteFile(filepath.Join(stateDir, "apt_update"), []byte(oldRefreshTime), 0600)
+	assert.NoError(t, err)
+	assert.True(t, nextRunTracker.ShouldRefresh("apt update", time.Hour))
+}
+
+func TestRefreshOncePerRun(t *testing.T) {
+	runner := &exec.RunnerMock{}
+	mngr := PackageTaskManager{
+		Runner: runner,
+		PackageManagerCmdProviders: []ManagementCmdsProvider{
+			&MockedOsPackageManagerCmdProvider{},
+		},
+		RefreshTracker: NewRefreshTracker(""),
+	}
+
+	for _, pkgName := range []string{"vim", "nano"} {
+		_, _, err := mngr.ExecuteTask(context.Background(), &tasks.PkgTask{
+			ShouldRefresh: true,
+			ActionType:    tasks.ActionInstall,
+			NamedTask:     tasks.NamedTask{Name: pkgName},
+		})
+		assert.NoError(t, err)
+	}
+
+	actualCmds := make([]string, 0, len(runner.GivenExecContexts))
+	for _, execContext := range runner.GivenExecContexts {
+		actualCmds = append(actualCmds, execContext.Cmds...)
+	}
+	assert.Equal(t, []string{
+		"mpmb --version",
+		"mpmb refresh",
+		"mpmb list",
+		"mpmb install vim",
+		"mpmb list",
+		"mpmb --version",
+		"mpmb list",
+		"mpmb install nano",
+		"mpmb list",
+	}, actualCmds)
+}
+
+type noRefreshCmdProvider struct {
+	MockedOsPackageManagerCmdProvider
+}
+
+func (ncp noRefreshCmdProvider) GetManagementCmds(t *tasks.PkgTask) (*ManagementCmds, error) {
+	mngtCmds, err := ncp.MockedOsPackageManagerCmdProvider.GetManagementCmds(t)
+	if err != nil {
+		return nil, err
+	}
+	mngtCmds.RefreshCmd = ""
+
+	return mngtCmds, nil
+}
+
+func TestRefreshSkippedWithoutRefreshCmd(t *testing.T) {
+	runner := &exec.RunnerMock{}
+	mngr := PackageTaskManager{
+		Runner: runner,
+		PackageManagerCmdProviders: []ManagementCmdsProvider{
+			noRefreshCmdProvider{},
+		},
+		RefreshTracker: NewRefreshTracker(""),
+	}
+
+	_, _, err := mngr.ExecuteTask(context.Background(), &tasks.PkgTask{
+		ShouldRefresh: true,
+		ActionType:    tasks.ActionInstall,
+		NamedTask:     tasks.NamedTask{Name: "vim"},
+	})
+	assert.NoError(t, err)
+
+	actualCmds := make([]string, 0, len(runner.GivenExecContexts))
+	for _, execContext := range runner.GivenExecContexts {
+		actualCmds = append(actualCmds, execContext.Cmds...)
+	}
+	assert.Equal(t, []string{
+		"mpmb --version",
+		"mpmb list",
+		"mpmb install vim",
+		"mpmb list",
+	}, actualCmds)
+}
+
+func TestRepoRefreshIsSharedWithPkgTasks(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tacoscript-repo")
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	runner := &exec.RunnerMock{}
+	refreshTracker := NewRefreshTracker("")
+
+	repoMngr := PackageRepoManager{
+		Runner:    runner,
+		FsManager: &utils.FsManager{},
+		RepoDefinitionProviders: []RepoDefinitionProvider{
+			MockedRepoDefinitionProvider{RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				FilePath:   filepath.Join(tmpDir, "new.list"),
+				Contents:   "deb https://repo.example.com focal main\n",
+			}},
+		},
+		RefreshTracker: refreshTracker,
+	}
+	pkgMngr := PackageTaskManager{
+		Runner: runner,
+		PackageManagerCmdProviders: []ManagementCmdsProvider{
+			&MockedOsPackageManagerCmdProvider{},
+		},
+		RefreshTracker: refreshTracker,
+	}
+
+	_, _, err = repoMngr.ExecuteRepoTask(context.Background(), &tasks.PkgRepoTask{
+		ActionType:    tasks.ActionRepoManage,
+		Name:          "new",
+		ShouldRefresh: true,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = pkgMngr.ExecuteTask(context.Background(), &tasks.PkgTask{
+		ShouldRefresh: true,
+		ActionType:    tasks.ActionInstall,
+		NamedTask:     tasks.NamedTask{Name: "vim"},
+	})
+	assert.NoError(t, err)
+
+	actualCmds := make([]string, 0, len(runner.GivenExecContexts))
+	for _, execContext := range runner.GivenExecContexts {
+		actualCmds = append(actualCmds, execContext.Cmds...)
+	}
+	assert.Equal(t, []string{
+		"mpmb --version",
+		"mpmb refresh",
+		"mpmb --version",
+		"mpmb list",
+		"mpmb install vim",
+		"mpmb list",
+	}, actualCmds)
+}
+
+func TestRefreshTrackerSharesAptIndex(t *testing.T) {
+	tracker := NewRefreshTracker("")
+	tracker.MarkRefreshed("apt-get update")
+	assert.False(t, tracker.ShouldRefresh("apt update", 0))
+}
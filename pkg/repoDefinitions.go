@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+)
+
+const (
+	aptSourcesDir   = "/etc/apt/sources.list.d"
+	aptKeyringsDir  = "/etc/apt/trusted.gpg.d"
+	yumReposDir     = "/etc/yum.repos.d"
+	managedByHeader = "# this file is managed by tacoscript, manual changes will be overwritten"
+)
+
+// aptSourceLine is a parsed one-line apt source e.g. "deb [arch=amd64] https://repo.example.com focal main"
+type aptSourceLine struct {
+	Type       string
+	Options    []string
+	URI        string
+	Suite      string
+	Components []string
+}
+
+func parseAptSourceLine(rawLine string) (aptSourceLine, error) {
+	res := aptSourceLine{}
+
+	rawLine = strings.TrimSpace(rawLine)
+	optionsStart := strings.Index(rawLine, "[")
+	optionsEnd := strings.Index(rawLine, "]")
+	if optionsStart >= 0 && optionsEnd > optionsStart {
+		res.Options = strings.Fields(rawLine[optionsStart+1 : optionsEnd])
+		rawLine = rawLine[:optionsStart] + rawLine[optionsEnd+1:]
+	}
+
+	parts := strings.Fields(rawLine)
+	if len(parts) < 3 {
+		return res, fmt.Errorf("invalid apt source '%s', expected format is 'deb [options] uri suite [components]'", rawLine)
+	}
+
+	if parts[0] != "deb" && parts[0] != "deb-src" {
+		return res, fmt.Errorf("invalid apt source type '%s', expected 'deb' or 'deb-src'", parts[0])
+	}
+
+	res.Type = parts[0]
+	res.URI = parts[1]
+	res.Suite = parts[2]
+	res.Components = parts[3:]
+
+	return res, nil
+}
+
+func aptKeyPath(t *tasks.PkgRepoTask) string {
+	if t.Key.RawLocation == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s/%s.asc", aptKeyringsDir, t.Name)
+}
+
+func aptRepoFilePath(t *tasks.PkgRepoTask) string {
+	if t.File != "" {
+		return t.File
+	}
+
+	if t.Deb822 {
+		return fmt.Sprintf("%s/%s.sources", aptSourcesDir, t.Name)
+	}
+
+	return fmt.Sprintf("%s/%s.list", aptSourcesDir, t.Name)
+}
+
+func renderAptSourceList(t *tasks.PkgRepoTask) (string, error) {
+	_, err := parseAptSourceLine(t.Repo)
+	if err != nil {
+		return "", err
+	}
+
+	line := strings.TrimSpace(t.Repo)
+	if !t.Enabled {
+		line = "# " + line
+	}
+
+	return fmt.Sprintf("%s\n%s\n", managedByHeader, line), nil
+}
+
+var deb822OptionNames = map[string]string{
+	"arch":      "Architectures",
+	"lang":      "Languages",
+	"target":    "Targets",
+	"pdiffs":    "PDiffs",
+	"trusted":   "Trusted",
+	"signed-by": "Signed-By",
+}
+
+func renderAptDeb822(t *tasks.PkgRepoTask) (string, error) {
+	sourceLine, err := parseAptSourceLine(t.Repo)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{
+		managedByHeader,
+		"Types: " + sourceLine.Type,
+		"URIs: " + sourceLine.URI,
+		"Suites: " + sourceLine.Suite,
+	}
+
+	if len(sourceLine.Components) > 0 {
+		lines = append(lines, "Components: "+strings.Join(sourceLine.Components, " "))
+	}
+
+	hasSignedBy := false
+	for _, option := range sourceLine.Options {
+		optionParts := strings.SplitN(option, "=", 2)
+		if len(optionParts) != 2 {
+			return "", fmt.Errorf("invalid apt source option '%s' in '%s'", option, t.Repo)
+		}
+		optionName, ok := deb822OptionNames[optionParts[0]]
+		if !ok {
+			return "", fmt.Errorf("unknown apt source option '%s' in '%s'", optionParts[0], t.Repo)
+		}
+		if optionName == "Signed-By" {
+			hasSignedBy = true
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", optionName, strings.ReplaceAll(optionParts[1], ",", " ")))
+	}
+
+	if keyPath := aptKeyPath(t); keyPath != "" && !hasSignedBy {
+		lines = append(lines, "Signed-By: "+keyPath)
+	}
+
+	if !t.Enabled {
+		lines = append(lines, "Enabled: no")
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func buildAptRepoDefinition(t *tasks.PkgRepoTask, versionCmd, refreshCmd string) (*RepoDefinition, error) {
+	repoDef := &RepoDefinition{
+		VersionCmd: versionCmd,
+		RefreshCmd: refreshCmd,
+		FilePath:   aptRepoFilePath(t),
+		KeyPath:    aptKeyPath(t),
+	}
+
+	if t.ActionType == tasks.ActionRepoRemove {
+		return repoDef, nil
+	}
+
+	var err error
+	if t.Deb822 {
+		repoDef.Contents, err = renderAptDeb822(t)
+	} else {
+		repoDef.Contents, err = renderAptSourceList(t)
+	}
+
+	return repoDef, err
+}
+
+func boolToYumFlag(val bool) string {
+	if val {
+		return "1"
+	}
+
+	return "0"
+}
+
+func yumKeyLocation(t *tasks.PkgRepoTask) string {
+	if t.Key.RawLocation == "" || t.Key.IsURL {
+		return t.Key.RawLocation
+	}
+
+	return "file://" + t.Key.LocalPath
+}
+
+func renderYumRepo(t *tasks.PkgRepoTask) (string, error) {
+	if t.BaseURL == "" {
+		return "", fmt.Errorf("empty '%s' field at path '%s.%s'", tasks.BaseURLField, t.Path, tasks.BaseURLField)
+	}
+
+	humanName := t.HumanName
+	if humanName == "" {
+		humanName = t.Name
+	}
+
+	lines := []string{
+		managedByHeader,
+		fmt.Sprintf("[%s]", t.Name),
+		"name=" + humanName,
+		"baseurl=" + t.BaseURL,
+		"enabled=" + boolToYumFlag(t.Enabled),
+		"gpgcheck=" + boolToYumFlag(t.GpgCheck),
+	}
+
+	if keyLocation := yumKeyLocation(t); keyLocation != "" {
+		lines = append(lines, "gpgkey="+keyLocation)
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+func buildYumRepoDefinition(t *tasks.PkgRepoTask, versionCmd, refreshCmd string) (*RepoDefinition, error) {
+	repoDef := &RepoDefinition{
+		VersionCmd: versionCmd,
+		RefreshCmd: refreshCmd,
+		FilePath:   t.File,
+	}
+
+	if repoDef.FilePath == "" {
+		repoDef.FilePath = fmt.Sprintf("%s/%s.repo", yumReposDir, t.Name)
+	}
+
+	if t.ActionType == tasks.ActionRepoRemove {
+		return repoDef, nil
+	}
+
+	if keyLocation := yumKeyLocation(t); keyLocation != "" {
+		repoDef.KeyImportCmds = []string{"rpm --import " + keyLocation}
+	}
+
+	var err error
+	repoDef.Contents, err = renderYumRepo(t)
+
+	return repoDef, err
+}
@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAptRepoDefinition(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Task             *tasks.PkgRepoTask
+		ExpectedFilePath string
+		ExpectedKeyPath  string
+		ExpectedContents string
+		ExpectedErrStr   string
+	}{
+		{
+			Name: "one_line_format",
+			Task: &tasks.PkgRepoTask{
+				ActionType: tasks.ActionRepoManage,
+				Name:       "internal",
+				Repo:       "deb [arch=amd64] https://repo.example.com/apt focal main",
+				Enabled:    true,
+			},
+			ExpectedFilePath: "/etc/apt/sources.list.d/internal.list",
+			ExpectedContents: managedByHeader + "\ndeb [arch=amd64] https://repo.example.com/apt focal main\n",
+		},
+		{
+			Name: "one_line_format_disabled_custom_file",
+			Task: &tasks.PkgRepoTask{
+				ActionType: tasks.ActionRepoManage,
+				Name:       "internal",
+				File:       "/etc/apt/sources.list.d/custom.list",
+				Repo:       "deb https://repo.example.com/apt focal main",
+			},
+			ExpectedFilePath: "/etc/apt/sources.list.d/custom.list",
+			ExpectedContents: managedByHeader + "\n# deb https://repo.example.com/apt focal main\n",
+		},
+		{
+			Name: "deb822_format_with_key",
+			Task: &tasks.PkgRepoTask{
+				ActionType: tasks.ActionRepoManage,
+				Name:       "internal",
+				Repo:       "deb [arch=amd64,arm64] https://repo.example.com/apt focal main contrib",
+				Key:        utils.ParseLocation("https://repo.example.com/key.asc"),
+				Deb822:     true,
+				Enabled:    true,
+			},
+			ExpectedFilePath: "/etc/apt/sources.list.d/internal.sources",
+			ExpectedKeyPath:  "/etc/apt/trusted.gpg.d/internal.asc",
+			ExpectedContents: managedByHeader + `
+Types: deb
+URIs: https://repo.example.com/apt
+Suites: focal
+Components: main contrib
+Architectures: amd64 arm64
+Signed-By: /etc/apt/trusted.gpg.d/internal.asc
+`,
+		},
+		{
+			Name: "invalid_source_line",
+			Task: &tasks.PkgRepoTask{
+				ActionType: tasks.ActionRepoManage,
+				Name:       "internal",
+				Repo:       "rpm https://repo.example.com/apt focal",
+			},
+			ExpectedErrStr: "invalid apt source type 'rpm', expected 'deb' or 'deb-src'",
+		},
+		{
+			Name: "remove_does_not_render",
+			Task: &tasks.PkgRepoTask{
+				ActionType: tasks.ActionRepoRemove,
+				Name:       "internal",
+			},
+			ExpectedFilePath: "/etc/apt/sources.list.d/internal.list",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			repoDef, err := buildAptRepoDefinition(tc.Task, "apt-get --version", "apt-get update")
+			if tc.ExpectedErrStr != "" {
+				assert.EqualError(tt, err, tc.ExpectedErrStr)
+				return
+			}
+
+			assert.NoError(tt, err)
+			assert.Equal(tt, tc.ExpectedFilePath, repoDef.FilePath)
+			assert.Equal(tt, tc.ExpectedKeyPath, repoDef.KeyPath)
+			assert.Equal(tt, tc.ExpectedContents, repoDef.Contents)
+			assert.Equal(tt, "apt-get update", repoDef.RefreshCmd)
+		})
+	}
+}
+
+func TestYumRepoDefinition(t *testing.T) {
+	task := &tasks.PkgRepoTask{
+		ActionType: tasks.ActionRepoManage,
+		Path:       "internal-yum",
+		Name:       "internal",
+		HumanName:  "Internal packages",
+		BaseURL:    "https://repo.example.com/yum/$releasever",
+		Key:        utils.ParseLocation("/etc/pki/internal.key"),
+		Enabled:    true,
+		GpgCheck:   true,
+	}
+
+	repoDef, err := buildYumRepoDefinition(task, "dnf --version", "dnf makecache")
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+
+	assert.Equal(t, "/etc/yum.repos.d/internal.repo", repoDef.FilePath)
+	assert.Equal(t, []string{"rpm --import file:///etc/pki/internal.key"}, repoDef.KeyImportCmds)
+	assert.Equal(t, managedByHeader+`
+[internal]
+name=Internal packages
+baseurl=https://repo.example.com/yum/$releasever
+enabled=1
+gpgcheck=1
+gpgkey=file:///etc/pki/internal.key
+`, repoDef.Contents)
+
+	_, err = buildYumRepoDefinition(&tasks.PkgRepoTask{
+		ActionType: tasks.ActionRepoManage,
+		Path:       "no-baseurl",
+		Name:       "internal",
+	}, "yum --version", "yum makecache")
+	assert.EqualError(t, err, "empty 'baseurl' field at path 'no-baseurl.baseurl'")
+}
@@ -0,0 +1,296 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/cloudradar-monitoring/tacoscript/conv"
+	"github.com/cloudradar-monitoring/tacoscript/exec"
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+	"github.com/sirupsen/logrus"
+)
+
+// RepoDefinition describes how a package repository is represented in the host system,
+// repositories are either rendered to a file (apt, yum, dnf) or managed by commands (brew taps, choco sources)
+type RepoDefinition struct {
+	VersionCmd    string
+	RefreshCmd    string
+	FilePath      string
+	Contents      string
+	KeyPath       string
+	KeyImportCmds []string
+	ListCmd       string
+	AddCmds       []string
+	RemoveCmds    []string
+}
+
+type RepoDefinitionProvider interface {
+	GetRepoDefinition(t *tasks.PkgRepoTask) (*RepoDefinition, error)
+}
+
+type PackageRepoManager struct {
+	Runner                  exec.Runner
+	FsManager               tasks.FsManager
+	RepoDefinitionProviders []RepoDefinitionProvider
+	// RefreshTracker is shared with the PackageTaskManager, so the pkg tasks don't refresh the index again
+	// after a repository change has refreshed it
+	RefreshTracker *RefreshTracker
+}
+
+// ExecuteRepoTask adds or removes the repository of the task, isChanged is false if the repository was up to date
+func (prm PackageRepoManager) ExecuteRepoTask(ctx context.Context, t *tasks.PkgRepoTask) (isChanged bool, output string, err error) {
+	if len(prm.RepoDefinitionProviders) == 0 {
+		err = fmt.Errorf("no package repository providers for the current OS")
+		return
+	}
+
+	var repoDefinition *RepoDefinition
+	for _, repoDefinitionProvider := range prm.RepoDefinitionProviders {
+		repoDefinition, err = repoDefinitionProvider.GetRepoDefinition(t)
+		if err != nil {
+			return false, "", err
+		}
+
+		logrus.Debugf("will execute version command %s to check if package manager is installed", repoDefinition.VersionCmd)
+
+		_, err = prm.run(ctx, t, repoDefinition.VersionCmd)
+		if err == nil {
+			logrus.Debugf("version command success: %s, will use it for repository management", repoDefinition.VersionCmd)
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	switch t.ActionType {
+	case tasks.ActionRepoManage:
+		isChanged, output, err = prm.manageRepo(ctx, t, repoDefinition)
+	case tasks.ActionRepoRemove:
+		isChanged, output, err = prm.removeRepo(ctx, t, repoDefinition)
+	default:
+		err = fmt.Errorf("unknown action type '%v' for task %s", t.ActionType, t.TypeName)
+	}
+	if err != nil {
+		return
+	}
+
+	if !isChanged {
+		logrus.Debugf("repository '%s' is up to date, nothing to refresh", t.Name)
+		return false, output, nil
+	}
+
+	refreshOutput, err := prm.refreshIfNeeded(ctx, t, repoDefinition)
+	output += refreshOutput
+
+	return true, output, err
+}
+
+func (prm PackageRepoManager) manageRepo(ctx context.Context, t *tasks.PkgRepoTask, repoDef *RepoDefinition) (isChanged bool, output string, err error) {
+	isKeyChanged, output, err := prm.manageKey(ctx, t, repoDef)
+	if err != nil {
+		return false, output, err
+	}
+
+	if repoDef.FilePath != "" {
+		var isFileChanged bool
+		isFileChanged, err = prm.writeRepoFileIfChanged(repoDef)
+		return isKeyChanged || isFileChanged, output, err
+	}
+
+	isPresent, err := prm.isRepoListed(ctx, t, repoDef)
+	if err != nil || isPresent {
+		return isKeyChanged, output, err
+	}
+
+	logrus.Debugf("will add repository by executing %s", conv.ConvertSourceToJSONStrIfPossible(repoDef.AddCmds))
+	addOutput, err := prm.run(ctx, t, repoDef.AddCmds...)
+
+	return true, output + addOutput, err
+}
+
+func (prm PackageRepoManager) removeRepo(ctx context.Context, t *tasks.PkgRepoTask, repoDef *RepoDefinition) (isChanged bool, output string, err error) {
+	if repoDef.FilePath == "" {
+		var isPresent bool
+		isPresent, err = prm.isRepoListed(ctx, t, repoDef)
+		if err != nil || !isPresent {
+			return false, "", err
+		}
+
+		logrus.Debugf("will remove repository by executing %s", conv.ConvertSourceToJSONStrIfPossible(repoDef.RemoveCmds))
+		output, err = prm.run(ctx, t, repoDef.RemoveCmds...)
+
+		return true, output, err
+	}
+
+	for _, filePath := range []string{repoDef.FilePath, repoDef.KeyPath} {
+		var isRemoved bool
+		isRemoved, err = prm.removeFileIfExists(filePath)
+		if err != nil {
+			return isChanged, "", err
+		}
+		isChanged = isChanged || isRemoved
+	}
+
+	return isChanged, "", nil
+}
+
+func (prm PackageRepoManager) manageKey(ctx context.Context, t *tasks.PkgRepoTask, repoDef *RepoDefinition) (isChanged bool, output string, err error) {
+	if t.Key.RawLocation == "" {
+		return false, "", nil
+	}
+
+	if repoDef.KeyPath == "" {
+		if len(repoDef.KeyImportCmds) == 0 {
+			return false, "", nil
+		}
+		logrus.Debugf("will import signing key by executing %s", conv.ConvertSourceToJSONStrIfPossible(repoDef.KeyImportCmds))
+		output, err = prm.run(ctx, t, repoDef.KeyImportCmds...)
+		return false, output, err
+	}
+
+	keySourcePath := t.Key.LocalPath
+	if t.Key.IsURL {
+		keySourcePath = repoDef.KeyPath + "_temp"
+		defer func() {
+			_, e := prm.removeFileIfExists(keySourcePath)
+			if e != nil {
+				logrus.Errorf("failed to delete '%s': %v", keySourcePath, e)
+			}
+		}()
+
+		err = prm.FsManager.DownloadFile(ctx, keySourcePath, t.Key.URL, false)
+		if err != nil {
+			return false, "", err
+		}
+	}
+
+	keyContents, err := prm.FsManager.ReadFile(keySourcePath)
+	if err != nil {
+		return false, "", err
+	}
+
+	isChanged, err = prm.writeFileIfChanged(repoDef.KeyPath, keyContents)
+
+	return isChanged, "", err
+}
+
+func (prm PackageRepoManager) writeRepoFileIfChanged(repoDef *RepoDefinition) (isChanged bool, err error) {
+	err = prm.FsManager.CreateDirPathIfNeeded(repoDef.FilePath, 0755)
+	if err != nil {
+		return false, err
+	}
+
+	return prm.writeFileIfChanged(repoDef.FilePath, repoDef.Contents)
+}
+
+func (prm PackageRepoManager) writeFileIfChanged(filePath, contents string) (isChanged bool, err error) {
+	fileExists, err := prm.FsManager.FileExists(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	if fileExists {
+		var actualContents string
+		actualContents, err = prm.FsManager.ReadFile(filePath)
+		if err != nil {
+			return false, err
+		}
+
+		if actualContents == contents {
+			logrus.Debugf("file '%s' matches the expected contents, will not change it", filePath)
+			return false, nil
+		}
+	}
+
+	logrus.Debugf("will write repository data to '%s'", filePath)
+	err = prm.FsManager.WriteFile(filePath, contents, 0644)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (prm PackageRepoManager) removeFileIfExists(filePath string) (isRemoved bool, err error) {
+	if filePath == "" {
+		return false, nil
+	}
+
+	fileExists, err := prm.FsManager.FileExists(filePath)
+	if err != nil || !fileExists {
+		return false, err
+	}
+
+	logrus.Debugf("will remove '%s'", filePath)
+	err = prm.FsManager.Remove(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (prm PackageRepoManager) isRepoListed(ctx context.Context, t *tasks.PkgRepoTask, repoDef *RepoDefinition) (bool, error) {
+	output, err := prm.run(ctx, t, repoDef.ListCmd)
+	if err != nil {
+		return false, err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '|'
+		})
+		if len(fields) > 0 && fields[0] == t.Name {
+			logrus.Debugf("repository '%s' is listed in the output of '%s'", t.Name, repoDef.ListCmd)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (prm PackageRepoManager) refreshIfNeeded(ctx context.Context, t *tasks.PkgRepoTask, repoDef *RepoDefinition) (output string, err error) {
+	if !t.ShouldRefresh || repoDef.RefreshCmd == "" {
+		return
+	}
+
+	logrus.Debugf("repository definition has changed, will refresh package manager: %s", repoDef.RefreshCmd)
+
+	output, err = prm.run(ctx, t, repoDef.RefreshCmd)
+
+	if err == nil && prm.RefreshTracker != nil {
+		prm.RefreshTracker.MarkRefreshed(repoDef.RefreshCmd)
+	}
+
+	return output, err
+}
+
+func (prm PackageRepoManager) run(ctx context.Context, t *tasks.PkgRepoTask, rawCmds ...string) (output string, err error) {
+	var stdoutBuf, stderrBuf bytes.Buffer
+	execCtx := &exec.Context{
+		Ctx:          ctx,
+		StdoutWriter: &stdoutBuf,
+		StderrWriter: &stderrBuf,
+		WorkingDir:   t.WorkingDir,
+		User:         t.User,
+		Path:         t.Path,
+		Envs:         t.Envs,
+		Cmds:         rawCmds,
+		Shell:        t.Shell,
+		HideOutput:   t.IsOutputHidden(),
+	}
+
+	err = prm.Runner.Run(execCtx)
+
+	if err == nil {
+		logrus.Debugf("Cmds %s success", conv.ConvertSourceToJSONStrIfPossible(rawCmds))
+	}
+
+	output = stderrBuf.String() + stdoutBuf.String()
+
+	return
+}
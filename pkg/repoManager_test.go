@@ -0,0 +1,260 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudradar-monitoring/tacoscript/exec"
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockedRepoDefinitionProvider struct {
+	RepoDefinition *RepoDefinition
+}
+
+func (mrdp MockedRepoDefinitionProvider) GetRepoDefinition(t *tasks.PkgRepoTask) (*RepoDefinition, error) {
+	repoDef := *mrdp.RepoDefinition
+	return &repoDef, nil
+}
+
+func TestRepoTaskExecution(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "tacoscript-repo")
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	existingRepoFile := filepath.Join(tmpDir, "existing.list")
+	err = ioutil.WriteFile(existingRepoFile, []byte("deb https://repo.example.com focal main\n"), 0600)
+	assert.NoError(t, err)
+
+	localKeyFile := filepath.Join(tmpDir, "local.key")
+	err = ioutil.WriteFile(localKeyFile, []byte("some key"), 0600)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		Name             string
+		Task             *tasks.PkgRepoTask
+		RepoDefinition   *RepoDefinition
+		Runner           *exec.RunnerMock
+		ExpectedCmds     []string
+		ExpectedChanged  bool
+		ExpectedErrStr   string
+		ExpectedFileData map[string]string
+		MissingFiles     []string
+	}{
+		{
+			Name: "new_repo_file_with_refresh",
+			Task: &tasks.PkgRepoTask{
+				ActionType:    tasks.ActionRepoManage,
+				Name:          "new",
+				ShouldRefresh: true,
+				Key:           utils.ParseLocation(localKeyFile),
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				FilePath:   filepath.Join(tmpDir, "sub", "new.list"),
+				Contents:   "deb https://repo.example.com focal main\n",
+				KeyPath:    filepath.Join(tmpDir, "new.asc"),
+			},
+			Runner:          &exec.RunnerMock{},
+			ExpectedCmds:    []string{"mpmb --version", "mpmb refresh"},
+			ExpectedChanged: true,
+			ExpectedFileData: map[string]string{
+				filepath.Join(tmpDir, "sub", "new.list"): "deb https://repo.example.com focal main\n",
+				filepath.Join(tmpDir, "new.asc"):         "some key",
+			},
+		},
+		{
+			Name: "unchanged_repo_file_without_refresh",
+			Task: &tasks.PkgRepoTask{
+				ActionType:    tasks.ActionRepoManage,
+				Name:          "existing",
+				ShouldRefresh: true,
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				FilePath:   existingRepoFile,
+				Contents:   "deb https://repo.example.com focal main\n",
+			},
+			Runner:       &exec.RunnerMock{},
+			ExpectedCmds: []string{"mpmb --version"},
+		},
+		{
+			Name: "changed_repo_file_with_disabled_refresh",
+			Task: &tasks.PkgRepoTask{
+				ActionType: tasks.ActionRepoManage,
+				Name:       "changed",
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				FilePath:   filepath.Join(tmpDir, "changed.list"),
+				Contents:   "deb https://repo.example.com focal main contrib\n",
+			},
+			Runner:          &exec.RunnerMock{},
+			ExpectedCmds:    []string{"mpmb --version"},
+			ExpectedChanged: true,
+			ExpectedFileData: map[string]string{
+				filepath.Join(tmpDir, "changed.list"): "deb https://repo.example.com focal main contrib\n",
+			},
+		},
+		{
+			Name: "key_import_cmds",
+			Task: &tasks.PkgRepoTask{
+				ActionType: tasks.ActionRepoManage,
+				Name:       "imported",
+				Key:        utils.ParseLocation("https://repo.example.com/key.asc"),
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd:    "mpmb --version",
+				FilePath:      filepath.Join(tmpDir, "imported.repo"),
+				Contents:      "[imported]\n",
+				KeyImportCmds: []string{"mpmb import-key https://repo.example.com/key.asc"},
+			},
+			Runner:          &exec.RunnerMock{},
+			ExpectedCmds:    []string{"mpmb --version", "mpmb import-key https://repo.example.com/key.asc"},
+			ExpectedChanged: true,
+		},
+		{
+			Name: "listed_repo_is_not_added",
+			Task: &tasks.PkgRepoTask{
+				ActionType:    tasks.ActionRepoManage,
+				Name:          "user/tap",
+				ShouldRefresh: true,
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				ListCmd:    "mpmb list",
+				AddCmds:    []string{"mpmb add user/tap"},
+			},
+			Runner: &exec.RunnerMock{
+				RunOutputCallback: func(stdOutWriter, stdErrWriter io.Writer) {
+					_, e := stdOutWriter.Write([]byte("homebrew/core\nuser/tap\n"))
+					assert.NoError(t, e)
+				},
+			},
+			ExpectedCmds: []string{"mpmb --version", "mpmb list"},
+		},
+		{
+			Name: "missing_repo_is_added",
+			Task: &tasks.PkgRepoTask{
+				ActionType:    tasks.ActionRepoManage,
+				Name:          "user/tap",
+				ShouldRefresh: true,
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				ListCmd:    "mpmb list",
+				AddCmds:    []string{"mpmb add user/tap"},
+			},
+			Runner:          &exec.RunnerMock{},
+			ExpectedCmds:    []string{"mpmb --version", "mpmb list", "mpmb add user/tap", "mpmb refresh"},
+			ExpectedChanged: true,
+		},
+		{
+			Name: "remove_repo_file",
+			Task: &tasks.PkgRepoTask{
+				ActionType:    tasks.ActionRepoRemove,
+				Name:          "existing",
+				ShouldRefresh: true,
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				FilePath:   existingRepoFile,
+			},
+			Runner:          &exec.RunnerMock{},
+			ExpectedCmds:    []string{"mpmb --version", "mpmb refresh"},
+			ExpectedChanged: true,
+			MissingFiles:    []string{existingRepoFile},
+		},
+		{
+			Name: "remove_missing_repo_file",
+			Task: &tasks.PkgRepoTask{
+				ActionType:    tasks.ActionRepoRemove,
+				Name:          "missing",
+				ShouldRefresh: true,
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+				RefreshCmd: "mpmb refresh",
+				FilePath:   filepath.Join(tmpDir, "missing.list"),
+			},
+			Runner:       &exec.RunnerMock{},
+			ExpectedCmds: []string{"mpmb --version"},
+		},
+		{
+			Name: "invalid_action_type",
+			Task: &tasks.PkgRepoTask{
+				TypeName: "some unknown type",
+				Name:     "invalid",
+			},
+			RepoDefinition: &RepoDefinition{
+				VersionCmd: "mpmb --version",
+			},
+			Runner:         &exec.RunnerMock{},
+			ExpectedCmds:   []string{"mpmb --version"},
+			ExpectedErrStr: "unknown action type '0' for task some unknown type",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			mngr := PackageRepoManager{
+				Runner:    tc.Runner,
+				FsManager: &utils.FsManager{},
+				RepoDefinitionProviders: []RepoDefinitionProvider{
+					MockedRepoDefinitionProvider{RepoDefinition: tc.RepoDefinition},
+				},
+			}
+
+			isChanged, _, err := mngr.ExecuteRepoTask(context.Background(), tc.Task)
+			assert.Equal(tt, tc.ExpectedChanged, isChanged)
+			if tc.ExpectedErrStr != "" {
+				assert.EqualError(tt, err, tc.ExpectedErrStr)
+			} else {
+				assert.NoError(tt, err)
+			}
+
+			actualCmds := make([]string, 0, len(tc.Runner.GivenExecContexts))
+			for _, execContext := range tc.Runner.GivenExecContexts {
+				actualCmds = append(actualCmds, execContext.Cmds...)
+			}
+			assert.Equal(tt, tc.ExpectedCmds, actualCmds)
+
+			for filePath, expectedData := range tc.ExpectedFileData {
+				actualData, e := ioutil.ReadFile(filePath)
+				assert.NoError(tt, e)
+				assert.Equal(tt, expectedData, string(actualData))
+			}
+
+			for _, filePath := range tc.MissingFiles {
+				fileExists, e := utils.FileExists(filePath)
+				assert.NoError(tt, e)
+				assert.False(tt, fileExists)
+			}
+		})
+	}
+}
+
+func TestNoRepoDefinitionProviders(t *testing.T) {
+	mngr := PackageRepoManager{
+		Runner: &exec.RunnerMock{},
+	}
+
+	_, _, err := mngr.ExecuteRepoTask(context.Background(), &tasks.PkgRepoTask{})
+	assert.EqualError(t, err, "no package repository providers for the current OS")
+}
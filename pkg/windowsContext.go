@@ -25,11 +25,29 @@ func (ecb OsPackageManagerCmdProvider) GetManagementCmds(t *tasks.PkgTask) (*Man
 		versionStr += " " + t.Version
 	}
 
+	// RefreshCmd is empty since choco reads the sources on every call and keeps no package list to refresh
 	return &ManagementCmds{
-		VersionCmd:    "choco --version",
-		UpgradeCmd:    "choco upgrade -y chocolatey",
-		InstallCmds:   []string{fmt.Sprintf("choco install -y %s%s", strings.Join(rawCmds, " "), versionStr)},
-		UninstallCmds: []string{fmt.Sprintf("choco uninstall -y %s", strings.Join(rawCmds, " "))},
-		UpgradeCmds:   []string{fmt.Sprintf("choco upgrade -y %s", strings.Join(rawCmds, " "))},
+		VersionCmd:       "choco --version",
+		ListInstalledCmd: "choco list --local-only --limit-output",
+		InstallCmds:      []string{fmt.Sprintf("choco install -y %s%s", strings.Join(rawCmds, " "), versionStr)},
+		UninstallCmds:    []string{fmt.Sprintf("choco uninstall -y %s", strings.Join(rawCmds, " "))},
+		UpgradeCmds:      []string{fmt.Sprintf("choco upgrade -y %s", strings.Join(rawCmds, " "))},
+	}, nil
+}
+
+func BuildRepoDefinitionProviders() ([]RepoDefinitionProvider, error) {
+	return []RepoDefinitionProvider{
+		ChocoSourceProvider{},
+	}, nil
+}
+
+type ChocoSourceProvider struct{}
+
+func (csp ChocoSourceProvider) GetRepoDefinition(t *tasks.PkgRepoTask) (*RepoDefinition, error) {
+	return &RepoDefinition{
+		VersionCmd: "choco --version",
+		ListCmd:    "choco source list --limit-output",
+		AddCmds:    []string{fmt.Sprintf("choco source add -n=%s -s=%s", t.Name, t.Repo)},
+		RemoveCmds: []string{fmt.Sprintf("choco source remove -n=%s", t.Name)},
 	}, nil
 }
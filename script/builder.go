@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"text/template"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/cloudradar-monitoring/tacoscript/applog"
 	"github.com/cloudradar-monitoring/tacoscript/utils"
 
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
@@ -21,6 +25,10 @@ func (fdp FileDataProvider) Read() ([]byte, error) {
 	return ioutil.ReadFile(fdp.Path)
 }
 
+func (fdp FileDataProvider) GetLocation() string {
+	return fdp.Path
+}
+
 type RawDataProvider interface {
 	Read() ([]byte, error)
 }
@@ -29,46 +37,75 @@ type TemplateVariablesProvider interface {
 	GetTemplateVariables() (map[string]interface{}, error)
 }
 
+// LazyTemplateVariable gives value of a template variable which is expensive to collect
+type LazyTemplateVariable func() (interface{}, error)
+
 type Builder struct {
 	DataProvider              RawDataProvider
 	TaskBuilder               tasks.Builder
 	TemplateVariablesProvider TemplateVariablesProvider
+	LazyTemplateVariables     map[string]LazyTemplateVariable
+	// Variables from vars files and command line, they override the vars section of the script and the facts
+	Variables map[string]interface{}
+	// SecretVariables are the names of the template variables which values are redacted in the logs, results and errors
+	SecretVariables []string
 }
 
 func (p Builder) BuildScripts() (tasks.Scripts, error) {
-	yamlTemplate, err := p.DataProvider.Read()
-	if err != nil {
-		return tasks.Scripts{}, err
+	scripts, err := p.buildScripts()
+
+	validationErr := ValidateScripts(scripts)
+	if validationErr == nil {
+		return scripts, err
 	}
 
-	templateVariables, err := p.TemplateVariablesProvider.GetTemplateVariables()
+	errs := utils.Errors{}
+	errs.Add(err)
+	errs.Add(validationErr)
+
+	return scripts, errs.ToError()
+}
+
+// buildScripts gives the scripts without checks of the requirements between them
+func (p Builder) buildScripts() (tasks.Scripts, error) {
+	templateVariables, err := p.getTemplateVariables()
 	if err != nil {
 		return tasks.Scripts{}, err
 	}
-	yamlBody, err := p.render(yamlTemplate, templateVariables)
+
+	loader := newScriptsLoader(p, templateVariables)
+	err = loader.load(p.DataProvider)
 	if err != nil {
 		return tasks.Scripts{}, err
 	}
 
-	rawScripts := map[string]map[string][]map[string]interface{}{}
-	err = yaml2.Unmarshal(yamlBody, &rawScripts)
+	err = loader.applyExtends()
 	if err != nil {
 		return tasks.Scripts{}, err
 	}
+	rawScripts := loader.scripts
 
 	scripts := make(tasks.Scripts, 0, len(rawScripts))
 	errs := utils.Errors{}
-	for scriptID, rawTasks := range rawScripts {
+	for _, scriptID := range loader.scriptIDs {
+		rawTasks := rawScripts[scriptID]
 		script := tasks.Script{
 			ID:    scriptID,
 			Tasks: make([]tasks.Task, 0, len(rawTasks)),
 		}
 		index := 0
-		for taskTypeID, taskContext := range rawTasks {
+		for _, taskTypeID := range loader.taskTypes[scriptID] {
+			taskContext := rawTasks[taskTypeID]
 			index++
 			task, e := p.TaskBuilder.Build(taskTypeID, fmt.Sprintf("%s.%s[%d]", scriptID, taskTypeID, index), taskContext)
 			if e != nil {
-				return tasks.Scripts{}, e
+				// errors of all tasks are collected to report them at once, the partially built task is kept
+				// so the requisite checks can report their errors too
+				errs.Add(e)
+				if task != nil {
+					script.Tasks = append(script.Tasks, task)
+				}
+				continue
 			}
 
 			errs.Add(task.Validate())
@@ -77,14 +114,234 @@ func (p Builder) BuildScripts() (tasks.Scripts, error) {
 
 		scripts = append(scripts, script)
 	}
-	err = ValidateScripts(scripts)
-	errs.Add(err)
 
 	return scripts, errs.ToError()
 }
 
+// RenderScript gives the rendered template of the main script file without parsing it, it's useful for debugging of templates
+func (p Builder) RenderScript() ([]byte, error) {
+	templateVariables, err := p.getTemplateVariables()
+	if err != nil {
+		return nil, err
+	}
+
+	renderedFile, err := p.renderScriptFile(p.DataProvider, templateVariables)
+
+	return renderedFile.Body, err
+}
+
+func (p Builder) getTemplateVariables() (map[string]interface{}, error) {
+	templateVariables, err := p.TemplateVariablesProvider.GetTemplateVariables()
+	if err != nil {
+		return nil, err
+	}
+	if templateVariables == nil {
+		templateVariables = map[string]interface{}{}
+	}
+
+	return templateVariables, nil
+}
+
+type renderedScriptFile struct {
+	Location  string
+	Template  []byte
+	Variables map[string]interface{}
+	Body      []byte
+}
+
+// renderScriptFile renders the script file template, lazy variables are collected into the shared variables,
+// while the vars section of the file applies only to this file
+func (p Builder) renderScriptFile(dataProvider RawDataProvider, variables map[string]interface{}) (renderedScriptFile, error) {
+	renderedFile := renderedScriptFile{}
+	if locatedDataProvider, ok := dataProvider.(LocatedDataProvider); ok {
+		renderedFile.Location = locatedDataProvider.GetLocation()
+	}
+
+	var err error
+	renderedFile.Template, err = dataProvider.Read()
+	if err != nil {
+		return renderedFile, err
+	}
+
+	err = p.addLazyTemplateVariables(string(renderedFile.Template), variables)
+	if err != nil {
+		return renderedFile, err
+	}
+
+	renderedFile.Variables = make(map[string]interface{}, len(variables))
+	mergeVariables(renderedFile.Variables, variables)
+
+	varsSection := extractVarsSection(string(renderedFile.Template))
+	err = p.addScriptVariables(renderedFile.Location, renderedFile.Template, varsSection, renderedFile.Variables)
+	if err != nil {
+		return renderedFile, err
+	}
+
+	renderedFile.Body, err = p.render(renderedFile.Template, renderedFile.Variables)
+	if err != nil {
+		return renderedFile, newTemplateError(renderedFile.Location, renderedFile.Template, err)
+	}
+
+	return renderedFile, nil
+}
+
+// readScriptFile renders the script file template and parses it
+func (p Builder) readScriptFile(dataProvider RawDataProvider, variables map[string]interface{}) (rawScriptFile, error) {
+	scriptFile := rawScriptFile{}
+
+	renderedFile, err := p.renderScriptFile(dataProvider, variables)
+	if err != nil {
+		return scriptFile, err
+	}
+
+	err = yaml2.Unmarshal(renderedFile.Body, &scriptFile)
+	if err != nil {
+		return scriptFile, p.newRenderedYAMLError(renderedFile.Location, renderedFile.Template, renderedFile.Template, renderedFile.Variables, err)
+	}
+
+	err = readDocumentOrder(renderedFile.Body, &scriptFile)
+	if err != nil {
+		return scriptFile, err
+	}
+
+	return scriptFile, nil
+}
+
+// readDocumentOrder fills the order of the scripts and their tasks as they are defined in the file
+func readDocumentOrder(body []byte, scriptFile *rawScriptFile) error {
+	orderedFile := yaml2.MapSlice{}
+	err := yaml2.Unmarshal(body, &orderedFile)
+	if err != nil {
+		return err
+	}
+
+	scriptFile.ScriptIDs = make([]string, 0, len(scriptFile.Scripts))
+	scriptFile.TaskTypes = make(map[string][]string, len(scriptFile.Scripts))
+	for _, scriptItem := range orderedFile {
+		scriptID := fmt.Sprint(scriptItem.Key)
+		if _, ok := scriptFile.Scripts[scriptID]; !ok {
+			continue
+		}
+		scriptFile.ScriptIDs = append(scriptFile.ScriptIDs, scriptID)
+
+		orderedTasks, _ := scriptItem.Value.(yaml2.MapSlice)
+		taskTypes := make([]string, 0, len(orderedTasks))
+		for _, taskItem := range orderedTasks {
+			taskTypes = append(taskTypes, fmt.Sprint(taskItem.Key))
+		}
+		scriptFile.TaskTypes[scriptID] = taskTypes
+	}
+
+	return nil
+}
+
+// newRenderedYAMLError maps lines of the yaml error in the rendered template to the lines of the source,
+// templateData is a part of the source with the same line numbers e.g. the vars section
+func (p Builder) newRenderedYAMLError(location string, source, templateData []byte, variables map[string]interface{}, err error) error {
+	renderedLinesMap, e := p.renderWithLineMarkers(templateData, variables)
+	if e != nil {
+		logrus.Debugf("failed to map rendered lines to the template source: %v", e)
+		renderedLinesMap = map[int]int{}
+	}
+
+	return newYAMLError(location, source, renderedLinesMap, err)
+}
+
+// addLazyTemplateVariables collects lazy variables only if they are referenced in the template and not collected yet
+func (p Builder) addLazyTemplateVariables(templateData string, variables map[string]interface{}) error {
+	for variableName, lazyVariable := range p.LazyTemplateVariables {
+		if !strings.Contains(templateData, variableName) {
+			continue
+		}
+
+		if _, ok := variables[variableName]; ok {
+			continue
+		}
+
+		logrus.Debugf("template references '%s', will collect its value", variableName)
+		variableValue, err := lazyVariable()
+		if err != nil {
+			return fmt.Errorf("failed to collect template variable '%s': %w", variableName, err)
+		}
+		variables[variableName] = variableValue
+	}
+
+	return nil
+}
+
+// addScriptVariables applies variables with the precedence: facts < vars section of the script < vars files and command line,
+// the vars section is rendered before the rest of the script so it can reference facts and command line variables
+func (p Builder) addScriptVariables(location string, source []byte, varsSection string, variables map[string]interface{}) error {
+	if varsSection != "" {
+		varsSectionVariables := make(map[string]interface{}, len(variables)+len(p.Variables))
+		mergeVariables(varsSectionVariables, variables)
+		mergeVariables(varsSectionVariables, p.Variables)
+
+		renderedVarsSection, err := p.render([]byte(varsSection), varsSectionVariables)
+		if err != nil {
+			return newTemplateError(location, source, err)
+		}
+
+		rawSection := map[string]map[string]interface{}{}
+		err = yaml2.Unmarshal(renderedVarsSection, &rawSection)
+		if err != nil {
+			err = p.newRenderedYAMLError(location, source, []byte(varsSection), varsSectionVariables, err)
+			if sourceErr, ok := err.(SourceError); ok {
+				sourceErr.Msg = fmt.Sprintf("invalid '%s' section: %s", VarsSection, sourceErr.Msg)
+				return sourceErr
+			}
+			return fmt.Errorf("invalid '%s' section: %w", VarsSection, err)
+		}
+
+		for key, val := range rawSection[VarsSection] {
+			variables[key] = utils.NormalizeYAMLValue(val)
+		}
+	}
+
+	mergeVariables(variables, p.Variables)
+	p.addSecretVariables(variables)
+
+	return nil
+}
+
+// addSecretVariables registers the values of the secret variables, a scalar variable is registered with any type
+// e.g. --var pin=1234, but only the string values of maps and lists are registered since the numbers or booleans
+// in them e.g. port: 8080 or enabled: true are rarely secret and would be redacted everywhere in the output
+func (p Builder) addSecretVariables(variables map[string]interface{}) {
+	for _, name := range p.SecretVariables {
+		switch val := variables[name].(type) {
+		case nil:
+		case map[string]interface{}, []interface{}:
+			applog.AddSecrets(secretStrings(val)...)
+		default:
+			applog.AddSecrets(fmt.Sprint(val))
+		}
+	}
+}
+
+func secretStrings(val interface{}) []string {
+	switch typedVal := val.(type) {
+	case string:
+		return []string{typedVal}
+	case map[string]interface{}:
+		values := make([]string, 0, len(typedVal))
+		for _, item := range typedVal {
+			values = append(values, secretStrings(item)...)
+		}
+		return values
+	case []interface{}:
+		values := make([]string, 0, len(typedVal))
+		for _, item := range typedVal {
+			values = append(values, secretStrings(item)...)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
 func (p Builder) render(templateData []byte, variables map[string]interface{}) (result []byte, err error) {
-	templ := template.New("goyaml")
+	templ := template.New("goyaml").Funcs(templateFuncs())
 
 	pageTemplate, err := templ.Parse(string(templateData))
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/cloudradar-monitoring/tacoscript/applog"
 	"github.com/cloudradar-monitoring/tacoscript/utils"
 
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
@@ -44,6 +45,9 @@ type TaskBuilderTaskMock struct {
 	Context         []map[string]interface{}
 	ValidationError error
 	Requirements    []string
+	Requisites      []tasks.Requisite
+	OnChanges       []tasks.Requisite
+	Order           tasks.Order
 }
 
 func (tm *TaskBuilderTaskMock) GetName() string {
@@ -70,8 +74,16 @@ func (rdpm RawDataProviderMock) Read() ([]byte, error) {
 	return []byte(rdpm.DataToReturn), rdpm.ErrToReturn
 }
 
-func (tm *TaskBuilderTaskMock) GetRequirements() []string {
-	return tm.Requirements
+func (tm *TaskBuilderTaskMock) GetRequirements() []tasks.Requisite {
+	return append(tasks.ScriptRequisites(tm.Requirements), tm.Requisites...)
+}
+
+func (tm *TaskBuilderTaskMock) GetRequisites() tasks.Requisites {
+	return tasks.Requisites{OnChanges: tm.OnChanges}
+}
+
+func (tm *TaskBuilderTaskMock) GetOrder() tasks.Order {
+	return tm.Order
 }
 
 type TemplateVariablesProviderMock struct {
@@ -95,6 +107,8 @@ func TestBuilder(t *testing.T) {
 		TaskRequirements       []string
 		TemplateVariables      map[string]interface{}
 		TemplateVariablesError error
+		LazyTemplateVariables  map[string]LazyTemplateVariable
+		Variables              map[string]interface{}
 	}{
 		{
 			YamlFileName: "test1.yaml",
@@ -147,8 +161,11 @@ func TestBuilder(t *testing.T) {
 			ExpectedScripts:     tasks.Scripts{},
 		},
 		{
-			YamlFileName:    "test4.yaml",
-			ExpectedErrMsg:  "yaml: line 5: found character that cannot start any token",
+			YamlFileName: "test4.yaml",
+			ExpectedErrMsg: "script at line 5: found character that cannot start any token\n" +
+				"4 |     - names:\n" +
+				"5 | \t\t\t\t\t\tname one\n" +
+				"  | \t\t\t\t\t\t^",
 			ExpectedScripts: tasks.Scripts{},
 		},
 		{
@@ -209,9 +226,8 @@ func TestBuilder(t *testing.T) {
 			},
 		},
 		{
-			YamlFileName: "test7.yaml",
-			ExpectedErrMsg: "task at path 'scriptValidation.cmd.run[1]' cannot require own script 'scriptValidation', " +
-				"cyclic requirements are detected: '[scriptValidation]'",
+			YamlFileName:     "test7.yaml",
+			ExpectedErrMsg:   "task at path 'scriptValidation.cmd.run[1]' cannot require own script 'scriptValidation'",
 			TaskRequirements: []string{"scriptValidation"},
 		},
 		{
@@ -262,6 +278,45 @@ func TestBuilder(t *testing.T) {
 				},
 			},
 		},
+		{
+			YamlInput: `zeta:
+  file.managed:
+    - name: /tmp/zeta
+  cmd.run:
+    - name: echo zeta
+alpha:
+  cmd.run:
+    - name: echo alpha
+`,
+			TemplateVariables: map[string]interface{}{},
+			ExpectedScripts: tasks.Scripts{
+				{
+					ID: "zeta",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName: "file.managed",
+							Path:     "zeta.file.managed[1]",
+							Context:  []map[string]interface{}{{tasks.NameField: "/tmp/zeta"}},
+						},
+						&TaskBuilderTaskMock{
+							TypeName: "cmd.run",
+							Path:     "zeta.cmd.run[2]",
+							Context:  []map[string]interface{}{{tasks.NameField: "echo zeta"}},
+						},
+					},
+				},
+				{
+					ID: "alpha",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName: "cmd.run",
+							Path:     "alpha.cmd.run[1]",
+							Context:  []map[string]interface{}{{tasks.NameField: "echo alpha"}},
+						},
+					},
+				},
+			},
+		},
 		{
 			TemplateVariables: map[string]interface{}{
 				utils.OSFamily: "",
@@ -272,8 +327,121 @@ func TestBuilder(t *testing.T) {
 			TemplateVariablesError: errors.New("cannot provide template variables"),
 		},
 		{
-			YamlFileName:    "test10.go.yaml",
-			ExpectedErrMsg:  `template: goyaml:3:6: executing "goyaml" at <eq "RedHat">: error calling eq: missing argument for comparison`,
+			YamlInput: `pkgs:
+  cmd.run:
+{{ if index .taco_pkgs "nginx" }}
+    - name: nginx -s reload
+{{ else }}
+    - name: echo no nginx
+{{ end }}
+`,
+			TemplateVariables: map[string]interface{}{},
+			LazyTemplateVariables: map[string]LazyTemplateVariable{
+				utils.InstalledPackages: func() (interface{}, error) {
+					return map[string]string{"nginx": "1.18.0"}, nil
+				},
+			},
+			ExpectedScripts: tasks.Scripts{
+				{
+					ID: "pkgs",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName: "cmd.run",
+							Path:     "pkgs.cmd.run[1]",
+							Context: []map[string]interface{}{
+								{tasks.NameField: "nginx -s reload"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			YamlFileName:      "test5.yaml",
+			TemplateVariables: map[string]interface{}{},
+			LazyTemplateVariables: map[string]LazyTemplateVariable{
+				utils.InstalledPackages: func() (interface{}, error) {
+					return nil, errors.New("should not be collected")
+				},
+			},
+			ExpectedScripts: tasks.Scripts{
+				{
+					ID: "cwd",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName: "cmd.run",
+							Path:     "cwd.cmd.run[1]",
+							Context: []map[string]interface{}{
+								{tasks.NamesField: []interface{}{"run one", "run two", "run three"}},
+								{tasks.RequireField: []interface{}{"req one", "req two", "req three"}},
+								{tasks.OnlyIf: []interface{}{"onlyif one", "onlyif two", "onlyif three"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			YamlInput:         `pkgs: {{ .taco_pkgs }}`,
+			TemplateVariables: map[string]interface{}{},
+			LazyTemplateVariables: map[string]LazyTemplateVariable{
+				utils.InstalledPackages: func() (interface{}, error) {
+					return nil, errors.New("no package manager")
+				},
+			},
+			ExpectedErrMsg: "failed to collect template variable 'taco_pkgs': no package manager",
+		},
+		{
+			YamlInput: `vars:
+  env: staging
+  replicas: 2
+  db_host: db-{{ .env }}.{{ .taco_hostname }}
+deploy:
+  cmd.run:
+    - name: ./deploy.sh {{ .env }} {{ .replicas }} {{ .db_host }}
+`,
+			TemplateVariables: map[string]interface{}{
+				"taco_hostname": "web01",
+				"replicas":      1,
+			},
+			Variables: map[string]interface{}{
+				"env": "production",
+			},
+			ExpectedScripts: tasks.Scripts{
+				{
+					ID: "deploy",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName: "cmd.run",
+							Path:     "deploy.cmd.run[1]",
+							Context: []map[string]interface{}{
+								{tasks.NameField: "./deploy.sh production 2 db-production.web01"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			YamlInput: `vars:
+  - env
+deploy:
+  cmd.run:
+    - name: echo 1
+`,
+			ExpectedErrMsg: "script at line 2: invalid 'vars' section: cannot unmarshal !!seq into map[string]interface {}\n" +
+				"1 | vars:\n" +
+				"2 |   - env\n" +
+				"  |   ^\n" +
+				"3 | deploy:",
+		},
+		{
+			YamlFileName: "test10.go.yaml",
+			ExpectedErrMsg: `script at line 3, column 7: executing "goyaml" at <eq "RedHat">: error calling eq: missing argument for comparison` + "\n" +
+				"2 |   cmd.run:\n" +
+				`3 | {{ if eq "RedHat" }}` + "\n" +
+				"  |       ^\n" +
+				"4 |     - name: yum --version",
 			ExpectedScripts: tasks.Scripts{},
 		},
 	}
@@ -300,6 +468,8 @@ func TestBuilder(t *testing.T) {
 			DataProvider:              dataProviderMock,
 			TaskBuilder:               taskBuilderMock,
 			TemplateVariablesProvider: templateVariablesProviderMock,
+			LazyTemplateVariables:     testCase.LazyTemplateVariables,
+			Variables:                 testCase.Variables,
 		}
 
 		scripts, err := parser.BuildScripts()
@@ -327,3 +497,59 @@ func BuildExpectedEnvs(expectedEnvs map[interface{}]interface{}) []interface{} {
 
 	return envs
 }
+
+func TestRenderScript(t *testing.T) {
+	parser := Builder{
+		DataProvider: RawDataProviderMock{
+			DataToReturn: "vars:\n  env: staging\ndeploy:\n  cmd.run:\n    - name: deploy {{ .env }} {{ .taco_os_family }}\n",
+		},
+		TemplateVariablesProvider: TemplateVariablesProviderMock{
+			Variables: map[string]interface{}{utils.OSFamily: "debian"},
+		},
+		Variables: map[string]interface{}{"env": "production"},
+	}
+
+	renderedScript, err := parser.RenderScript()
+	assert.NoError(t, err)
+	assert.Equal(t, "vars:\n  env: staging\ndeploy:\n  cmd.run:\n    - name: deploy production debian\n", string(renderedScript))
+}
+
+func TestBuilderSecretVariables(t *testing.T) {
+	parser := Builder{
+		DataProvider: RawDataProviderMock{
+			DataToReturn: "vars:\n  db:\n    password: vars-section-pwd\n    port: 8080\n    ssl: true\n    user: db\n" +
+				"deploy:\n  cmd.run:\n    - name: deploy {{ .api_token }}\n",
+		},
+		TaskBuilder:               &TaskBuilderMock{},
+		TemplateVariablesProvider: TemplateVariablesProviderMock{},
+		Variables:                 map[string]interface{}{"api_token": "cli-api-token", "pin": 73519},
+		SecretVariables:           []string{"db", "api_token", "pin", "missing"},
+	}
+
+	_, err := parser.BuildScripts()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "deploy *** *** ***", applog.Redact("deploy cli-api-token vars-section-pwd 73519"))
+	// the numbers and booleans of secret maps and too short values are not redacted
+	assert.Equal(t, "db listens on 8080, ssl: true", applog.Redact("db listens on 8080, ssl: true"))
+}
+
+func TestBuilderReportsFieldAndRequisiteErrors(t *testing.T) {
+	parser := Builder{
+		DataProvider: RawDataProviderMock{
+			DataToReturn: "a:\n  cmd.run:\n    - name: echo a\n    - cwdd: /tmp\n    - require:\n      - b\n" +
+				"b:\n  cmd.run:\n    - name: echo b\n    - require:\n      - a\n",
+		},
+		TaskBuilder: tasks.NewBuilderRouter(map[string]tasks.Builder{
+			tasks.TaskTypeCmdRun: &tasks.CmdRunTaskBuilder{},
+		}),
+		TemplateVariablesProvider: TemplateVariablesProviderMock{},
+	}
+
+	_, err := parser.BuildScripts()
+	assert.EqualError(
+		t,
+		err,
+		"unknown field 'cwdd' at path 'a.cmd.run[1].cwdd', did you mean 'cwd'?, cyclic requirements are detected: 'a' -> 'b' -> 'a'",
+	)
+}
@@ -2,6 +2,7 @@ package script
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/cloudradar-monitoring/tacoscript/pkg"
 	"github.com/sirupsen/logrus"
@@ -12,69 +13,229 @@ import (
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
 )
 
-// RunScript main entry point for the script execution
-func RunScript(scriptPath string) error {
+// RunScript main entry point for the script execution, custom facts are read from localFactsDir,
+// variables override the facts and the vars section of the script in templates, the values of secretVariables
+// are redacted in the logs, results and errors
+func RunScript(scriptPath, localFactsDir string, variables map[string]interface{}, secretVariables []string) error {
 	fileDataProvider := FileDataProvider{
 		Path: scriptPath,
 	}
 
-	parser := Builder{
-		DataProvider: fileDataProvider,
-		TaskBuilder: tasks.NewBuilderRouter(map[string]tasks.Builder{
-			tasks.TaskTypeCmdRun: &tasks.CmdRunTaskBuilder{},
-			tasks.FileManaged:    &tasks.FileManagedTaskBuilder{},
-			tasks.PkgInstalled:   &tasks.PkgTaskBuilder{},
-			tasks.PkgRemoved:     &tasks.PkgTaskBuilder{},
-			tasks.PkgUpgraded:    &tasks.PkgTaskBuilder{},
-		}),
-		TemplateVariablesProvider: utils.OSDataProvider{},
+	cmdRunner := exec.SystemRunner{
+		SystemAPI: exec.OSApi{},
+	}
+
+	pkgTaskManager := buildPkgTaskManager(cmdRunner)
+	parser := buildParser(fileDataProvider, localFactsDir, variables, secretVariables, pkgTaskManager)
+
+	repoDefinitionProviders, err := pkg.BuildRepoDefinitionProviders()
+	if err != nil {
+		logrus.Warn(err.Error())
+	}
+	conditionChecker := buildConditionChecker(localFactsDir, pkgTaskManager)
+	execRouter := buildExecutorRouter(cmdRunner, pkgTaskManager, repoDefinitionProviders, conditionChecker)
+
+	scripts, err := parser.BuildScripts()
+
+	if err != nil {
+		return err
 	}
 
+	runner := Runner{
+		DataProvider:   fileDataProvider,
+		ExecutorRouter: execRouter,
+	}
+
+	err = runner.Run(context.Background(), scripts)
+
+	return err
+}
+
+// RenderScript gives the rendered template of the script with the same variables as RunScript uses
+func RenderScript(scriptPath, localFactsDir string, variables map[string]interface{}, secretVariables []string) ([]byte, error) {
 	cmdRunner := exec.SystemRunner{
 		SystemAPI: exec.OSApi{},
 	}
 
-	pkgCmdProviders, err := pkg.BuildManagementCmdsProviders()
+	parser := buildParser(FileDataProvider{Path: scriptPath}, localFactsDir, variables, secretVariables, buildPkgTaskManager(cmdRunner))
+
+	return parser.RenderScript()
+}
+
+// BuildScriptGraph gives the requirements graph of the script, the graph is built even if the requirements are cyclic or missing
+func BuildScriptGraph(
+	scriptPath, localFactsDir string,
+	variables map[string]interface{},
+	secretVariables []string,
+) (RequirementsGraph, error) {
+	cmdRunner := exec.SystemRunner{
+		SystemAPI: exec.OSApi{},
+	}
+
+	parser := buildParser(FileDataProvider{Path: scriptPath}, localFactsDir, variables, secretVariables, buildPkgTaskManager(cmdRunner))
+
+	scripts, err := parser.buildScripts()
 	if err != nil {
-		logrus.Warn(err.Error())
+		return RequirementsGraph{}, err
 	}
-	pkgTaskManager := pkg.PackageTaskManager{
-		Runner:                     cmdRunner,
-		PackageManagerCmdProviders: pkgCmdProviders,
+	injectReverseRequisites(scripts)
+
+	return BuildRequirementsGraph(scripts), nil
+}
+
+// ValidateScript builds and checks the script without executing it, if factsPath is not empty the templates are rendered
+// with the facts from this file instead of the current host ones and the checks which depend on the current host are skipped
+func ValidateScript(scriptPath, localFactsDir, factsPath string, variables map[string]interface{}, secretVariables []string) error {
+	cmdRunner := exec.SystemRunner{
+		SystemAPI: exec.OSApi{},
 	}
+
+	pkgTaskManager := buildPkgTaskManager(cmdRunner)
+	parser := buildParser(FileDataProvider{Path: scriptPath}, localFactsDir, variables, secretVariables, pkgTaskManager)
+	if factsPath != "" {
+		parser.TemplateVariablesProvider = utils.FactsFileProvider{Path: factsPath}
+		// lazy variables are collected from the current host, the facts file should contain them if they are needed
+		parser.LazyTemplateVariables = nil
+	}
+
+	errs := utils.Errors{}
+	scripts, err := parser.BuildScripts()
+	errs.Add(err)
+
+	execRouter := buildExecutorRouter(cmdRunner, pkgTaskManager, nil, buildConditionChecker(localFactsDir, pkgTaskManager))
+	errs.Add(ValidateExecutors(scripts, execRouter))
+
+	if factsPath == "" {
+		errs.Add(validateHostSupport(scripts))
+	}
+
+	return errs.ToError()
+}
+
+// validateHostSupport checks that the package management tasks are supported on the current host
+func validateHostSupport(scrpts tasks.Scripts) error {
+	_, pkgErr := pkg.BuildManagementCmdsProviders()
+	_, repoErr := pkg.BuildRepoDefinitionProviders()
+
+	errs := utils.Errors{}
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			switch task.GetName() {
+			case tasks.PkgInstalled, tasks.PkgRemoved, tasks.PkgUpgraded:
+				if pkgErr != nil {
+					errs.Add(fmt.Errorf("task at path '%s' cannot be executed on this host: %w", task.GetPath(), pkgErr))
+				}
+			case tasks.PkgRepoManaged, tasks.PkgRepoAbsent:
+				if repoErr != nil {
+					errs.Add(fmt.Errorf("task at path '%s' cannot be executed on this host: %w", task.GetPath(), repoErr))
+				}
+			}
+		}
+	}
+
+	return errs.ToError()
+}
+
+func buildExecutorRouter(
+	cmdRunner exec.Runner,
+	pkgTaskManager pkg.PackageTaskManager,
+	repoDefinitionProviders []pkg.RepoDefinitionProvider,
+	conditionChecker *tasks.ConditionChecker,
+) tasks.ExecutorRouter {
 	pkgTaskExecutor := &tasks.PkgTaskExecutor{
-		PackageManager: pkgTaskManager,
-		Runner:         cmdRunner,
+		PackageManager:   pkgTaskManager,
+		Runner:           cmdRunner,
+		ConditionChecker: conditionChecker,
+	}
+
+	pkgRepoTaskExecutor := &tasks.PkgRepoTaskExecutor{
+		RepoManager: pkg.PackageRepoManager{
+			Runner:                  cmdRunner,
+			FsManager:               &utils.FsManager{},
+			RepoDefinitionProviders: repoDefinitionProviders,
+			RefreshTracker:          pkgTaskManager.RefreshTracker,
+		},
+		Runner:           cmdRunner,
+		ConditionChecker: conditionChecker,
 	}
-	execRouter := tasks.ExecutorRouter{
+
+	return tasks.ExecutorRouter{
 		Executors: map[string]tasks.Executor{
 			tasks.TaskTypeCmdRun: &tasks.CmdRunTaskExecutor{
-				Runner:    cmdRunner,
-				FsManager: &utils.FsManager{},
+				Runner:           cmdRunner,
+				ConditionChecker: conditionChecker,
 			},
 			tasks.FileManaged: &tasks.FileManagedTaskExecutor{
-				Runner:      cmdRunner,
-				FsManager:   &utils.FsManager{},
-				HashManager: &utils.HashManager{},
+				Runner:           cmdRunner,
+				FsManager:        &utils.FsManager{},
+				HashManager:      &utils.HashManager{},
+				ConditionChecker: conditionChecker,
 			},
-			tasks.PkgInstalled: pkgTaskExecutor,
-			tasks.PkgRemoved:   pkgTaskExecutor,
-			tasks.PkgUpgraded:  pkgTaskExecutor,
+			tasks.PkgInstalled:   pkgTaskExecutor,
+			tasks.PkgRemoved:     pkgTaskExecutor,
+			tasks.PkgUpgraded:    pkgTaskExecutor,
+			tasks.PkgRepoManaged: pkgRepoTaskExecutor,
+			tasks.PkgRepoAbsent:  pkgRepoTaskExecutor,
 		},
 	}
+}
 
-	scripts, err := parser.BuildScripts()
+// buildConditionChecker gives the checker of the creates files and the structured onlyif and unless conditions
+// which is shared by all executors
+func buildConditionChecker(localFactsDir string, pkgTaskManager pkg.PackageTaskManager) *tasks.ConditionChecker {
+	return &tasks.ConditionChecker{
+		FsManager:        &utils.FsManager{},
+		FactsProvider:    utils.OSDataProvider{LocalFactsDir: localFactsDir},
+		PackagesProvider: pkgTaskManager,
+	}
+}
 
+func buildPkgTaskManager(cmdRunner exec.Runner) pkg.PackageTaskManager {
+	pkgCmdProviders, err := pkg.BuildManagementCmdsProviders()
 	if err != nil {
-		return err
+		logrus.Warn(err.Error())
 	}
 
-	runner := Runner{
-		DataProvider:   fileDataProvider,
-		ExecutorRouter: execRouter,
+	return pkg.PackageTaskManager{
+		Runner:                     cmdRunner,
+		PackageManagerCmdProviders: pkgCmdProviders,
+		RefreshTracker:             pkg.NewRefreshTracker(pkg.DefaultRefreshStateDir()),
 	}
+}
 
-	err = runner.Run(context.Background(), scripts)
+func buildParser(
+	dataProvider RawDataProvider,
+	localFactsDir string,
+	variables map[string]interface{},
+	secretVariables []string,
+	pkgTaskManager pkg.PackageTaskManager,
+) Builder {
+	lazyTemplateVariables := map[string]LazyTemplateVariable{
+		utils.InstalledPackages: func() (interface{}, error) {
+			return pkgTaskManager.GetInstalledPackages(context.Background())
+		},
+		utils.LocalFacts: func() (interface{}, error) {
+			return utils.CollectLocalFacts(localFactsDir)
+		},
+	}
+	for factName, lazyFact := range utils.LazySystemFacts() {
+		lazyTemplateVariables[factName] = lazyFact
+	}
 
-	return err
+	return Builder{
+		DataProvider: dataProvider,
+		TaskBuilder: tasks.NewBuilderRouter(map[string]tasks.Builder{
+			tasks.TaskTypeCmdRun: &tasks.CmdRunTaskBuilder{},
+			tasks.FileManaged:    &tasks.FileManagedTaskBuilder{},
+			tasks.PkgInstalled:   &tasks.PkgTaskBuilder{},
+			tasks.PkgRemoved:     &tasks.PkgTaskBuilder{},
+			tasks.PkgUpgraded:    &tasks.PkgTaskBuilder{},
+			tasks.PkgRepoManaged: &tasks.PkgRepoTaskBuilder{},
+			tasks.PkgRepoAbsent:  &tasks.PkgRepoTaskBuilder{},
+		}),
+		TemplateVariablesProvider: utils.OSDataProvider{SkipSystemFacts: true, SkipLocalFacts: true},
+		Variables:                 variables,
+		SecretVariables:           secretVariables,
+		LazyTemplateVariables:     lazyTemplateVariables,
+	}
 }
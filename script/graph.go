@@ -0,0 +1,406 @@
+package script
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+)
+
+const (
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+	GraphFormatJSON    = "json"
+)
+
+// RequirementsGraph is the graph of requirements between scripts, an edge goes from the required script
+// to the script which requires it, so edges follow the execution order
+type RequirementsGraph struct {
+	Nodes  []GraphNode `json:"nodes"`
+	Edges  []GraphEdge `json:"edges"`
+	Cycles [][]string  `json:"cycles"`
+	// OrderConflicts are the requirements which contradict the explicit orders of the scripts
+	OrderConflicts []OrderConflict `json:"order_conflicts"`
+	// Order is the execution order of scripts, it's empty if the graph has cycles or order conflicts
+	Order []string `json:"order"`
+}
+
+type GraphNode struct {
+	ID        string   `json:"id"`
+	TaskTypes []string `json:"task_types"`
+	// Order is the explicit order of the script, see tasks.OrderField
+	Order string `json:"order,omitempty"`
+	// Missing is true for the required scripts which are not defined
+	Missing bool `json:"missing,omitempty"`
+	InCycle bool `json:"in_cycle,omitempty"`
+}
+
+// OrderConflict is a requirement of a script which should run before the required one according to their orders,
+// DependentOrder is the order of the dependent script or the earliest order of the scripts which depend on it
+type OrderConflict struct {
+	Required       string `json:"required"`
+	RequiredOrder  string `json:"required_order"`
+	Dependent      string `json:"dependent"`
+	DependentOrder string `json:"dependent_order"`
+}
+
+type GraphEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	InCycle bool   `json:"in_cycle,omitempty"`
+}
+
+// BuildRequirementsGraph builds the graph from the requisites of the script tasks, a typed requisite e.g. "- file: /etc/hosts"
+// adds edges from the scripts which have the referenced tasks, requisites between tasks of the same script are not shown
+func BuildRequirementsGraph(scrpts tasks.Scripts) RequirementsGraph {
+	graph := RequirementsGraph{
+		Nodes:          make([]GraphNode, 0, len(scrpts)),
+		Edges:          []GraphEdge{},
+		Cycles:         [][]string{},
+		OrderConflicts: []OrderConflict{},
+		Order:          []string{},
+	}
+
+	depGraph := newDependencyGraph(len(scrpts))
+	nodeIndexes := make(map[string]int, len(scrpts))
+	nodeOrders := make([]tasks.Order, 0, len(scrpts))
+	for _, script := range scrpts {
+		taskTypes := make([]string, 0, len(script.Tasks))
+		for _, task := range script.Tasks {
+			taskTypes = append(taskTypes, task.GetName())
+		}
+		order := scriptOrder(script)
+		nodeIndexes[script.ID] = len(graph.Nodes)
+		nodeOrders = append(nodeOrders, order)
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: script.ID, TaskTypes: taskTypes, Order: order.String()})
+	}
+
+	for scriptIndex, script := range scrpts {
+		for _, task := range script.Tasks {
+			for _, requisite := range taskRequisites(task) {
+				requiredScriptIDs := resolveRequisiteScripts(scrpts, requisite)
+				if len(requiredScriptIDs) == 0 {
+					requiredScriptIDs = []string{requisite.String()}
+					if _, ok := nodeIndexes[requisite.String()]; !ok {
+						nodeIndexes[requisite.String()] = depGraph.addNode()
+						nodeOrders = append(nodeOrders, tasks.Order{})
+						graph.Nodes = append(graph.Nodes, GraphNode{ID: requisite.String(), TaskTypes: []string{}, Missing: true})
+					}
+				}
+
+				for _, requiredScriptID := range requiredScriptIDs {
+					if requisite.IsTyped() && requiredScriptID == script.ID {
+						continue
+					}
+
+					if depGraph.addEdge(nodeIndexes[requiredScriptID], scriptIndex) {
+						graph.Edges = append(graph.Edges, GraphEdge{From: requiredScriptID, To: script.ID})
+					}
+				}
+			}
+		}
+	}
+
+	for _, cycle := range depGraph.findCycles() {
+		cycleIDs := make([]string, 0, len(cycle))
+		for _, nodeIndex := range cycle {
+			cycleIDs = append(cycleIDs, graph.Nodes[nodeIndex].ID)
+		}
+		graph.Cycles = append(graph.Cycles, cycleIDs)
+	}
+	graph.markCycles(nodeIndexes)
+
+	if len(graph.Cycles) > 0 {
+		return graph
+	}
+
+	effectiveOrders := depGraph.inheritOrders(nodeOrders)
+	for from, nextNodes := range depGraph.nextNodes {
+		for _, to := range nextNodes {
+			if nodeOrders[from].IsSet() && effectiveOrders[to].Before(nodeOrders[from]) {
+				graph.OrderConflicts = append(graph.OrderConflicts, OrderConflict{
+					Required:       graph.Nodes[from].ID,
+					RequiredOrder:  nodeOrders[from].String(),
+					Dependent:      graph.Nodes[to].ID,
+					DependentOrder: effectiveOrders[to].String(),
+				})
+			}
+		}
+	}
+	if len(graph.OrderConflicts) > 0 {
+		return graph
+	}
+
+	byOrder := func(i, j int) bool {
+		if effectiveOrders[i] != effectiveOrders[j] {
+			return effectiveOrders[i].Before(effectiveOrders[j])
+		}
+		return i < j
+	}
+	for _, nodeIndex := range depGraph.sortTopologically(byOrder) {
+		if !graph.Nodes[nodeIndex].Missing {
+			graph.Order = append(graph.Order, graph.Nodes[nodeIndex].ID)
+		}
+	}
+
+	return graph
+}
+
+func (g RequirementsGraph) markCycles(nodeIndexes map[string]int) {
+	cycleEdges := map[GraphEdge]bool{}
+	for _, cycle := range g.Cycles {
+		for i, nodeID := range cycle {
+			g.Nodes[nodeIndexes[nodeID]].InCycle = true
+			cycleEdges[GraphEdge{From: nodeID, To: cycle[(i+1)%len(cycle)]}] = true
+		}
+	}
+
+	for i := range g.Edges {
+		g.Edges[i].InCycle = cycleEdges[g.Edges[i]]
+	}
+}
+
+// dependencyGraph is a directed graph of nodes which are identified by their positions in the document,
+// an edge goes from a node to the node which depends on it, it's used for both scripts and tasks
+type dependencyGraph struct {
+	nextNodes [][]int
+	edgesSet  map[[2]int]bool
+}
+
+func newDependencyGraph(nodesCount int) *dependencyGraph {
+	return &dependencyGraph{
+		nextNodes: make([][]int, nodesCount),
+		edgesSet:  map[[2]int]bool{},
+	}
+}
+
+func (dg *dependencyGraph) addNode() int {
+	dg.nextNodes = append(dg.nextNodes, nil)
+
+	return len(dg.nextNodes) - 1
+}
+
+// addEdge gives false if the edge already exists
+func (dg *dependencyGraph) addEdge(from, to int) bool {
+	edge := [2]int{from, to}
+	if dg.edgesSet[edge] {
+		return false
+	}
+	dg.edgesSet[edge] = true
+	dg.nextNodes[from] = append(dg.nextNodes[from], to)
+
+	return true
+}
+
+// sortTopologically gives the order of nodes where each node goes after the nodes it depends on, it's Kahn's algorithm
+// which takes the first node by the less function among the ones with met dependencies, so the order is stable,
+// the nodes in cycles are not included
+func (dg *dependencyGraph) sortTopologically(less func(i, j int) bool) []int {
+	dependenciesCount := make([]int, len(dg.nextNodes))
+	for _, nextNodes := range dg.nextNodes {
+		for _, nextNode := range nextNodes {
+			dependenciesCount[nextNode]++
+		}
+	}
+
+	readyNodes := &nodeIndexesHeap{less: less}
+	for node, count := range dependenciesCount {
+		if count == 0 {
+			heap.Push(readyNodes, node)
+		}
+	}
+
+	order := make([]int, 0, len(dg.nextNodes))
+	for readyNodes.Len() > 0 {
+		node := heap.Pop(readyNodes).(int)
+		order = append(order, node)
+
+		for _, nextNode := range dg.nextNodes[node] {
+			dependenciesCount[nextNode]--
+			if dependenciesCount[nextNode] == 0 {
+				heap.Push(readyNodes, nextNode)
+			}
+		}
+	}
+
+	return order
+}
+
+// findCycles walks the graph in depth and gives a cycle for each edge which leads back to a node in the current path,
+// so every group of nodes which depend on each other is reported with at least one cycle
+func (dg *dependencyGraph) findCycles() [][]int {
+	cycles := [][]int{}
+	visited := make([]bool, len(dg.nextNodes))
+	pathPositions := map[int]int{}
+	path := []int{}
+
+	var visit func(node int)
+	visit = func(node int) {
+		visited[node] = true
+		pathPositions[node] = len(path)
+		path = append(path, node)
+
+		for _, nextNode := range dg.nextNodes[node] {
+			if pos, ok := pathPositions[nextNode]; ok {
+				cycle := make([]int, len(path)-pos)
+				copy(cycle, path[pos:])
+				cycles = append(cycles, cycle)
+				continue
+			}
+			if !visited[nextNode] {
+				visit(nextNode)
+			}
+		}
+
+		path = path[:len(path)-1]
+		delete(pathPositions, node)
+	}
+
+	for node := range dg.nextNodes {
+		if !visited[node] {
+			visit(node)
+		}
+	}
+
+	return cycles
+}
+
+// inheritOrders gives the orders of the nodes where a node gets the earliest order of the nodes which depend on it
+// if it's earlier than its own one, so e.g. the scripts required by a script with order first run first as well
+func (dg *dependencyGraph) inheritOrders(orders []tasks.Order) []tasks.Order {
+	effectiveOrders := make([]tasks.Order, len(orders))
+	copy(effectiveOrders, orders)
+
+	// the dependent nodes go after their dependencies in the topological order, so they are processed first in the reverse one
+	sortedNodes := dg.sortTopologically(byNodeIndex)
+	for i := len(sortedNodes) - 1; i >= 0; i-- {
+		node := sortedNodes[i]
+		for _, nextNode := range dg.nextNodes[node] {
+			if effectiveOrders[nextNode].Before(effectiveOrders[node]) {
+				effectiveOrders[node] = effectiveOrders[nextNode]
+			}
+		}
+	}
+
+	return effectiveOrders
+}
+
+// byNodeIndex keeps the document order of nodes
+func byNodeIndex(i, j int) bool {
+	return i < j
+}
+
+// nodeIndexesHeap gives the first node index by the less function
+type nodeIndexesHeap struct {
+	nodes []int
+	less  func(i, j int) bool
+}
+
+func (h nodeIndexesHeap) Len() int           { return len(h.nodes) }
+func (h nodeIndexesHeap) Less(i, j int) bool { return h.less(h.nodes[i], h.nodes[j]) }
+func (h nodeIndexesHeap) Swap(i, j int)      { h.nodes[i], h.nodes[j] = h.nodes[j], h.nodes[i] }
+
+func (h *nodeIndexesHeap) Push(x interface{}) {
+	h.nodes = append(h.nodes, x.(int))
+}
+
+func (h *nodeIndexesHeap) Pop() interface{} {
+	item := h.nodes[len(h.nodes)-1]
+	h.nodes = h.nodes[:len(h.nodes)-1]
+
+	return item
+}
+
+func (g RequirementsGraph) orderComment() string {
+	if len(g.Cycles) > 0 {
+		return "execution order is undefined because of cyclic requirements"
+	}
+
+	if len(g.OrderConflicts) > 0 {
+		return "execution order is undefined because of contradictory script orders"
+	}
+
+	return "execution order: " + strings.Join(g.Order, ", ")
+}
+
+// ToDOT gives the graph in the Graphviz format, scripts and requirements which form cycles are red
+func (g RequirementsGraph) ToDOT() string {
+	sb := strings.Builder{}
+	sb.WriteString("digraph requirements {\n")
+	sb.WriteString(fmt.Sprintf("  // %s\n", g.orderComment()))
+	sb.WriteString("  node [shape=box];\n")
+
+	for _, node := range g.Nodes {
+		label := node.ID + "\\n" + strings.Join(node.TaskTypes, ", ")
+		attrs := []string{fmt.Sprintf("label=%s", quoteDOT(label))}
+		if node.Missing {
+			attrs = []string{fmt.Sprintf("label=%s", quoteDOT(node.ID+"\\n(missing)")), "style=dashed"}
+		}
+		if node.InCycle {
+			attrs = append(attrs, "color=red")
+		}
+		sb.WriteString(fmt.Sprintf("  %s [%s];\n", quoteDOT(node.ID), strings.Join(attrs, ", ")))
+	}
+
+	for _, edge := range g.Edges {
+		attrs := ""
+		if edge.InCycle {
+			attrs = " [color=red]"
+		}
+		sb.WriteString(fmt.Sprintf("  %s -> %s%s;\n", quoteDOT(edge.From), quoteDOT(edge.To), attrs))
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// ToMermaid gives the graph as a mermaid flowchart, scripts and requirements which form cycles are red
+func (g RequirementsGraph) ToMermaid() string {
+	sb := strings.Builder{}
+	sb.WriteString("flowchart TD\n")
+	sb.WriteString(fmt.Sprintf("  %%%% %s\n", g.orderComment()))
+
+	// script ids can contain any characters, so nodes get generated ids and the script ids are used as labels
+	nodeIDs := make(map[string]string, len(g.Nodes))
+	cycleNodeIDs := []string{}
+	for i, node := range g.Nodes {
+		nodeID := fmt.Sprintf("n%d", i)
+		nodeIDs[node.ID] = nodeID
+
+		label := escapeMermaid(node.ID) + "<br/>" + escapeMermaid(strings.Join(node.TaskTypes, ", "))
+		if node.Missing {
+			label = escapeMermaid(node.ID) + "<br/>(missing)"
+		}
+		sb.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", nodeID, label))
+
+		if node.InCycle {
+			cycleNodeIDs = append(cycleNodeIDs, nodeID)
+		}
+	}
+
+	cycleEdgeIndexes := []string{}
+	for i, edge := range g.Edges {
+		sb.WriteString(fmt.Sprintf("  %s --> %s\n", nodeIDs[edge.From], nodeIDs[edge.To]))
+		if edge.InCycle {
+			cycleEdgeIndexes = append(cycleEdgeIndexes, fmt.Sprint(i))
+		}
+	}
+
+	if len(cycleNodeIDs) > 0 {
+		sb.WriteString("  classDef cycle stroke:#f00,stroke-width:2px\n")
+		sb.WriteString(fmt.Sprintf("  class %s cycle\n", strings.Join(cycleNodeIDs, ",")))
+		sb.WriteString(fmt.Sprintf("  linkStyle %s stroke:#f00,stroke-width:2px\n", strings.Join(cycleEdgeIndexes, ",")))
+	}
+
+	return sb.String()
+}
+
+func quoteDOT(val string) string {
+	return `"` + strings.ReplaceAll(val, `"`, `\"`) + `"`
+}
+
+func escapeMermaid(val string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(val)
+}
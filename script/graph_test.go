@@ -0,0 +1,200 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+)
+
+func TestBuildRequirementsGraph(t *testing.T) {
+	testCases := []struct {
+		name          string
+		scripts       tasks.Scripts
+		expectedGraph RequirementsGraph
+	}{
+		{
+			name: "acyclic graph",
+			scripts: tasks.Scripts{
+				{
+					ID: "app",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{TypeName: tasks.FileManaged},
+						&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Requirements: []string{"pkgs", "repo"}},
+					},
+				},
+				{
+					ID:    "pkgs",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.PkgInstalled, Requirements: []string{"repo"}}},
+				},
+				{
+					ID:    "repo",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.PkgRepoManaged}},
+				},
+			},
+			expectedGraph: RequirementsGraph{
+				Nodes: []GraphNode{
+					{ID: "app", TaskTypes: []string{tasks.FileManaged, tasks.TaskTypeCmdRun}},
+					{ID: "pkgs", TaskTypes: []string{tasks.PkgInstalled}},
+					{ID: "repo", TaskTypes: []string{tasks.PkgRepoManaged}},
+				},
+				Edges: []GraphEdge{
+					{From: "pkgs", To: "app"},
+					{From: "repo", To: "app"},
+					{From: "repo", To: "pkgs"},
+				},
+				Cycles:         [][]string{},
+				OrderConflicts: []OrderConflict{},
+				Order:          []string{"repo", "pkgs", "app"},
+			},
+		},
+		{
+			name: "cycles and missing requirements",
+			scripts: tasks.Scripts{
+				{
+					ID:    "one",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Requirements: []string{"two", "missing"}}},
+				},
+				{
+					ID:    "two",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Requirements: []string{"one"}}},
+				},
+				{
+					ID:    "three",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Requirements: []string{"three", "one"}}},
+				},
+			},
+			expectedGraph: RequirementsGraph{
+				Nodes: []GraphNode{
+					{ID: "one", TaskTypes: []string{tasks.TaskTypeCmdRun}, InCycle: true},
+					{ID: "two", TaskTypes: []string{tasks.TaskTypeCmdRun}, InCycle: true},
+					{ID: "three", TaskTypes: []string{tasks.TaskTypeCmdRun}, InCycle: true},
+					{ID: "missing", TaskTypes: []string{}, Missing: true},
+				},
+				Edges: []GraphEdge{
+					{From: "two", To: "one", InCycle: true},
+					{From: "missing", To: "one"},
+					{From: "one", To: "two", InCycle: true},
+					{From: "three", To: "three", InCycle: true},
+					{From: "one", To: "three"},
+				},
+				Cycles:         [][]string{{"one", "two"}, {"three"}},
+				OrderConflicts: []OrderConflict{},
+				Order:          []string{},
+			},
+		},
+		{
+			name: "explicit orders",
+			scripts: tasks.Scripts{
+				{ID: "report", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Order: tasks.Order{Kind: tasks.OrderLast}}}},
+				{ID: "app", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun}}},
+				{ID: "second", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Order: tasks.Order{Kind: tasks.OrderNumbered, Number: 2}}}},
+				{ID: "first", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Order: tasks.Order{Kind: tasks.OrderNumbered, Number: 1}}}},
+				{ID: "proxy", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Requirements: []string{"repo"}, Order: tasks.Order{Kind: tasks.OrderFirst}}}},
+				{ID: "repo", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.PkgRepoManaged}}},
+			},
+			expectedGraph: RequirementsGraph{
+				Nodes: []GraphNode{
+					{ID: "report", TaskTypes: []string{tasks.TaskTypeCmdRun}, Order: "last"},
+					{ID: "app", TaskTypes: []string{tasks.TaskTypeCmdRun}},
+					{ID: "second", TaskTypes: []string{tasks.TaskTypeCmdRun}, Order: "2"},
+					{ID: "first", TaskTypes: []string{tasks.TaskTypeCmdRun}, Order: "1"},
+					{ID: "proxy", TaskTypes: []string{tasks.TaskTypeCmdRun}, Order: "first"},
+					{ID: "repo", TaskTypes: []string{tasks.PkgRepoManaged}},
+				},
+				Edges:          []GraphEdge{{From: "repo", To: "proxy"}},
+				Cycles:         [][]string{},
+				OrderConflicts: []OrderConflict{},
+				Order:          []string{"repo", "proxy", "first", "second", "app", "report"},
+			},
+		},
+		{
+			name: "order conflicts",
+			scripts: tasks.Scripts{
+				{ID: "report", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Order: tasks.Order{Kind: tasks.OrderLast}}}},
+				{ID: "app", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Requirements: []string{"report"}}}},
+				{ID: "second", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Order: tasks.Order{Kind: tasks.OrderNumbered, Number: 2}}}},
+				{ID: "first", Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Requirements: []string{"second"}, Order: tasks.Order{Kind: tasks.OrderNumbered, Number: 1}}}},
+			},
+			expectedGraph: RequirementsGraph{
+				Nodes: []GraphNode{
+					{ID: "report", TaskTypes: []string{tasks.TaskTypeCmdRun}, Order: "last"},
+					{ID: "app", TaskTypes: []string{tasks.TaskTypeCmdRun}},
+					{ID: "second", TaskTypes: []string{tasks.TaskTypeCmdRun}, Order: "2"},
+					{ID: "first", TaskTypes: []string{tasks.TaskTypeCmdRun}, Order: "1"},
+				},
+				Edges:  []GraphEdge{{From: "report", To: "app"}, {From: "second", To: "first"}},
+				Cycles: [][]string{},
+				OrderConflicts: []OrderConflict{
+					{Required: "report", RequiredOrder: "last", Dependent: "app", DependentOrder: ""},
+					{Required: "second", RequiredOrder: "2", Dependent: "first", DependentOrder: "1"},
+				},
+				Order: []string{},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			actualGraph := BuildRequirementsGraph(tc.scripts)
+			assert.Equal(t, tc.expectedGraph, actualGraph)
+		})
+	}
+}
+
+func TestRequirementsGraphFormats(t *testing.T) {
+	graph := RequirementsGraph{
+		Nodes: []GraphNode{
+			{ID: "one", TaskTypes: []string{tasks.TaskTypeCmdRun}, InCycle: true},
+			{ID: `"two"`, TaskTypes: []string{tasks.TaskTypeCmdRun, tasks.FileManaged}, InCycle: true},
+			{ID: "missing", TaskTypes: []string{}, Missing: true},
+		},
+		Edges: []GraphEdge{
+			{From: `"two"`, To: "one", InCycle: true},
+			{From: "missing", To: "one"},
+			{From: "one", To: `"two"`, InCycle: true},
+		},
+		Cycles: [][]string{{"one", `"two"`}},
+		Order:  []string{},
+	}
+
+	assert.Equal(t, `digraph requirements {
+  // execution order is undefined because of cyclic requirements
+  node [shape=box];
+  "one" [label="one\ncmd.run", color=red];
+  "\"two\"" [label="\"two\"\ncmd.run, file.managed", color=red];
+  "missing" [label="missing\n(missing)", style=dashed];
+  "\"two\"" -> "one" [color=red];
+  "missing" -> "one";
+  "one" -> "\"two\"" [color=red];
+}
+`, graph.ToDOT())
+
+	assert.Equal(t, `flowchart TD
+  %% execution order is undefined because of cyclic requirements
+  n0["one<br/>cmd.run"]
+  n1["#quot;two#quot;<br/>cmd.run, file.managed"]
+  n2["missing<br/>(missing)"]
+  n1 --> n0
+  n2 --> n0
+  n0 --> n1
+  classDef cycle stroke:#f00,stroke-width:2px
+  class n0,n1 cycle
+  linkStyle 0,2 stroke:#f00,stroke-width:2px
+`, graph.ToMermaid())
+
+	acyclicGraph := RequirementsGraph{
+		Nodes: []GraphNode{{ID: "one", TaskTypes: []string{tasks.TaskTypeCmdRun}}, {ID: "two", TaskTypes: []string{tasks.PkgInstalled}}},
+		Edges: []GraphEdge{{From: "one", To: "two"}},
+		Order: []string{"one", "two"},
+	}
+
+	assert.Equal(t, `flowchart TD
+  %% execution order: one, two
+  n0["one<br/>cmd.run"]
+  n1["two<br/>pkg.installed"]
+  n0 --> n1
+`, acyclicGraph.ToMermaid())
+}
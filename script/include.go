@@ -0,0 +1,299 @@
+package script
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+)
+
+const (
+	// IncludeSection is the optional top-level list of script files which should be loaded together with the current one
+	IncludeSection = "include"
+	// ExtendSection is the optional top-level section which overrides fields of tasks from the included files
+	ExtendSection = "extend"
+
+	includeDownloadTimeout = time.Second * 30
+)
+
+type rawScripts map[string]map[string][]map[string]interface{}
+
+type rawScriptFile struct {
+	// Vars are applied before rendering of the file, see Builder.addScriptVariables
+	Vars    map[string]interface{} `yaml:"vars"`
+	Include []string               `yaml:"include"`
+	Extend  rawScripts             `yaml:"extend"`
+	Scripts rawScripts             `yaml:",inline"`
+	// ScriptIDs and TaskTypes keep the document order of the scripts and their tasks which is lost in the maps
+	ScriptIDs []string            `yaml:"-"`
+	TaskTypes map[string][]string `yaml:"-"`
+}
+
+// LocatedDataProvider is implemented by data providers which know the location of their data,
+// it's needed to resolve relative includes
+type LocatedDataProvider interface {
+	GetLocation() string
+}
+
+type URLDataProvider struct {
+	URL string
+}
+
+func (udp URLDataProvider) Read() ([]byte, error) {
+	client := http.Client{Timeout: includeDownloadTimeout}
+	resp, err := client.Get(udp.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download '%s', bad response status code %d", udp.URL, resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (udp URLDataProvider) GetLocation() string {
+	return udp.URL
+}
+
+func newDataProvider(location string) RawDataProvider {
+	if isURLLocation(location) {
+		return URLDataProvider{URL: location}
+	}
+
+	return FileDataProvider{Path: location}
+}
+
+func isURLLocation(location string) bool {
+	lowerLocation := strings.ToLower(location)
+	return strings.HasPrefix(lowerLocation, "http://") || strings.HasPrefix(lowerLocation, "https://")
+}
+
+func normalizeLocation(location string) string {
+	if location == "" || isURLLocation(location) {
+		return location
+	}
+
+	absLocation, err := filepath.Abs(location)
+	if err != nil {
+		return location
+	}
+
+	return absLocation
+}
+
+// resolveInclude converts an include item to locations, relative paths are resolved against the including file
+// and globs are expanded for local files
+func resolveInclude(baseLocation, includeItem string) ([]string, error) {
+	if isURLLocation(includeItem) {
+		return []string{includeItem}, nil
+	}
+
+	if isURLLocation(baseLocation) {
+		baseURL, err := url.Parse(baseLocation)
+		if err != nil {
+			return nil, err
+		}
+		includeURL, err := url.Parse(includeItem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include '%s': %w", includeItem, err)
+		}
+
+		return []string{baseURL.ResolveReference(includeURL).String()}, nil
+	}
+
+	includePath := includeItem
+	if !filepath.IsAbs(includePath) && baseLocation != "" {
+		includePath = filepath.Join(filepath.Dir(baseLocation), includePath)
+	}
+
+	if !strings.ContainsAny(includePath, "*?[") {
+		return []string{includePath}, nil
+	}
+
+	matches, err := filepath.Glob(includePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern '%s': %w", includeItem, err)
+	}
+
+	if len(matches) == 0 {
+		logrus.Debugf("no files match the include pattern '%s'", includeItem)
+	}
+
+	return matches, nil
+}
+
+// scriptsLoader collects scripts from a file and the files it includes into one set
+type scriptsLoader struct {
+	builder         Builder
+	variables       map[string]interface{}
+	loadedLocations map[string]bool
+	scripts         rawScripts
+	scriptIDs       []string
+	taskTypes       map[string][]string
+	scriptLocations map[string]string
+	extends         []rawScripts
+}
+
+func newScriptsLoader(builder Builder, variables map[string]interface{}) *scriptsLoader {
+	return &scriptsLoader{
+		builder:         builder,
+		variables:       variables,
+		loadedLocations: map[string]bool{},
+		scripts:         rawScripts{},
+		scriptIDs:       []string{},
+		taskTypes:       map[string][]string{},
+		scriptLocations: map[string]string{},
+		extends:         []rawScripts{},
+	}
+}
+
+func (sl *scriptsLoader) load(dataProvider RawDataProvider) error {
+	location := ""
+	if locatedDataProvider, ok := dataProvider.(LocatedDataProvider); ok {
+		location = normalizeLocation(locatedDataProvider.GetLocation())
+	}
+
+	if location != "" {
+		if sl.loadedLocations[location] {
+			logrus.Debugf("'%s' is already loaded, will skip it", location)
+			return nil
+		}
+		sl.loadedLocations[location] = true
+	}
+
+	scriptFile, err := sl.builder.readScriptFile(dataProvider, sl.variables)
+	if err != nil {
+		return err
+	}
+
+	for _, includeItem := range scriptFile.Include {
+		includeLocations, err := resolveInclude(location, includeItem)
+		if err != nil {
+			return err
+		}
+
+		for _, includeLocation := range includeLocations {
+			logrus.Debugf("will include '%s'", includeLocation)
+			err = sl.load(newDataProvider(includeLocation))
+			if err != nil {
+				return fmt.Errorf("failed to include '%s': %w", includeLocation, err)
+			}
+		}
+	}
+
+	// scripts of the included files go first, the order is used to sort the scripts which don't require each other
+	for _, scriptID := range scriptFile.ScriptIDs {
+		rawTasks := scriptFile.Scripts[scriptID]
+		if definedLocation, ok := sl.scriptLocations[scriptID]; ok {
+			return fmt.Errorf(
+				"duplicate script id '%s' in '%s', it's already defined in '%s', use the '%s' section to change it",
+				scriptID,
+				location,
+				definedLocation,
+				ExtendSection,
+			)
+		}
+		sl.scripts[scriptID] = rawTasks
+		sl.scriptIDs = append(sl.scriptIDs, scriptID)
+		sl.taskTypes[scriptID] = scriptFile.TaskTypes[scriptID]
+		sl.scriptLocations[scriptID] = location
+	}
+
+	if len(scriptFile.Extend) > 0 {
+		sl.extends = append(sl.extends, scriptFile.Extend)
+	}
+
+	return nil
+}
+
+// applyExtends overrides fields of the loaded tasks in the order in which extend sections were loaded
+func (sl *scriptsLoader) applyExtends() error {
+	errs := &utils.Errors{}
+	for _, extend := range sl.extends {
+		scriptIDs := make([]string, 0, len(extend))
+		for scriptID := range extend {
+			scriptIDs = append(scriptIDs, scriptID)
+		}
+		sort.Strings(scriptIDs)
+
+		for _, scriptID := range scriptIDs {
+			extendTasks := extend[scriptID]
+			rawTasks, ok := sl.scripts[scriptID]
+			if !ok {
+				errs.Add(fmt.Errorf("cannot extend missing script '%s'", scriptID))
+				continue
+			}
+
+			for taskTypeID, extendContext := range extendTasks {
+				taskContext, ok := rawTasks[taskTypeID]
+				if !ok {
+					errs.Add(fmt.Errorf("cannot extend missing task '%s' in script '%s'", taskTypeID, scriptID))
+					continue
+				}
+
+				rawTasks[taskTypeID] = extendTaskContext(taskContext, extendContext)
+			}
+		}
+	}
+
+	return errs.ToError()
+}
+
+// extendTaskContext replaces the task fields with the extending ones, requisites e.g. require or watch are appended
+// rather than replaced
+func extendTaskContext(taskContext, extendContext []map[string]interface{}) []map[string]interface{} {
+	res := make([]map[string]interface{}, len(taskContext))
+	copy(res, taskContext)
+
+	for _, extendItem := range extendContext {
+		for key, val := range extendItem {
+			itemIndex := -1
+			for i, contextItem := range res {
+				if _, ok := contextItem[key]; ok {
+					itemIndex = i
+					break
+				}
+			}
+
+			if itemIndex < 0 {
+				res = append(res, map[string]interface{}{key: val})
+				continue
+			}
+
+			if tasks.IsRequisitesField(key) {
+				requirements := make([]interface{}, 0)
+				requirements = append(requirements, toInterfaceSlice(res[itemIndex][key])...)
+				val = append(requirements, toInterfaceSlice(val)...)
+			}
+
+			newItem := make(map[string]interface{}, len(res[itemIndex]))
+			for itemKey, itemVal := range res[itemIndex] {
+				newItem[itemKey] = itemVal
+			}
+			newItem[key] = val
+			res[itemIndex] = newItem
+		}
+	}
+
+	return res
+}
+
+func toInterfaceSlice(val interface{}) []interface{} {
+	if items, ok := val.([]interface{}); ok {
+		return items
+	}
+
+	return []interface{}{val}
+}
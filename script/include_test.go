@@ -0,0 +1,201 @@
+package script
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+)
+
+func buildScriptsFromFile(path string, variables map[string]interface{}) (tasks.Scripts, error) {
+	builder := Builder{
+		DataProvider:              FileDataProvider{Path: path},
+		TaskBuilder:               &TaskBuilderMock{},
+		TemplateVariablesProvider: TemplateVariablesProviderMock{Variables: variables},
+	}
+
+	scripts, err := builder.BuildScripts()
+	sort.Slice(scripts, func(i, j int) bool {
+		return scripts[i].ID < scripts[j].ID
+	})
+
+	return scripts, err
+}
+
+func TestBuildScriptsWithIncludes(t *testing.T) {
+	scripts, err := buildScriptsFromFile(filepath.Join("yaml", "include", "main.yaml"), map[string]interface{}{"user": "app"})
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, tasks.Scripts{
+		{
+			ID: "app",
+			Tasks: []tasks.Task{
+				&TaskBuilderTaskMock{
+					TypeName: tasks.TaskTypeCmdRun,
+					Path:     "app.cmd.run[1]",
+					Context: []map[string]interface{}{
+						{tasks.NameField: "./start.sh"},
+						{tasks.RequireField: []interface{}{"base-dirs"}},
+					},
+				},
+			},
+		},
+		{
+			ID: "base-dirs",
+			Tasks: []tasks.Task{
+				&TaskBuilderTaskMock{
+					TypeName: tasks.TaskTypeCmdRun,
+					Path:     "base-dirs.cmd.run[1]",
+					Context: []map[string]interface{}{
+						{tasks.NameField: "mkdir -p /opt/app"},
+						{tasks.RequireField: []interface{}{"users", "packages"}},
+					},
+				},
+			},
+		},
+		{
+			ID: "packages",
+			Tasks: []tasks.Task{
+				&TaskBuilderTaskMock{
+					TypeName: tasks.TaskTypeCmdRun,
+					Path:     "packages.cmd.run[1]",
+					Context: []map[string]interface{}{
+						{tasks.NameField: "apt-get install -y nginx"},
+						{tasks.UserField: "nginx"},
+					},
+				},
+			},
+		},
+		{
+			ID: "users",
+			Tasks: []tasks.Task{
+				&TaskBuilderTaskMock{
+					TypeName: tasks.TaskTypeCmdRun,
+					Path:     "users.cmd.run[1]",
+					Context: []map[string]interface{}{
+						{tasks.NameField: "useradd app"},
+					},
+				},
+			},
+		},
+	}, scripts)
+}
+
+func TestBuildScriptsWithIncludeErrors(t *testing.T) {
+	includeDir, err := filepath.Abs(filepath.Join("yaml", "include"))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		FileName       string
+		ExpectedErrMsg string
+	}{
+		{
+			FileName: "duplicate.yaml",
+			ExpectedErrMsg: fmt.Sprintf(
+				"duplicate script id 'packages' in '%s', it's already defined in '%s', use the 'extend' section to change it",
+				filepath.Join(includeDir, "duplicate.yaml"),
+				filepath.Join(includeDir, "packages.yaml"),
+			),
+		},
+		{
+			FileName: "extendMissing.yaml",
+			ExpectedErrMsg: "cannot extend missing task 'file.managed' in script 'packages', " +
+				"cannot extend missing script 'services'",
+		},
+		{
+			FileName:       "missing.yaml",
+			ExpectedErrMsg: fmt.Sprintf("failed to include '%s': ", filepath.Join(includeDir, "notExisting.yaml")),
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.FileName, func(t *testing.T) {
+			_, err := buildScriptsFromFile(filepath.Join(includeDir, tc.FileName), map[string]interface{}{})
+			assert.Error(t, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), tc.ExpectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestBuildScriptsWithURLIncludes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scripts/web.yaml":
+			_, _ = w.Write([]byte("include:\n  - common.yaml\nweb:\n  cmd.run:\n    - name: nginx\n    - require: common\n"))
+		case "/scripts/common.yaml":
+			_, _ = w.Write([]byte("common:\n  cmd.run:\n    - name: apt-get update\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	builder := Builder{
+		DataProvider: RawDataProviderMock{
+			DataToReturn: fmt.Sprintf("include:\n  - %s/scripts/web.yaml\n", srv.URL),
+		},
+		TaskBuilder:               &TaskBuilderMock{},
+		TemplateVariablesProvider: TemplateVariablesProviderMock{},
+	}
+
+	scripts, err := builder.BuildScripts()
+	assert.NoError(t, err)
+
+	scriptIDs := make([]string, 0, len(scripts))
+	for _, script := range scripts {
+		scriptIDs = append(scriptIDs, script.ID)
+	}
+	sort.Strings(scriptIDs)
+	assert.Equal(t, []string{"common", "web"}, scriptIDs)
+
+	builder.DataProvider = RawDataProviderMock{
+		DataToReturn: fmt.Sprintf("include:\n  - %s/scripts/missing.yaml\n", srv.URL),
+	}
+	_, err = builder.BuildScripts()
+	assert.EqualError(
+		t,
+		err,
+		fmt.Sprintf(
+			"failed to include '%s/scripts/missing.yaml': failed to download '%s/scripts/missing.yaml', bad response status code 404",
+			srv.URL,
+			srv.URL,
+		),
+	)
+}
+
+func TestExtendTaskContext(t *testing.T) {
+	taskContext := []map[string]interface{}{
+		{tasks.NameField: "echo 1", tasks.ShellField: "bash"},
+		{tasks.RequireField: "one"},
+		{tasks.WatchField: "config"},
+	}
+
+	actualContext := extendTaskContext(taskContext, []map[string]interface{}{
+		{tasks.ShellField: "zsh"},
+		{tasks.RequireField: []interface{}{"two", "three"}},
+		{tasks.WatchField: []interface{}{map[interface{}]interface{}{"file": "/etc/app.conf"}}},
+		{tasks.CwdField: "/tmp"},
+	})
+
+	assert.Equal(t, []map[string]interface{}{
+		{tasks.NameField: "echo 1", tasks.ShellField: "zsh"},
+		{tasks.RequireField: []interface{}{"one", "two", "three"}},
+		{tasks.WatchField: []interface{}{"config", map[interface{}]interface{}{"file": "/etc/app.conf"}}},
+		{tasks.CwdField: "/tmp"},
+	}, actualContext)
+
+	assert.Equal(t, []map[string]interface{}{
+		{tasks.NameField: "echo 1", tasks.ShellField: "bash"},
+		{tasks.RequireField: "one"},
+		{tasks.WatchField: "config"},
+	}, taskContext)
+}
@@ -0,0 +1,70 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+)
+
+// scriptOrder gives the explicit order of the script which is defined by the order field of its tasks
+func scriptOrder(script tasks.Script) tasks.Order {
+	for _, task := range script.Tasks {
+		if orderedTask, ok := task.(tasks.OrderedTask); ok && orderedTask.GetOrder().IsSet() {
+			return orderedTask.GetOrder()
+		}
+	}
+
+	return tasks.Order{}
+}
+
+// validateScriptOrder checks that the tasks of the script don't define different orders since the order applies to the whole script
+func validateScriptOrder(script tasks.Script) error {
+	var firstOrderedTask tasks.Task
+	for _, task := range script.Tasks {
+		orderedTask, ok := task.(tasks.OrderedTask)
+		if !ok || !orderedTask.GetOrder().IsSet() {
+			continue
+		}
+
+		if firstOrderedTask == nil {
+			firstOrderedTask = task
+			continue
+		}
+
+		firstOrder := firstOrderedTask.(tasks.OrderedTask).GetOrder()
+		if orderedTask.GetOrder() != firstOrder {
+			return fmt.Errorf(
+				"different orders of script '%s': '%s' at path '%s.%s' and '%s' at path '%s.%s'",
+				script.ID,
+				firstOrder,
+				firstOrderedTask.GetPath(),
+				tasks.OrderField,
+				orderedTask.GetOrder(),
+				task.GetPath(),
+				tasks.OrderField,
+			)
+		}
+	}
+
+	return nil
+}
+
+func newOrderConflictsError(conflicts []OrderConflict) error {
+	conflictDescriptions := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		dependent := fmt.Sprintf("'%s' without order", conflict.Dependent)
+		if conflict.DependentOrder != "" {
+			dependent = fmt.Sprintf("'%s' with order '%s'", conflict.Dependent, conflict.DependentOrder)
+		}
+
+		conflictDescriptions = append(conflictDescriptions, fmt.Sprintf(
+			"'%s' with order '%s' is required by %s",
+			conflict.Required,
+			conflict.RequiredOrder,
+			dependent,
+		))
+	}
+
+	return fmt.Errorf("contradictory script orders are detected: %s", strings.Join(conflictDescriptions, ", "))
+}
@@ -0,0 +1,197 @@
+package script
+
+import (
+	"github.com/cloudradar-monitoring/tacoscript/tasks"
+)
+
+// fieldRequisites are the requisites of a task field e.g. require or onchanges
+type fieldRequisites struct {
+	field      string
+	requisites []tasks.Requisite
+	// isReverse is true for require_in and watch_in which reference the tasks depending on the task
+	isReverse bool
+}
+
+// taskFieldRequisites gives the requisites of the task grouped by their fields
+func taskFieldRequisites(task tasks.Task) []fieldRequisites {
+	fieldReqs := []fieldRequisites{{field: tasks.RequireField, requisites: task.GetRequirements()}}
+
+	requisitesTask, ok := task.(tasks.RequisitesTask)
+	if !ok {
+		return fieldReqs
+	}
+
+	requisites := requisitesTask.GetRequisites()
+
+	return append(
+		fieldReqs,
+		fieldRequisites{field: tasks.OnChangesField, requisites: requisites.OnChanges},
+		fieldRequisites{field: tasks.WatchField, requisites: requisites.Watch},
+		fieldRequisites{field: tasks.OnFailField, requisites: requisites.OnFail},
+		fieldRequisites{field: tasks.OnFailAnyField, requisites: requisites.OnFailAny},
+		fieldRequisites{field: tasks.OnFailAllField, requisites: requisites.OnFailAll},
+		fieldRequisites{field: tasks.RequireInField, requisites: requisites.RequireIn, isReverse: true},
+		fieldRequisites{field: tasks.WatchInField, requisites: requisites.WatchIn, isReverse: true},
+	)
+}
+
+// taskRequisites gives the requisites of the task including the ones injected by require_in and watch_in
+// of other tasks, each of them orders the tasks as require does
+func taskRequisites(task tasks.Task) []tasks.Requisite {
+	requisites := make([]tasks.Requisite, 0)
+	for _, fieldReqs := range taskFieldRequisites(task) {
+		if !fieldReqs.isReverse {
+			requisites = append(requisites, fieldReqs.requisites...)
+		}
+	}
+
+	if requisitesTask, ok := task.(tasks.RequisitesTask); ok {
+		injectedRequisites := requisitesTask.GetRequisites()
+		requisites = append(requisites, injectedRequisites.InjectedRequire...)
+		requisites = append(requisites, injectedRequisites.InjectedWatch...)
+	}
+
+	return requisites
+}
+
+// injectReverseRequisites adds the task with require_in or watch_in to the requisites of the referenced tasks,
+// e.g. "- require_in: [{cmd: app}]" of a pkg.installed task in the nginx script makes the cmd.run tasks of the app script
+// require "pkg.installed: nginx", the injected requisites are replaced on every call
+func injectReverseRequisites(scrpts tasks.Scripts) {
+	injectedRequire := map[string][]tasks.Requisite{}
+	injectedWatch := map[string][]tasks.Requisite{}
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			requisitesTask, ok := task.(tasks.RequisitesTask)
+			if !ok {
+				continue
+			}
+
+			requisites := requisitesTask.GetRequisites()
+			taskRequisite := tasks.Requisite{Module: task.GetName(), Ref: script.ID, Path: task.GetPath()}
+			for _, requisite := range requisites.RequireIn {
+				for _, targetTask := range resolveRequisite(scrpts, requisite) {
+					injectedRequire[targetTask.GetPath()] = append(injectedRequire[targetTask.GetPath()], taskRequisite)
+				}
+			}
+			for _, requisite := range requisites.WatchIn {
+				for _, targetTask := range resolveRequisite(scrpts, requisite) {
+					injectedWatch[targetTask.GetPath()] = append(injectedWatch[targetTask.GetPath()], taskRequisite)
+				}
+			}
+		}
+	}
+
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			if injector, ok := task.(tasks.RequisitesInjector); ok {
+				injector.InjectRequisites(injectedRequire[task.GetPath()], injectedWatch[task.GetPath()])
+			}
+		}
+	}
+}
+
+// resolveRequisite gives the tasks which are referenced by the requisite
+func resolveRequisite(scrpts tasks.Scripts, requisite tasks.Requisite) []tasks.Task {
+	requiredTasks := make([]tasks.Task, 0)
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			if requisite.Matches(script.ID, task) {
+				requiredTasks = append(requiredTasks, task)
+			}
+		}
+	}
+
+	return requiredTasks
+}
+
+// resolveRequisiteScripts gives ids of the scripts which have tasks referenced by the requisite,
+// a script requisite gives its script id even if the script has no tasks
+func resolveRequisiteScripts(scrpts tasks.Scripts, requisite tasks.Requisite) []string {
+	scriptIDs := make([]string, 0)
+	for _, script := range scrpts {
+		if !requisite.IsTyped() {
+			if script.ID == requisite.Ref {
+				scriptIDs = append(scriptIDs, script.ID)
+			}
+			continue
+		}
+
+		for _, task := range script.Tasks {
+			if requisite.Matches(script.ID, task) {
+				scriptIDs = append(scriptIDs, script.ID)
+				break
+			}
+		}
+	}
+
+	return scriptIDs
+}
+
+// hasTaskCycles is true if the tasks of the scripts require each other cyclically, a cycle between scripts
+// doesn't always mean a cycle between their tasks e.g. if a task of 'a' requires a task of 'b' which is
+// independent from the task of 'b' requiring a task of 'a'
+func hasTaskCycles(scrpts tasks.Scripts) bool {
+	type taskNode struct {
+		scriptID string
+		task     tasks.Task
+	}
+
+	nodes := make([]taskNode, 0)
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			nodes = append(nodes, taskNode{scriptID: script.ID, task: task})
+		}
+	}
+
+	depGraph := newDependencyGraph(len(nodes))
+	for taskIndex, node := range nodes {
+		for _, requisite := range taskRequisites(node.task) {
+			for requiredIndex, requiredNode := range nodes {
+				if requiredIndex != taskIndex && requisite.Matches(requiredNode.scriptID, requiredNode.task) {
+					depGraph.addEdge(requiredIndex, taskIndex)
+				}
+			}
+		}
+	}
+
+	return len(depGraph.findCycles()) > 0
+}
+
+// sortScriptTasks orders tasks of the script so each task goes after the tasks of the same script which it references,
+// the tasks keep the document order otherwise, cycles are given as lists of task paths
+func sortScriptTasks(script tasks.Script) (sortedTasks []tasks.Task, cycles [][]string) {
+	depGraph := newDependencyGraph(len(script.Tasks))
+	for taskIndex, task := range script.Tasks {
+		for _, requisite := range taskRequisites(task) {
+			if !requisite.IsTyped() {
+				continue
+			}
+
+			for requiredIndex, requiredTask := range script.Tasks {
+				if requiredIndex != taskIndex && requisite.Matches(script.ID, requiredTask) {
+					depGraph.addEdge(requiredIndex, taskIndex)
+				}
+			}
+		}
+	}
+
+	cycles = [][]string{}
+	for _, cycle := range depGraph.findCycles() {
+		cyclePaths := make([]string, 0, len(cycle))
+		for _, taskIndex := range cycle {
+			cyclePaths = append(cyclePaths, script.Tasks[taskIndex].GetPath())
+		}
+		cycles = append(cycles, cyclePaths)
+	}
+	if len(cycles) > 0 {
+		return script.Tasks, cycles
+	}
+
+	sortedTasks = make([]tasks.Task, 0, len(script.Tasks))
+	for _, taskIndex := range depGraph.sortTopologically(byNodeIndex) {
+		sortedTasks = append(sortedTasks, script.Tasks[taskIndex])
+	}
+
+	return sortedTasks, cycles
+}
@@ -32,6 +32,16 @@ type scriptSummary struct {
 	Changes           int           `yaml:"Changes"`
 	TotalFunctionsRun int           `yaml:"TotalFunctionsRun"`
 	TotalRunTime      time.Duration `yaml:"TotalRunTime"`
+
+	Recoveries []recoveryResult `yaml:"Recoveries,omitempty"`
+}
+
+// recoveryResult is the outcome of a task which was run because the tasks referenced by its onfail requisites failed
+type recoveryResult struct {
+	ID               string   `yaml:"ID"`
+	Path             string   `yaml:"Path"`
+	FailedRequisites []string `yaml:"FailedRequisites"`
+	Result           bool     `yaml:"Result"`
 }
 
 func intsToString(a []int) string {
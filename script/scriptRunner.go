@@ -9,6 +9,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 
+	"github.com/cloudradar-monitoring/tacoscript/applog"
 	"github.com/cloudradar-monitoring/tacoscript/exec"
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
 )
@@ -19,7 +20,10 @@ type Runner struct {
 }
 
 func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
-	SortScriptsRespectingRequirements(scripts)
+	err := SortScriptsRespectingRequirements(scripts)
+	if err != nil {
+		return err
+	}
 
 	result := scriptResult{}
 	scriptStart := time.Now()
@@ -28,6 +32,8 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
 	failed := 0
 	tasksRun := 0
 	changes := 0
+	taskResults := map[string]tasks.ExecutionResult{}
+	recoveries := make([]recoveryResult, 0)
 
 	for _, script := range scripts {
 		logrus.Debugf("will run script '%s'", script.ID)
@@ -38,8 +44,29 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
 				return err
 			}
 
-			logrus.Debugf("will run task '%s' at path '%s'", task.GetName(), task.GetPath())
-			res := executr.Execute(ctx, task)
+			var res tasks.ExecutionResult
+			requisitesComment := ""
+			failedRequisites := findFailedRequisites(scripts, task, taskResults)
+			onFailTriggers := findOnFailTriggers(scripts, task, taskResults)
+			switch {
+			case len(failedRequisites) > 0:
+				logrus.Debugf("will not run task '%s' at path '%s' since its requisites failed", task.GetName(), task.GetPath())
+				res = tasks.ExecutionResult{
+					Err: fmt.Errorf("one or more requisites failed: %s", strings.Join(failedRequisites, ", ")),
+				}
+				requisitesComment = res.Err.Error()
+			case !hasChangedRequisites(scripts, task, taskResults):
+				logrus.Debugf("will skip task '%s' at path '%s' since its requisites reported no changes", task.GetName(), task.GetPath())
+				res = tasks.ExecutionResult{IsSkipped: true}
+				requisitesComment = fmt.Sprintf("none of the %s or %s requisites reported changes", tasks.OnChangesField, tasks.WatchField)
+			case isRecoveryTask(task) && len(onFailTriggers) == 0:
+				logrus.Debugf("will skip task '%s' at path '%s' since its onfail requisites didn't fail", task.GetName(), task.GetPath())
+				res = tasks.ExecutionResult{IsSkipped: true}
+				requisitesComment = fmt.Sprintf("the %s requisites didn't fail", tasks.OnFailField)
+			default:
+				logrus.Debugf("will run task '%s' at path '%s'", task.GetName(), task.GetPath())
+				res = executr.Execute(ctx, task)
+			}
 
 			logrus.Debugf("finished task '%s' at path '%s', result: %s", task.GetName(), task.GetPath(), res.String())
 
@@ -48,6 +75,19 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
 			} else {
 				failed++
 			}
+			if res.Changed {
+				changes++
+			}
+			taskResults[task.GetPath()] = res
+
+			if isRecoveryTask(task) && len(onFailTriggers) > 0 && len(failedRequisites) == 0 && !res.IsSkipped {
+				recoveries = append(recoveries, recoveryResult{
+					ID:               script.ID,
+					Path:             task.GetPath(),
+					FailedRequisites: onFailTriggers,
+					Result:           res.Succeeded(),
+				})
+			}
 
 			tasksRun++
 
@@ -59,15 +99,17 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
 				name = strings.Join(cmdRunTask.GetNames(), "; ")
 				comment = `Command "` + name + `" run`
 
-				if !res.IsSkipped {
+				if res.Changed {
 					changeMap["pid"] = intsToString(res.Pids)
 					if runErr, ok := res.Err.(exec.RunError); ok {
 						changeMap["retcode"] = fmt.Sprintf("%d", runErr.ExitCode)
+					} else if res.SuccessRule != "" {
+						changeMap["retcode"] = fmt.Sprintf("%d", res.ExitCode)
+						changeMap["success_rule"] = res.SuccessRule
 					}
 
 					changeMap["stderr"] = res.StdErr
 					changeMap["stdout"] = res.StdOut
-					changes++
 				}
 			}
 
@@ -75,12 +117,30 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
 				name = pkgTask.NamedTask.Name
 			}
 
+			if res.Check != nil {
+				changeMap["check_retcode"] = fmt.Sprintf("%d", res.Check.ExitCode)
+				changeMap["check_stderr"] = res.Check.StdErr
+				changeMap["check_stdout"] = res.Check.StdOut
+			}
+
+			if res.Comment != "" {
+				comment = res.Comment
+			}
+
+			if requisitesComment != "" {
+				comment = requisitesComment
+			}
+
+			for key, val := range changeMap {
+				changeMap[key] = applog.Redact(val)
+			}
+
 			result.Results = append(result.Results, taskResult{
 				ID:       script.ID,
 				Function: task.GetName(),
-				Name:     name,
+				Name:     applog.Redact(name),
 				Result:   res.Succeeded(),
-				Comment:  comment,
+				Comment:  applog.Redact(comment),
 				Started:  onlyTime(taskStart),
 				Duration: res.Duration,
 				Changes:  changeMap,
@@ -96,6 +156,7 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
 		Changes:           changes,
 		TotalFunctionsRun: tasksRun,
 		TotalRunTime:      time.Since(scriptStart),
+		Recoveries:        recoveries,
 	}
 
 	y, err := yaml.Marshal(result)
@@ -106,3 +167,104 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts) error {
 
 	return nil
 }
+
+// findFailedRequisites gives the require and watch requisites of the task including the injected ones which reference failed tasks, the tasks
+// which were not run because of their failed requisites are failed too, so the failures propagate through the chain
+// of requisites, onchanges requisites of failed tasks just don't report changes
+func findFailedRequisites(scrpts tasks.Scripts, task tasks.Task, taskResults map[string]tasks.ExecutionResult) []string {
+	failedRequisites := make([]string, 0)
+	if len(taskResults) == 0 {
+		return failedRequisites
+	}
+
+	requisites := append([]tasks.Requisite{}, task.GetRequirements()...)
+	if requisitesTask, ok := task.(tasks.RequisitesTask); ok {
+		taskRequisites := requisitesTask.GetRequisites()
+		requisites = append(requisites, taskRequisites.Watch...)
+		requisites = append(requisites, taskRequisites.InjectedRequire...)
+		requisites = append(requisites, taskRequisites.InjectedWatch...)
+	}
+
+	for _, requisite := range requisites {
+		if isRequisiteFailed(scrpts, requisite, taskResults) {
+			failedRequisites = append(failedRequisites, requisite.String())
+		}
+	}
+
+	return failedRequisites
+}
+
+// isRequisiteFailed is true if one of the tasks referenced by the requisite failed
+func isRequisiteFailed(scrpts tasks.Scripts, requisite tasks.Requisite, taskResults map[string]tasks.ExecutionResult) bool {
+	for _, requiredTask := range resolveRequisite(scrpts, requisite) {
+		res, ok := taskResults[requiredTask.GetPath()]
+		if ok && !res.Succeeded() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRecoveryTask is true if the task has onfail requisites, so it runs only if the referenced tasks failed
+func isRecoveryTask(task tasks.Task) bool {
+	requisitesTask, ok := task.(tasks.RequisitesTask)
+
+	return ok && requisitesTask.GetRequisites().HasOnFail()
+}
+
+// findOnFailTriggers gives the onfail requisites of the task which reference failed tasks, it's empty if the task
+// should not run: onfail and onfail_any need one of their requisites to fail, onfail_all needs all of them to fail
+func findOnFailTriggers(scrpts tasks.Scripts, task tasks.Task, taskResults map[string]tasks.ExecutionResult) []string {
+	triggers := make([]string, 0)
+	requisitesTask, ok := task.(tasks.RequisitesTask)
+	if !ok {
+		return triggers
+	}
+	requisites := requisitesTask.GetRequisites()
+
+	for _, requisite := range append(append([]tasks.Requisite{}, requisites.OnFail...), requisites.OnFailAny...) {
+		if isRequisiteFailed(scrpts, requisite, taskResults) {
+			triggers = append(triggers, requisite.String())
+		}
+	}
+
+	allTriggers := make([]string, 0, len(requisites.OnFailAll))
+	for _, requisite := range requisites.OnFailAll {
+		if !isRequisiteFailed(scrpts, requisite, taskResults) {
+			allTriggers = nil
+			break
+		}
+		allTriggers = append(allTriggers, requisite.String())
+	}
+
+	return append(triggers, allTriggers...)
+}
+
+// hasChangedRequisites is true if the task has no onchanges and watch requisites
+// or one of the tasks referenced by them succeeded and reported changes
+func hasChangedRequisites(scrpts tasks.Scripts, task tasks.Task, taskResults map[string]tasks.ExecutionResult) bool {
+	requisitesTask, ok := task.(tasks.RequisitesTask)
+	if !ok {
+		return true
+	}
+
+	requisites := requisitesTask.GetRequisites()
+	changeRequisites := append([]tasks.Requisite{}, requisites.OnChanges...)
+	changeRequisites = append(changeRequisites, requisites.Watch...)
+	changeRequisites = append(changeRequisites, requisites.InjectedWatch...)
+	if len(changeRequisites) == 0 {
+		return true
+	}
+
+	for _, requisite := range changeRequisites {
+		for _, requiredTask := range resolveRequisite(scrpts, requisite) {
+			res := taskResults[requiredTask.GetPath()]
+			if res.Succeeded() && res.Changed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
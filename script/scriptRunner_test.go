@@ -2,6 +2,7 @@ package script
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
@@ -12,6 +13,11 @@ type TaskMock struct {
 	ID           string
 	ExecResult   tasks.ExecutionResult
 	Requirements []string
+	Requisites   []tasks.Requisite
+	OnChanges    []tasks.Requisite
+	Watch        []tasks.Requisite
+	OnFail       []tasks.Requisite
+	OnFailAll    []tasks.Requisite
 }
 
 func (tm *TaskMock) GetName() string {
@@ -23,20 +29,34 @@ func (tm *TaskMock) Validate() error {
 }
 
 func (tm *TaskMock) GetPath() string {
-	return ""
+	return tm.ID
 }
 
-func (tm *TaskMock) GetRequirements() []string {
-	return tm.Requirements
+func (tm *TaskMock) GetRequirements() []tasks.Requisite {
+	return append(tasks.ScriptRequisites(tm.Requirements), tm.Requisites...)
+}
+
+func (tm *TaskMock) GetRequisites() tasks.Requisites {
+	return tasks.Requisites{OnChanges: tm.OnChanges, Watch: tm.Watch, OnFail: tm.OnFail, OnFailAll: tm.OnFailAll}
+}
+
+func (tm *TaskMock) GetNames() []string {
+	return []string{tm.ID}
 }
 
 type ExecutorMock struct {
 	ExecResult tasks.ExecutionResult
-	InputTasks []tasks.Task
+	// ExecResultsByPath overrides ExecResult for the tasks with the given paths
+	ExecResultsByPath map[string]tasks.ExecutionResult
+	InputTasks        []tasks.Task
 }
 
 func (em *ExecutorMock) Execute(ctx context.Context, task tasks.Task) tasks.ExecutionResult {
 	em.InputTasks = append(em.InputTasks, task)
+	if res, ok := em.ExecResultsByPath[task.GetPath()]; ok {
+		return res
+	}
+
 	return em.ExecResult
 }
 
@@ -137,3 +157,147 @@ func TestRunner(t *testing.T) {
 		assert.Equal(t, testCase.ExpectedExecutedTasks, actualExecutedTasks)
 	}
 }
+
+func TestRunnerRequisitesFailure(t *testing.T) {
+	executorMock := &ExecutorMock{
+		ExecResultsByPath: map[string]tasks.ExecutionResult{
+			"install": {Err: errors.New("install failed")},
+		},
+	}
+
+	scripts := tasks.Scripts{
+		{ID: "install-script", Tasks: []tasks.Task{&TaskMock{ID: "install"}}},
+		{ID: "configure-script", Tasks: []tasks.Task{&TaskMock{ID: "configure", Requirements: []string{"install-script"}}}},
+		{ID: "restart-script", Tasks: []tasks.Task{&TaskMock{ID: "restart", Requisites: []tasks.Requisite{{Module: "TaskMock", Ref: "configure"}}}}},
+		{ID: "cleanup-script", Tasks: []tasks.Task{&TaskMock{ID: "cleanup"}}},
+	}
+
+	runr := Runner{
+		ExecutorRouter: tasks.ExecutorRouter{
+			Executors: map[string]tasks.Executor{
+				"TaskMock": executorMock,
+			},
+		},
+	}
+	err := runr.Run(context.Background(), scripts)
+	assert.NoError(t, err)
+
+	actualExecutedTasks := make([]string, 0, len(executorMock.InputTasks))
+	for _, task := range executorMock.InputTasks {
+		actualExecutedTasks = append(actualExecutedTasks, task.(*TaskMock).ID)
+	}
+
+	assert.Equal(t, []string{"install", "cleanup"}, actualExecutedTasks)
+
+	failedRequisites := findFailedRequisites(scripts, scripts[2].Tasks[0], map[string]tasks.ExecutionResult{
+		"configure": {Err: errors.New("configure failed")},
+	})
+	assert.Equal(t, []string{"TaskMock: configure"}, failedRequisites)
+}
+
+func TestRunnerChangeRequisites(t *testing.T) {
+	executorMock := &ExecutorMock{
+		ExecResultsByPath: map[string]tasks.ExecutionResult{
+			"config":        {Changed: true},
+			"failed-config": {Err: errors.New("write failed"), Changed: true},
+		},
+	}
+
+	config := tasks.Requisite{Module: "TaskMock", Ref: "config"}
+	unchangedConfig := tasks.Requisite{Module: "TaskMock", Ref: "unchanged-config"}
+	failedConfig := tasks.Requisite{Module: "TaskMock", Ref: "failed-config"}
+	scripts := tasks.Scripts{
+		{
+			ID: "web",
+			Tasks: []tasks.Task{
+				&TaskMock{ID: "restart", OnChanges: []tasks.Requisite{config}},
+				&TaskMock{ID: "reload", OnChanges: []tasks.Requisite{unchangedConfig}},
+				&TaskMock{ID: "reload-any", OnChanges: []tasks.Requisite{unchangedConfig, config}},
+				&TaskMock{ID: "watch-changed", Watch: []tasks.Requisite{config}},
+				&TaskMock{ID: "watch-unchanged", Watch: []tasks.Requisite{unchangedConfig}},
+				&TaskMock{ID: "onchanges-failed", OnChanges: []tasks.Requisite{failedConfig}},
+				&TaskMock{ID: "watch-failed", Watch: []tasks.Requisite{failedConfig}},
+				&TaskMock{ID: "config"},
+				&TaskMock{ID: "unchanged-config"},
+				&TaskMock{ID: "failed-config"},
+			},
+		},
+	}
+
+	runr := Runner{
+		ExecutorRouter: tasks.ExecutorRouter{
+			Executors: map[string]tasks.Executor{
+				"TaskMock": executorMock,
+			},
+		},
+	}
+	err := runr.Run(context.Background(), scripts)
+	assert.NoError(t, err)
+
+	actualExecutedTasks := make([]string, 0, len(executorMock.InputTasks))
+	for _, task := range executorMock.InputTasks {
+		actualExecutedTasks = append(actualExecutedTasks, task.(*TaskMock).ID)
+	}
+
+	assert.Equal(
+		t,
+		[]string{"config", "restart", "watch-changed", "unchanged-config", "reload-any", "failed-config"},
+		actualExecutedTasks,
+	)
+
+	taskResults := map[string]tasks.ExecutionResult{"failed-config": {Err: errors.New("write failed")}}
+	assert.Equal(t, []string{}, findFailedRequisites(scripts, &TaskMock{ID: "onchanges-failed", OnChanges: []tasks.Requisite{failedConfig}}, taskResults))
+	assert.Equal(t, []string{"TaskMock: failed-config"}, findFailedRequisites(scripts, &TaskMock{ID: "watch-failed", Watch: []tasks.Requisite{failedConfig}}, taskResults))
+}
+
+func TestRunnerOnFailRequisites(t *testing.T) {
+	executorMock := &ExecutorMock{
+		ExecResultsByPath: map[string]tasks.ExecutionResult{
+			"upgrade": {Err: errors.New("upgrade failed")},
+		},
+	}
+
+	upgrade := tasks.Requisite{Module: "TaskMock", Ref: "upgrade"}
+	backup := tasks.Requisite{Module: "TaskMock", Ref: "backup"}
+	scripts := tasks.Scripts{
+		{
+			ID: "app",
+			Tasks: []tasks.Task{
+				&TaskMock{ID: "rollback", OnFail: []tasks.Requisite{upgrade}},
+				&TaskMock{ID: "notify-backup", OnFail: []tasks.Requisite{backup}},
+				&TaskMock{ID: "notify-all", OnFailAll: []tasks.Requisite{upgrade, backup}},
+				&TaskMock{ID: "notify-upgrade-only", OnFailAll: []tasks.Requisite{upgrade}},
+				&TaskMock{ID: "backup"},
+				&TaskMock{ID: "upgrade", Requisites: []tasks.Requisite{backup}},
+				&TaskMock{ID: "restart", Requisites: []tasks.Requisite{upgrade}},
+			},
+		},
+	}
+
+	runr := Runner{
+		ExecutorRouter: tasks.ExecutorRouter{
+			Executors: map[string]tasks.Executor{
+				"TaskMock": executorMock,
+			},
+		},
+	}
+	err := runr.Run(context.Background(), scripts)
+	assert.NoError(t, err)
+
+	actualExecutedTasks := make([]string, 0, len(executorMock.InputTasks))
+	for _, task := range executorMock.InputTasks {
+		actualExecutedTasks = append(actualExecutedTasks, task.(*TaskMock).ID)
+	}
+
+	assert.Equal(t, []string{"backup", "upgrade", "rollback", "notify-upgrade-only"}, actualExecutedTasks)
+
+	taskResults := map[string]tasks.ExecutionResult{
+		"backup":  {Err: errors.New("backup failed")},
+		"upgrade": {Err: errors.New("one or more requisites failed: TaskMock: backup")},
+	}
+	assert.Equal(
+		t,
+		[]string{"TaskMock: upgrade", "TaskMock: backup"},
+		findOnFailTriggers(scripts, &TaskMock{ID: "notify-all", OnFailAll: []tasks.Requisite{upgrade, backup}}, taskResults),
+	)
+}
@@ -1,90 +1,79 @@
 package script
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
 )
 
-type positionedRequirement struct {
-	previous string
-	next     string
-}
-
-func SortScriptsRespectingRequirements(scripts tasks.Scripts) {
-	requirements, positionsMap := buildRequirementsMap(scripts)
-
-	for {
-		allReqMet, req := allRequirementsMet(requirements, positionsMap)
-		if allReqMet {
-			break
-		}
+// SortScriptsRespectingRequirements orders the scripts so each script goes after the scripts it requires
+// and the tasks of each script so each task goes after the tasks of the same script it requires,
+// the requisites injected by require_in and watch_in are respected as well, the scripts which don't depend on each other
+// are ordered by their order fields, the scripts and tasks without them keep their document order
+func SortScriptsRespectingRequirements(scripts tasks.Scripts) error {
+	injectReverseRequisites(scripts)
 
-		previousElementIndex := positionsMap[req.previous]
-		nexElementIndex := positionsMap[req.next]
+	graph := BuildRequirementsGraph(scripts)
+	if len(graph.Cycles) > 0 {
+		return newScriptCyclesError(scripts, graph.Cycles)
+	}
 
-		moveScriptToPosition(scripts, previousElementIndex, nexElementIndex)
+	if len(graph.OrderConflicts) > 0 {
+		return newOrderConflictsError(graph.OrderConflicts)
+	}
 
-		positionsMap = make(map[string]int, len(positionsMap))
-		for pos, script := range scripts {
-			positionsMap[script.ID] = pos
-		}
+	scriptsByID := make(map[string]tasks.Script, len(scripts))
+	for _, script := range scripts {
+		scriptsByID[script.ID] = script
 	}
-}
 
-func allRequirementsMet(requirements []positionedRequirement, positionsMap map[string]int) (bool, positionedRequirement) {
-	for _, req := range requirements {
-		if req.previous == req.next {
-			continue
-		}
-		previousPos, ok := positionsMap[req.previous]
-		if !ok {
-			continue
-		}
+	for i, scriptID := range graph.Order {
+		script := scriptsByID[scriptID]
 
-		nextPos, ok := positionsMap[req.next]
-		if !ok {
-			continue
+		sortedTasks, cycles := sortScriptTasks(script)
+		if len(cycles) > 0 {
+			return newCyclesError(cycles)
 		}
+		script.Tasks = sortedTasks
 
-		if previousPos > nextPos {
-			return false, req
-		}
+		scripts[i] = script
 	}
 
-	return true, positionedRequirement{}
+	return nil
 }
 
-func buildRequirementsMap(scrpts tasks.Scripts) (req []positionedRequirement, positionsMap map[string]int) {
-	req = make([]positionedRequirement, 0)
-	positionsMap = make(map[string]int)
-
-	for pos, script := range scrpts {
-		positionsMap[script.ID] = pos
-		for _, task := range script.Tasks {
-			for _, reqName := range task.GetRequirements() {
-				req = append(req, positionedRequirement{
-					previous: reqName,
-					next:     script.ID,
-				})
-			}
-		}
+func newCyclesError(cycles [][]string) error {
+	cycleDescriptions := make([]string, 0, len(cycles))
+	for _, cycle := range cycles {
+		cycleDescriptions = append(cycleDescriptions, formatCycle(cycle))
 	}
 
-	return req, positionsMap
+	return fmt.Errorf("cyclic requirements are detected: %s", strings.Join(cycleDescriptions, ", "))
 }
 
-func insertScript(array tasks.Scripts, value tasks.Script, index int) tasks.Scripts {
-	return append(array[:index], append(tasks.Scripts{value}, array[index:]...)...)
-}
+// newScriptCyclesError explains the cycles between scripts whose tasks don't require each other cyclically,
+// the scripts are executed as a whole one after another, so such requirements cannot be satisfied either
+func newScriptCyclesError(scrpts tasks.Scripts, cycles [][]string) error {
+	err := newCyclesError(cycles)
+	if hasTaskCycles(scrpts) {
+		return err
+	}
 
-func removeScript(array tasks.Scripts, index int) tasks.Scripts {
-	return append(array[:index], array[index+1:]...)
+	return fmt.Errorf(
+		"%v, the tasks don't require each other cyclically, but every script is executed as a whole, "+
+			"so two scripts cannot require tasks of each other, move the tasks to break the cycle",
+		err,
+	)
 }
 
-func moveScriptToPosition(array tasks.Scripts, srcIndex, dstIndex int) tasks.Scripts {
-	if srcIndex > len(array)-1 {
-		return tasks.Scripts{}
+// formatCycle gives the cycle as a chain of requirements e.g. 'a' -> 'b' -> 'a'
+func formatCycle(cycle []string) string {
+	cycleItems := make([]string, 0, len(cycle)+1)
+	for _, scriptID := range cycle {
+		cycleItems = append(cycleItems, fmt.Sprintf("'%s'", scriptID))
 	}
+	cycleItems = append(cycleItems, cycleItems[0])
 
-	value := array[srcIndex]
-	return insertScript(removeScript(array, srcIndex), value, dstIndex)
+	return strings.Join(cycleItems, " -> ")
 }
@@ -1,6 +1,7 @@
 package script
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
@@ -57,7 +58,7 @@ func TestSort(t *testing.T) {
 					Tasks: []tasks.Task{},
 				},
 			},
-			expectedScriptIDs: []string{"script 5", "script 6", "script 1", "script 7", "script 4", "script 8", "script 9"},
+			expectedScriptIDs: []string{"script 5", "script 6", "script 4", "script 1", "script 7", "script 8", "script 9"},
 		},
 		{
 			scriptsInInput: tasks.Scripts{
@@ -115,7 +116,8 @@ func TestSort(t *testing.T) {
 
 	for _, testCase := range testCases {
 		actualScripts := testCase.scriptsInInput
-		SortScriptsRespectingRequirements(actualScripts)
+		err := SortScriptsRespectingRequirements(actualScripts)
+		assert.Equal(t, err, nil)
 
 		actualScriptIDs := make([]string, 0, len(actualScripts))
 		for _, script := range actualScripts {
@@ -125,3 +127,196 @@ func TestSort(t *testing.T) {
 		assert.Equal(t, actualScriptIDs, testCase.expectedScriptIDs)
 	}
 }
+
+func TestSortDeepRequirementsChain(t *testing.T) {
+	const chainLength = 2000
+
+	// every script requires the next one, so the order should be reversed
+	scripts := make(tasks.Scripts, 0, chainLength)
+	for i := 0; i < chainLength; i++ {
+		requirements := []string{}
+		if i < chainLength-1 {
+			requirements = append(requirements, fmt.Sprintf("script %d", i+1))
+		}
+		scripts = append(scripts, tasks.Script{
+			ID:    fmt.Sprintf("script %d", i),
+			Tasks: []tasks.Task{RequirementsTaskMock{RequirementsToGive: requirements}},
+		})
+	}
+
+	err := SortScriptsRespectingRequirements(scripts)
+	assert.Equal(t, err, nil)
+
+	for i, script := range scripts {
+		assert.Equal(t, script.ID, fmt.Sprintf("script %d", chainLength-1-i))
+	}
+}
+
+func TestSortCyclicRequirements(t *testing.T) {
+	scripts := tasks.Scripts{
+		{ID: "a", Tasks: []tasks.Task{RequirementsTaskMock{RequirementsToGive: []string{"b"}}}},
+		{ID: "b", Tasks: []tasks.Task{RequirementsTaskMock{RequirementsToGive: []string{"a"}}}},
+		{ID: "c", Tasks: []tasks.Task{RequirementsTaskMock{RequirementsToGive: []string{"d"}}}},
+		{ID: "d", Tasks: []tasks.Task{RequirementsTaskMock{RequirementsToGive: []string{"c"}}}},
+	}
+
+	err := SortScriptsRespectingRequirements(scripts)
+	assert.Equal(t, err.Error(), "cyclic requirements are detected: 'a' -> 'b' -> 'a', 'c' -> 'd' -> 'c'")
+}
+
+func TestSortTasksWithTypedRequisites(t *testing.T) {
+	scripts := tasks.Scripts{
+		{
+			ID: "web",
+			Tasks: []tasks.Task{
+				RequirementsTaskMock{
+					Name:             tasks.TaskTypeCmdRun,
+					Path:             "web.cmd.run[1]",
+					RequisitesToGive: []tasks.Requisite{{Module: "file", Ref: "web"}},
+				},
+				RequirementsTaskMock{Name: tasks.FileManaged, Path: "web.file.managed[2]"},
+			},
+		},
+		{
+			ID: "config",
+			Tasks: []tasks.Task{
+				RequirementsTaskMock{Name: tasks.FileManaged, Path: "config.file.managed[1]"},
+			},
+		},
+		{
+			ID: "build",
+			Tasks: []tasks.Task{
+				RequirementsTaskMock{
+					Name:             tasks.TaskTypeCmdRun,
+					Path:             "build.cmd.run[1]",
+					RequisitesToGive: []tasks.Requisite{{Module: "file", Ref: "web"}},
+				},
+			},
+		},
+	}
+
+	err := SortScriptsRespectingRequirements(scripts)
+	assert.Equal(t, err, nil)
+
+	actualTaskPaths := []string{}
+	for _, script := range scripts {
+		for _, task := range script.Tasks {
+			actualTaskPaths = append(actualTaskPaths, task.GetPath())
+		}
+	}
+
+	assert.Equal(t, actualTaskPaths, []string{"web.file.managed[2]", "web.cmd.run[1]", "config.file.managed[1]", "build.cmd.run[1]"})
+}
+
+func TestSortWithReverseRequisites(t *testing.T) {
+	appTask := &tasks.CmdRunTask{TypeName: tasks.TaskTypeCmdRun, Path: "app.cmd.run[1]", NamedTask: tasks.NamedTask{Name: "start app"}}
+	restartTask := &tasks.CmdRunTask{TypeName: tasks.TaskTypeCmdRun, Path: "web.cmd.run[1]", NamedTask: tasks.NamedTask{Name: "restart"}}
+	scripts := tasks.Scripts{
+		{ID: "app", Tasks: []tasks.Task{appTask}},
+		{
+			ID: "web",
+			Tasks: []tasks.Task{
+				restartTask,
+				&tasks.FileManagedTask{
+					TypeName:   tasks.FileManaged,
+					Path:       "web.file.managed[2]",
+					Name:       "/etc/nginx/nginx.conf",
+					Requisites: tasks.Requisites{WatchIn: []tasks.Requisite{{Module: "cmd", Ref: "web"}}},
+				},
+			},
+		},
+		{
+			ID: "nginx",
+			Tasks: []tasks.Task{
+				&tasks.PkgTask{
+					TypeName:   tasks.PkgInstalled,
+					Path:       "nginx.pkg.installed[1]",
+					NamedTask:  tasks.NamedTask{Name: "nginx"},
+					Requisites: tasks.Requisites{RequireIn: []tasks.Requisite{{Module: "cmd", Ref: "app"}}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, nil, ValidateScripts(scripts))
+
+	err := SortScriptsRespectingRequirements(scripts)
+	assert.Equal(t, err, nil)
+
+	actualTaskPaths := []string{}
+	for _, script := range scripts {
+		for _, task := range script.Tasks {
+			actualTaskPaths = append(actualTaskPaths, task.GetPath())
+		}
+	}
+
+	assert.Equal(t, actualTaskPaths, []string{"web.file.managed[2]", "web.cmd.run[1]", "nginx.pkg.installed[1]", "app.cmd.run[1]"})
+	assert.Equal(t, appTask.InjectedRequire, []tasks.Requisite{{Module: tasks.PkgInstalled, Ref: "nginx", Path: "nginx.pkg.installed[1]"}})
+	assert.Equal(t, restartTask.InjectedWatch, []tasks.Requisite{{Module: tasks.FileManaged, Ref: "web", Path: "web.file.managed[2]"}})
+}
+
+func TestReverseRequisitesReferenceTheExactTask(t *testing.T) {
+	restartTask := &tasks.CmdRunTask{TypeName: tasks.TaskTypeCmdRun, Path: "web.cmd.run[1]", NamedTask: tasks.NamedTask{Name: "restart"}}
+	scripts := tasks.Scripts{
+		{
+			ID: "web",
+			Tasks: []tasks.Task{
+				&tasks.FileManagedTask{TypeName: tasks.FileManaged, Path: "web.file.managed[1]", Name: "/var/www/index.html"},
+				&tasks.FileManagedTask{
+					TypeName:   tasks.FileManaged,
+					Path:       "web.file.managed[2]",
+					Name:       "/etc/nginx/nginx.conf",
+					Requisites: tasks.Requisites{WatchIn: []tasks.Requisite{{Module: "cmd", Ref: "restart"}}},
+				},
+			},
+		},
+		{ID: "restart", Tasks: []tasks.Task{restartTask}},
+	}
+
+	err := SortScriptsRespectingRequirements(scripts)
+	assert.Equal(t, err, nil)
+
+	watchedPaths := []string{}
+	for _, requisite := range restartTask.InjectedWatch {
+		for _, watchedTask := range resolveRequisite(scripts, requisite) {
+			watchedPaths = append(watchedPaths, watchedTask.GetPath())
+		}
+	}
+
+	// the sibling file.managed task of the web script is not watched
+	assert.Equal(t, watchedPaths, []string{"web.file.managed[2]"})
+}
+
+func TestSortCyclicScriptsWithoutTaskCycles(t *testing.T) {
+	scripts := tasks.Scripts{
+		{
+			ID: "a",
+			Tasks: []tasks.Task{
+				RequirementsTaskMock{
+					Name:             tasks.TaskTypeCmdRun,
+					Path:             "a.cmd.run[1]",
+					RequisitesToGive: []tasks.Requisite{{Module: "file", Ref: "b"}},
+				},
+			},
+		},
+		{
+			ID: "b",
+			Tasks: []tasks.Task{
+				RequirementsTaskMock{Name: tasks.FileManaged, Path: "b.file.managed[1]"},
+				RequirementsTaskMock{
+					Name:             tasks.PkgInstalled,
+					Path:             "b.pkg.installed[2]",
+					RequisitesToGive: []tasks.Requisite{{Module: "cmd", Ref: "a"}},
+				},
+			},
+		},
+	}
+
+	err := SortScriptsRespectingRequirements(scripts)
+	assert.Equal(
+		t,
+		err.Error(),
+		"cyclic requirements are detected: 'a' -> 'b' -> 'a', the tasks don't require each other cyclically, "+
+			"but every script is executed as a whole, so two scripts cannot require tasks of each other, move the tasks to break the cycle",
+	)
+}
@@ -0,0 +1,238 @@
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	excerptContextLines = 1
+	lineMarkerStart     = "\x1e"
+	lineMarkerEnd       = "\x1f"
+)
+
+var (
+	templateErrPosRegex = regexp.MustCompile(`^template: [^:]+:(\d+)(?::(\d+))?: `)
+	yamlErrLineRegex    = regexp.MustCompile(`line (\d+):`)
+	lineMarkerRegex     = regexp.MustCompile(lineMarkerStart + `(\d+)` + lineMarkerEnd)
+)
+
+// SourceError is an error which points to a line in a script file, Column is 0 if it's unknown
+type SourceError struct {
+	Location string
+	Line     int
+	Column   int
+	Msg      string
+	Excerpt  string
+}
+
+func (se SourceError) Error() string {
+	location := "script"
+	if se.Location != "" {
+		location = fmt.Sprintf("'%s'", se.Location)
+	}
+
+	position := fmt.Sprintf("line %d", se.Line)
+	if se.Column > 0 {
+		position = fmt.Sprintf("line %d, column %d", se.Line, se.Column)
+	}
+
+	msg := fmt.Sprintf("%s at %s: %s", location, position, se.Msg)
+	if se.Excerpt != "" {
+		msg += "\n" + se.Excerpt
+	}
+
+	return msg
+}
+
+// buildExcerpt gives the source line with its neighbours and a caret under the column or the first non space character
+func buildExcerpt(source []byte, lineNum, column int) string {
+	lines := strings.Split(strings.TrimRight(string(source), "\n"), "\n")
+	if lineNum < 1 || lineNum > len(lines) {
+		return ""
+	}
+
+	firstLine := lineNum - excerptContextLines
+	if firstLine < 1 {
+		firstLine = 1
+	}
+	lastLine := lineNum + excerptContextLines
+	if lastLine > len(lines) {
+		lastLine = len(lines)
+	}
+
+	numWidth := len(strconv.Itoa(lastLine))
+	excerptLines := make([]string, 0, lastLine-firstLine+2)
+	for i := firstLine; i <= lastLine; i++ {
+		excerptLines = append(excerptLines, fmt.Sprintf("%*d | %s", numWidth, i, lines[i-1]))
+		if i != lineNum {
+			continue
+		}
+
+		caretPos := column - 1
+		if caretPos < 0 {
+			caretPos = len(lines[i-1]) - len(strings.TrimLeft(lines[i-1], " \t"))
+		}
+		if caretPos > len(lines[i-1]) {
+			caretPos = len(lines[i-1])
+		}
+
+		// tabs are kept so the caret is aligned with the source line in the terminal
+		caretPrefix := strings.Map(func(r rune) rune {
+			if r == '\t' {
+				return r
+			}
+			return ' '
+		}, lines[i-1][:caretPos])
+		excerptLines = append(excerptLines, fmt.Sprintf("%s | %s^", strings.Repeat(" ", numWidth), caretPrefix))
+	}
+
+	return strings.Join(excerptLines, "\n")
+}
+
+// newTemplateError converts a text/template error to a SourceError, template positions already refer to the source file
+func newTemplateError(location string, source []byte, err error) error {
+	errMsg := err.Error()
+	matches := templateErrPosRegex.FindStringSubmatch(errMsg)
+	if matches == nil {
+		return err
+	}
+
+	lineNum, _ := strconv.Atoi(matches[1])
+	column := 0
+	if matches[2] != "" {
+		// text/template gives 0 based byte offsets in the line
+		column, _ = strconv.Atoi(matches[2])
+		column++
+	}
+
+	return SourceError{
+		Location: location,
+		Line:     lineNum,
+		Column:   column,
+		Msg:      strings.TrimPrefix(errMsg, matches[0]),
+		Excerpt:  buildExcerpt(source, lineNum, column),
+	}
+}
+
+// newYAMLError converts a yaml error with line numbers of the rendered document to a SourceError which refers
+// to the template source, renderedLinesMap maps rendered lines to the source ones
+func newYAMLError(location string, source []byte, renderedLinesMap map[int]int, err error) error {
+	errMsg := err.Error()
+	matches := yamlErrLineRegex.FindAllStringSubmatchIndex(errMsg, -1)
+	if len(matches) == 0 {
+		return err
+	}
+
+	firstSourceLine := 0
+	msgBuf := bytes.Buffer{}
+	lastPos := 0
+	for _, match := range matches {
+		renderedLine, _ := strconv.Atoi(errMsg[match[2]:match[3]])
+		sourceLine, ok := renderedLinesMap[renderedLine]
+		if !ok {
+			sourceLine = renderedLine
+		}
+		if firstSourceLine == 0 {
+			firstSourceLine = sourceLine
+		}
+
+		msgBuf.WriteString(errMsg[lastPos:match[2]])
+		msgBuf.WriteString(strconv.Itoa(sourceLine))
+		lastPos = match[3]
+	}
+	msgBuf.WriteString(errMsg[lastPos:])
+
+	msg := strings.TrimPrefix(msgBuf.String(), "yaml: ")
+	if len(matches) == 1 {
+		msg = strings.TrimPrefix(msg, "unmarshal errors:\n  ")
+		msg = strings.TrimPrefix(msg, fmt.Sprintf("line %d: ", firstSourceLine))
+	}
+
+	return SourceError{
+		Location: location,
+		Line:     firstSourceLine,
+		Msg:      msg,
+		Excerpt:  buildExcerpt(source, firstSourceLine, 0),
+	}
+}
+
+// renderWithLineMarkers renders the template with markers of source lines in the literal text, so each rendered line
+// can be mapped to the source line which has produced it, the lines which are produced by actions
+// e.g. {{ .multilineVar }} are mapped to the line of the preceding text
+func (p Builder) renderWithLineMarkers(templateData []byte, variables map[string]interface{}) (map[int]int, error) {
+	templ, err := template.New("goyaml").Funcs(templateFuncs()).Parse(string(templateData))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range templ.Templates() {
+		if t.Tree != nil {
+			addLineMarkers(t.Tree.Root, templateData)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	err = templ.Execute(&buf, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedLinesMap := map[int]int{}
+	lastSourceLine := 1
+	for i, renderedLine := range strings.Split(buf.String(), "\n") {
+		if markerMatch := lineMarkerRegex.FindStringSubmatch(renderedLine); markerMatch != nil {
+			lastSourceLine, _ = strconv.Atoi(markerMatch[1])
+		}
+		renderedLinesMap[i+1] = lastSourceLine
+	}
+
+	return renderedLinesMap, nil
+}
+
+func addLineMarkers(node parse.Node, source []byte) {
+	switch typedNode := node.(type) {
+	case *parse.ListNode:
+		if typedNode == nil {
+			return
+		}
+		for _, childNode := range typedNode.Nodes {
+			addLineMarkers(childNode, source)
+		}
+	case *parse.IfNode:
+		addLineMarkers(typedNode.List, source)
+		addLineMarkers(typedNode.ElseList, source)
+	case *parse.RangeNode:
+		addLineMarkers(typedNode.List, source)
+		addLineMarkers(typedNode.ElseList, source)
+	case *parse.WithNode:
+		addLineMarkers(typedNode.List, source)
+		addLineMarkers(typedNode.ElseList, source)
+	case *parse.TextNode:
+		pos := int(typedNode.Position())
+		if pos > len(source) {
+			return
+		}
+		lineNum := 1 + bytes.Count(source[:pos], []byte("\n"))
+
+		markedText := bytes.Buffer{}
+		markedText.WriteString(lineMarker(lineNum))
+		for _, char := range typedNode.Text {
+			markedText.WriteByte(char)
+			if char == '\n' {
+				lineNum++
+				markedText.WriteString(lineMarker(lineNum))
+			}
+		}
+		typedNode.Text = markedText.Bytes()
+	}
+}
+
+func lineMarker(lineNum int) string {
+	return lineMarkerStart + strconv.Itoa(lineNum) + lineMarkerEnd
+}
@@ -0,0 +1,103 @@
+package script
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceErrors(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		YamlInput      string
+		ExpectedErrMsg string
+	}{
+		{
+			Name: "yaml error after removed lines",
+			YamlInput: `{{ if eq .taco_os_family "debian" }}
+# debian only
+# scripts
+{{ end }}
+app:
+  cmd.run:
+    - name: echo
+   - cwd: /tmp
+`,
+			ExpectedErrMsg: "script at line 7: did not find expected key\n" +
+				"6 |   cmd.run:\n" +
+				"7 |     - name: echo\n" +
+				"  |     ^\n" +
+				"8 |    - cwd: /tmp",
+		},
+		{
+			Name: "yaml error after lines produced by range",
+			YamlInput: `app:
+  cmd.run:
+{{ range .names }}
+    - name: {{ . }}
+{{ end }}
+    - names: [
+`,
+			ExpectedErrMsg: "script at line 6: did not find expected node content\n" +
+				"5 | {{ end }}\n" +
+				"6 |     - names: [\n" +
+				"  |     ^",
+		},
+		{
+			Name:      "yaml type error",
+			YamlInput: "app:\n  cmd.run: 5\n",
+			ExpectedErrMsg: "script at line 2: cannot unmarshal !!int `5` into []map[string]interface {}\n" +
+				"1 | app:\n" +
+				"2 |   cmd.run: 5\n" +
+				"  |   ^",
+		},
+		{
+			Name:      "template parse error",
+			YamlInput: "app:\n  cmd.run:\n    - name: {{ end }}\n",
+			ExpectedErrMsg: "script at line 3: unexpected {{end}}\n" +
+				"2 |   cmd.run:\n" +
+				"3 |     - name: {{ end }}\n" +
+				"  |     ^",
+		},
+		{
+			Name:      "template execution error",
+			YamlInput: "app:\n  cmd.run:\n    - name: {{ .names | join }}\n",
+			ExpectedErrMsg: `script at line 3, column 25: executing "goyaml" at <join>: wrong number of args for join: want 2 got 1` + "\n" +
+				"2 |   cmd.run:\n" +
+				"3 |     - name: {{ .names | join }}\n" +
+				"  |                         ^",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			builder := Builder{
+				DataProvider: RawDataProviderMock{DataToReturn: tc.YamlInput},
+				TaskBuilder:  &TaskBuilderMock{},
+				TemplateVariablesProvider: TemplateVariablesProviderMock{
+					Variables: map[string]interface{}{
+						"taco_os_family": "redhat",
+						"names":          []string{"one", "two"},
+					},
+				},
+			}
+
+			_, err := builder.BuildScripts()
+			assert.EqualError(t, err, tc.ExpectedErrMsg)
+		})
+	}
+}
+
+func TestSourceErrorLocation(t *testing.T) {
+	err := newYAMLError("/opt/scripts/main.yaml", []byte("app:\n\tcmd.run: 1\n"), map[int]int{3: 2}, errors.New("yaml: line 3: found a tab character"))
+
+	assert.Equal(t, SourceError{
+		Location: "/opt/scripts/main.yaml",
+		Line:     2,
+		Msg:      "found a tab character",
+		Excerpt:  "1 | app:\n2 | \tcmd.run: 1\n  | \t^",
+	}, err)
+	assert.EqualError(t, err, "'/opt/scripts/main.yaml' at line 2: found a tab character\n1 | app:\n2 | \tcmd.run: 1\n  | \t^")
+}
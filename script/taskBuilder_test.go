@@ -48,13 +48,17 @@ maintain-my-file:
 						MakeDirs:   true,
 						Replace:    false,
 						SkipVerify: true,
-						Creates:    []string{"C:\\Program Files\\notepad++\\notepad++.exe"},
-						User:       "",
-						Group:      "",
-						Encoding:   "",
-						Mode:       0,
-						OnlyIf:     nil,
-						Require:    nil,
+						CmdContext: tasks.CmdContext{
+							User: "",
+						},
+						Group:    "",
+						Encoding: "",
+						Mode:     0,
+						Require:  nil,
+						Conditionals: tasks.Conditionals{
+							Creates: []string{"C:\\Program Files\\notepad++\\notepad++.exe"},
+							OnlyIf:  nil,
+						},
 					},
 				},
 			},
@@ -93,11 +97,15 @@ Funny file
 						MakeDirs:   false,
 						Replace:    true,
 						SkipVerify: false,
-						User:       "root",
-						Group:      "www-data",
-						Encoding:   "UTF-8",
-						Mode:       os.FileMode(0755),
-						OnlyIf:     []string{"which apache2", "grep -q foo /tmp/bla"},
+						CmdContext: tasks.CmdContext{
+							User: "root",
+						},
+						Group:    "www-data",
+						Encoding: "UTF-8",
+						Mode:     os.FileMode(0755),
+						Conditionals: tasks.Conditionals{
+							OnlyIf: []string{"which apache2", "grep -q foo /tmp/bla"},
+						},
 					},
 				},
 			},
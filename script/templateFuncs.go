@@ -0,0 +1,169 @@
+package script
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+	yaml2 "gopkg.in/yaml.v2"
+)
+
+// templateFuncs gives functions which are available in script templates, the functions which take
+// a value to process expect it as the last argument, so they can be used in pipelines e.g. {{ .name | replace "-" "_" | upper }}
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"replace":    replaceFunc,
+		"split":      splitFunc,
+		"join":       joinFunc,
+		"regexMatch": regexMatchFunc,
+		"default":    defaultFunc,
+		"ternary":    ternaryFunc,
+		"env":        os.Getenv,
+		"toYaml":     toYamlFunc,
+		"toJson":     toJSONFunc,
+		"fromJson":   fromJSONFunc,
+		"b64enc":     b64encFunc,
+		"b64dec":     b64decFunc,
+		"sha256sum":  sha256sumFunc,
+		"base":       filepath.Base,
+		"dir":        filepath.Dir,
+		"ext":        filepath.Ext,
+		"clean":      filepath.Clean,
+		"isAbs":      filepath.IsAbs,
+		"pathJoin":   filepath.Join,
+		"fileExists": fileExistsFunc,
+		"readFile":   readFileFunc,
+	}
+}
+
+func replaceFunc(old, new, src string) string {
+	return strings.ReplaceAll(src, old, new)
+}
+
+func splitFunc(sep, src string) []string {
+	return strings.Split(src, sep)
+}
+
+func joinFunc(sep string, items interface{}) (string, error) {
+	itemsVal := reflect.ValueOf(items)
+	if itemsVal.Kind() != reflect.Slice && itemsVal.Kind() != reflect.Array {
+		return "", fmt.Errorf("join expects a list, got %T", items)
+	}
+
+	strItems := make([]string, 0, itemsVal.Len())
+	for i := 0; i < itemsVal.Len(); i++ {
+		strItems = append(strItems, fmt.Sprint(itemsVal.Index(i).Interface()))
+	}
+
+	return strings.Join(strItems, sep), nil
+}
+
+func regexMatchFunc(regex, src string) (bool, error) {
+	return regexp.MatchString(regex, src)
+}
+
+// defaultFunc gives the default value if the given one is empty, e.g. {{ .port | default 8080 }}
+func defaultFunc(defaultVal, given interface{}) interface{} {
+	if isEmptyValue(given) {
+		return defaultVal
+	}
+
+	return given
+}
+
+func isEmptyValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+
+	reflectVal := reflect.ValueOf(val)
+	switch reflectVal.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return reflectVal.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return reflectVal.IsNil()
+	default:
+		return reflectVal.IsZero()
+	}
+}
+
+// ternaryFunc gives trueVal if the condition is true, e.g. {{ eq .env "prod" | ternary "warn" "debug" }}
+func ternaryFunc(trueVal, falseVal interface{}, condition bool) interface{} {
+	if condition {
+		return trueVal
+	}
+
+	return falseVal
+}
+
+func toYamlFunc(val interface{}) (string, error) {
+	data, err := yaml2.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+func toJSONFunc(val interface{}) (string, error) {
+	data, err := json.Marshal(utils.NormalizeYAMLValue(val))
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func fromJSONFunc(src string) (interface{}, error) {
+	var val interface{}
+	err := json.Unmarshal([]byte(src), &val)
+
+	return val, err
+}
+
+func b64encFunc(src string) string {
+	return base64.StdEncoding.EncodeToString([]byte(src))
+}
+
+func b64decFunc(src string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(src)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func sha256sumFunc(src string) string {
+	hash := sha256.Sum256([]byte(src))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func fileExistsFunc(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
+func readFileFunc(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,74 @@
+package script
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tmpFile, err := ioutil.TempFile("", "tacoscript-template-funcs")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.WriteString("file contents")
+	assert.NoError(t, err)
+	assert.NoError(t, tmpFile.Close())
+
+	assert.NoError(t, os.Setenv("TACOSCRIPT_TEMPLATE_TEST", "env value"))
+	defer os.Unsetenv("TACOSCRIPT_TEMPLATE_TEST")
+
+	variables := map[string]interface{}{
+		"name":     " My-App ",
+		"ports":    []interface{}{80, 443},
+		"emptyStr": "",
+		"db":       map[string]interface{}{"host": "localhost", "port": 5432},
+		"tmpFile":  tmpFile.Name(),
+		"json":     `{"version": "1.2.3", "tags": ["a", "b"]}`,
+	}
+
+	testCases := []struct {
+		Template       string
+		ExpectedOutput string
+		ExpectedErrMsg string
+	}{
+		{Template: `{{ .name | trim | lower }}`, ExpectedOutput: "my-app"},
+		{Template: `{{ .name | trim | replace "-" "_" | upper }}`, ExpectedOutput: "MY_APP"},
+		{Template: `{{ split "," "a,b,c" | join ";" }}`, ExpectedOutput: "a;b;c"},
+		{Template: `{{ .ports | join "," }}`, ExpectedOutput: "80,443"},
+		{Template: `{{ join "," .name }}`, ExpectedErrMsg: `template: goyaml:1:3: executing "goyaml" at <join "," .name>: error calling join: join expects a list, got string`},
+		{Template: `{{ regexMatch "^[0-9]+$" "12345" }} {{ "abc" | regexMatch "^[0-9]+$" }}`, ExpectedOutput: "true false"},
+		{Template: `{{ regexMatch "[" "abc" }}`, ExpectedErrMsg: "template: goyaml:1:3: executing \"goyaml\" at <regexMatch \"[\" \"abc\">: error calling regexMatch: error parsing regexp: missing closing ]: `[`"},
+		{Template: `{{ .emptyStr | default "fallback" }} {{ .missing | default 8080 }} {{ .db.host | default "db" }}`, ExpectedOutput: "fallback 8080 localhost"},
+		{Template: `{{ eq .db.host "localhost" | ternary "local" "remote" }}`, ExpectedOutput: "local"},
+		{Template: `{{ env "TACOSCRIPT_TEMPLATE_TEST" }}`, ExpectedOutput: "env value"},
+		{Template: `{{ .db | toJson }}`, ExpectedOutput: `{"host":"localhost","port":5432}`},
+		{Template: `{{ .ports | toYaml }}`, ExpectedOutput: "- 80\n- 443"},
+		{Template: `{{ $data := fromJson .json }}{{ $data.version }} {{ index $data.tags 1 }}`, ExpectedOutput: "1.2.3 b"},
+		{Template: `{{ fromJson "{" }}`, ExpectedErrMsg: `template: goyaml:1:3: executing "goyaml" at <fromJson "{">: error calling fromJson: unexpected end of JSON input`},
+		{Template: `{{ "secret" | b64enc }} {{ "c2VjcmV0" | b64dec }}`, ExpectedOutput: "c2VjcmV0 secret"},
+		{Template: `{{ "abc" | sha256sum }}`, ExpectedOutput: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{Template: `{{ base .tmpFile | eq "" }} {{ dir .tmpFile | isAbs }} {{ ext "conf/nginx.conf" }}`, ExpectedOutput: "false true .conf"},
+		{Template: `{{ pathJoin "a" "b" "c.txt" }} {{ clean "a/../b" }}`, ExpectedOutput: filepath.Join("a", "b", "c.txt") + " b"},
+		{Template: `{{ fileExists .tmpFile }} {{ fileExists "/not/existing/file" }}`, ExpectedOutput: "true false"},
+		{Template: `{{ readFile .tmpFile }}`, ExpectedOutput: "file contents"},
+	}
+
+	builder := Builder{}
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Template, func(t *testing.T) {
+			output, err := builder.render([]byte(tc.Template), variables)
+			if tc.ExpectedErrMsg != "" {
+				assert.EqualError(t, err, tc.ExpectedErrMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ExpectedOutput, string(output))
+		})
+	}
+}
@@ -6,83 +6,91 @@ import (
 
 	"github.com/cloudradar-monitoring/tacoscript/tasks"
 	"github.com/cloudradar-monitoring/tacoscript/utils"
-	"github.com/elliotchance/orderedmap"
 )
 
-func isCyclic(curScriptID string,
-	scriptIDToRequiredScriptIDsMap map[string][]string,
-	visited map[string]bool,
-	requestStack,
-	cyclicItems *orderedmap.OrderedMap) bool {
-	if isInRequestStack, ok := requestStack.Get(curScriptID); ok && isInRequestStack.(bool) {
-		cyclicItems.Set(curScriptID, true)
-		return true
-	}
+// ValidateScripts checks that the required scripts and tasks exist, that the requirements have no cycles
+// and don't contradict the orders of the scripts
+func ValidateScripts(scrpts tasks.Scripts) error {
+	injectReverseRequisites(scrpts)
 
-	if isVisited, ok := visited[curScriptID]; ok && isVisited {
-		return false
-	}
+	errs := utils.Errors{}
+	scriptReqFailures := make([]string, 0)
+	taskReqFailures := make([]string, 0)
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			for _, fieldReqs := range taskFieldRequisites(task) {
+				for k, requisite := range fieldReqs.requisites {
+					reqPath := fmt.Sprintf("%s.%s[%d]", task.GetPath(), fieldReqs.field, k)
+					if !requisite.IsTyped() && requisite.Ref == script.ID {
+						errs.Add(fmt.Errorf("task at path '%s' cannot require own script '%s'", task.GetPath(), script.ID))
+						continue
+					}
 
-	visited[curScriptID] = true
-	requestStack.Set(curScriptID, true)
-	cyclicItems.Set(curScriptID, true)
+					if !requisite.IsTyped() {
+						if len(resolveRequisiteScripts(scrpts, requisite)) == 0 {
+							scriptReqFailures = append(scriptReqFailures, fmt.Sprintf("'%s' at path '%s'", requisite, reqPath))
+						}
+						continue
+					}
 
-	if requirements, ok := scriptIDToRequiredScriptIDsMap[curScriptID]; ok {
-		for _, requirement := range requirements {
-			isCyclic := isCyclic(requirement, scriptIDToRequiredScriptIDsMap, visited, requestStack, cyclicItems)
-			if isCyclic {
-				return true
+					requiredTasks := resolveRequisite(scrpts, requisite)
+					if len(requiredTasks) == 0 {
+						taskReqFailures = append(taskReqFailures, fmt.Sprintf("'%s' at path '%s'", requisite, reqPath))
+						continue
+					}
+
+					if len(requiredTasks) == 1 && requiredTasks[0].GetPath() == task.GetPath() {
+						errs.Add(fmt.Errorf("task at path '%s' cannot require itself", task.GetPath()))
+					}
+				}
 			}
 		}
-	}
-
-	requestStack.Set(curScriptID, false)
-	cyclicItems.Delete(curScriptID)
 
-	return false
-}
+		_, taskCycles := sortScriptTasks(script)
+		if len(taskCycles) > 0 {
+			errs.Add(newCyclesError(taskCycles))
+		}
 
-func ValidateScripts(scrpts tasks.Scripts) error {
-	scriptIDToNodesMap := make(map[string][]string)
-	scriptIDsMap := make(map[string]bool, len(scrpts))
-	requirements := make(map[string]string)
-	errs := utils.Errors{}
+		errs.Add(validateScriptOrder(script))
+	}
 
-	for _, script := range scrpts {
-		scriptIDToNodesMap[script.ID] = make([]string, 0)
-		scriptIDsMap[script.ID] = true
-		for _, task := range script.Tasks {
-			for k, reqName := range task.GetRequirements() {
-				requirements[reqName] = fmt.Sprintf("%s.%s[%d]", task.GetPath(), tasks.RequireField, k)
-				scriptIDToNodesMap[script.ID] = append(scriptIDToNodesMap[script.ID], reqName)
+	if len(scriptReqFailures) > 0 {
+		errs.Add(fmt.Errorf("missing required scripts %s", strings.Join(scriptReqFailures, ", ")))
+	}
 
-				if reqName == script.ID {
-					errs.Add(fmt.Errorf("task at path '%s' cannot require own script '%s'", task.GetPath(), script.ID))
-					continue
-				}
-			}
-		}
+	if len(taskReqFailures) > 0 {
+		errs.Add(fmt.Errorf("missing required tasks %s", strings.Join(taskReqFailures, ", ")))
 	}
 
-	reqFailures := make([]string, 0, len(requirements))
-	for reqName, reqPath := range requirements {
-		if _, ok := scriptIDsMap[reqName]; !ok {
-			reqFailures = append(reqFailures, fmt.Sprintf("'%s' at path '%s'", reqName, reqPath))
+	graph := BuildRequirementsGraph(scrpts)
+
+	// scripts which require themselves are reported above
+	cycles := make([][]string, 0)
+	for _, cycle := range graph.Cycles {
+		if len(cycle) > 1 {
+			cycles = append(cycles, cycle)
 		}
 	}
+	if len(cycles) > 0 {
+		errs.Add(newScriptCyclesError(scrpts, cycles))
+	}
 
-	if len(reqFailures) > 0 {
-		errs.Add(fmt.Errorf("missing required scripts %s", strings.Join(reqFailures, ", ")))
+	if len(graph.OrderConflicts) > 0 {
+		errs.Add(newOrderConflictsError(graph.OrderConflicts))
 	}
 
-	requestStack := orderedmap.NewOrderedMap()
-	visited := make(map[string]bool)
-	for curScriptID := range scriptIDToNodesMap {
-		cyclicItms := orderedmap.NewOrderedMap()
-		isCyclic := isCyclic(curScriptID, scriptIDToNodesMap, visited, requestStack, cyclicItms)
-		if isCyclic {
-			errs.Add(fmt.Errorf("cyclic requirements are detected: '%s'", cyclicItms.Keys()))
-			break
+	return errs.ToError()
+}
+
+// ValidateExecutors checks that every task of the scripts has an executor which can run it
+func ValidateExecutors(scrpts tasks.Scripts, executorRouter tasks.ExecutorRouter) error {
+	errs := utils.Errors{}
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			_, err := executorRouter.GetExecutor(task)
+			if err != nil {
+				errs.Add(fmt.Errorf("%w at path '%s'", err, task.GetPath()))
+			}
 		}
 	}
 
@@ -11,11 +11,13 @@ import (
 
 type RequirementsTaskMock struct {
 	RequirementsToGive []string
+	RequisitesToGive   []tasks.Requisite
 	Path               string
+	Name               string
 }
 
 func (rtm RequirementsTaskMock) GetName() string {
-	return ""
+	return rtm.Name
 }
 
 func (rtm RequirementsTaskMock) Execute(ctx context.Context) tasks.ExecutionResult {
@@ -30,8 +32,8 @@ func (rtm RequirementsTaskMock) GetPath() string {
 	return rtm.Path
 }
 
-func (rtm RequirementsTaskMock) GetRequirements() []string {
-	return rtm.RequirementsToGive
+func (rtm RequirementsTaskMock) GetRequirements() []tasks.Requisite {
+	return append(tasks.ScriptRequisites(rtm.RequirementsToGive), rtm.RequisitesToGive...)
 }
 
 type errorExpectation struct {
@@ -300,3 +302,229 @@ func TestScriptsValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestExecutorsValidation(t *testing.T) {
+	scripts := tasks.Scripts{
+		{
+			ID: "script 1",
+			Tasks: []tasks.Task{
+				&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "script 1.cmd.run[1]"},
+				&TaskBuilderTaskMock{TypeName: "service.running", Path: "script 1.service.running[2]"},
+			},
+		},
+	}
+
+	executorRouter := tasks.ExecutorRouter{
+		Executors: map[string]tasks.Executor{
+			tasks.TaskTypeCmdRun: &ExecutorMock{},
+		},
+	}
+
+	err := ValidateExecutors(scripts, executorRouter)
+	assert.EqualError(t, err, "cannot find executor for task service.running at path 'script 1.service.running[2]'")
+
+	executorRouter.Executors["service.running"] = &ExecutorMock{}
+	assert.NoError(t, ValidateExecutors(scripts, executorRouter))
+}
+
+func TestTypedRequisitesValidation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		scripts        tasks.Scripts
+		expectedErrMsg string
+	}{
+		{
+			name: "required tasks are found",
+			scripts: tasks.Scripts{
+				{
+					ID: "nginx",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{TypeName: tasks.FileManaged, Path: "nginx.file.managed[1]"},
+						&TaskBuilderTaskMock{
+							TypeName:   tasks.TaskTypeCmdRun,
+							Path:       "nginx.cmd.run[2]",
+							Requisites: []tasks.Requisite{{Module: "file", Ref: "nginx"}, {Module: "cmd", Ref: "build"}},
+						},
+					},
+				},
+				{
+					ID:    "build",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "build.cmd.run[1]"}},
+				},
+			},
+		},
+		{
+			name: "missing required tasks",
+			scripts: tasks.Scripts{
+				{
+					ID: "nginx",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName:   tasks.TaskTypeCmdRun,
+							Path:       "nginx.cmd.run[1]",
+							Requisites: []tasks.Requisite{{Module: "file", Ref: "nginx"}, {Module: "pkg", Ref: "nginx"}},
+						},
+					},
+				},
+			},
+			expectedErrMsg: "missing required tasks 'file: nginx' at path 'nginx.cmd.run[1].require[0]', " +
+				"'pkg: nginx' at path 'nginx.cmd.run[1].require[1]'",
+		},
+		{
+			name: "missing onchanges tasks",
+			scripts: tasks.Scripts{
+				{
+					ID: "nginx",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{TypeName: tasks.FileManaged, Path: "nginx.file.managed[1]"},
+						&TaskBuilderTaskMock{
+							TypeName:  tasks.TaskTypeCmdRun,
+							Path:      "nginx.cmd.run[2]",
+							OnChanges: []tasks.Requisite{{Module: "file", Ref: "nginx"}, {Module: "pkg", Ref: "nginx"}},
+						},
+					},
+				},
+			},
+			expectedErrMsg: "missing required tasks 'pkg: nginx' at path 'nginx.cmd.run[2].onchanges[1]'",
+		},
+		{
+			name: "missing require_in tasks",
+			scripts: tasks.Scripts{
+				{
+					ID: "nginx",
+					Tasks: []tasks.Task{
+						&tasks.PkgTask{
+							TypeName:   tasks.PkgInstalled,
+							Path:       "nginx.pkg.installed[1]",
+							NamedTask:  tasks.NamedTask{Name: "nginx"},
+							Requisites: tasks.Requisites{RequireIn: []tasks.Requisite{{Module: "cmd", Ref: "app"}}},
+						},
+					},
+				},
+			},
+			expectedErrMsg: "missing required tasks 'cmd: app' at path 'nginx.pkg.installed[1].require_in[0]'",
+		},
+		{
+			name: "task requires itself",
+			scripts: tasks.Scripts{
+				{
+					ID: "build",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName:   tasks.TaskTypeCmdRun,
+							Path:       "build.cmd.run[1]",
+							Requisites: []tasks.Requisite{{Module: "cmd", Ref: "build"}},
+						},
+					},
+				},
+			},
+			expectedErrMsg: "task at path 'build.cmd.run[1]' cannot require itself",
+		},
+		{
+			name: "cyclic tasks of a script",
+			scripts: tasks.Scripts{
+				{
+					ID: "app",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{
+							TypeName:   tasks.FileManaged,
+							Path:       "app.file.managed[1]",
+							Requisites: []tasks.Requisite{{Module: "cmd", Ref: "app"}},
+						},
+						&TaskBuilderTaskMock{
+							TypeName:   tasks.TaskTypeCmdRun,
+							Path:       "app.cmd.run[2]",
+							Requisites: []tasks.Requisite{{Module: "file", Ref: "app"}},
+						},
+					},
+				},
+			},
+			expectedErrMsg: "cyclic requirements are detected: 'app.file.managed[1]' -> 'app.cmd.run[2]' -> 'app.file.managed[1]'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateScripts(tc.scripts)
+			if tc.expectedErrMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tc.expectedErrMsg)
+		})
+	}
+}
+
+func TestScriptOrdersValidation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		scripts        tasks.Scripts
+		expectedErrMsg string
+	}{
+		{
+			name: "same orders of script tasks",
+			scripts: tasks.Scripts{
+				{
+					ID: "bootstrap",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{TypeName: tasks.PkgRepoManaged, Path: "bootstrap.pkgrepo.managed[1]", Order: tasks.Order{Kind: tasks.OrderFirst}},
+						&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "bootstrap.cmd.run[2]"},
+						&TaskBuilderTaskMock{TypeName: tasks.FileManaged, Path: "bootstrap.file.managed[3]", Order: tasks.Order{Kind: tasks.OrderFirst}},
+					},
+				},
+			},
+		},
+		{
+			name: "different orders of script tasks",
+			scripts: tasks.Scripts{
+				{
+					ID: "bootstrap",
+					Tasks: []tasks.Task{
+						&TaskBuilderTaskMock{TypeName: tasks.PkgRepoManaged, Path: "bootstrap.pkgrepo.managed[1]", Order: tasks.Order{Kind: tasks.OrderFirst}},
+						&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "bootstrap.cmd.run[2]", Order: tasks.Order{Kind: tasks.OrderNumbered, Number: 3}},
+					},
+				},
+			},
+			expectedErrMsg: "different orders of script 'bootstrap': 'first' at path 'bootstrap.pkgrepo.managed[1].order' " +
+				"and '3' at path 'bootstrap.cmd.run[2].order'",
+		},
+		{
+			name: "requirements contradict orders",
+			scripts: tasks.Scripts{
+				{
+					ID:    "bootstrap",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "bootstrap.cmd.run[1]", Requirements: []string{"report"}, Order: tasks.Order{Kind: tasks.OrderFirst}}},
+				},
+				{
+					ID:    "app",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "app.cmd.run[1]", Requirements: []string{"cleanup"}}},
+				},
+				{
+					ID:    "report",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "report.cmd.run[1]", Order: tasks.Order{Kind: tasks.OrderNumbered, Number: 5}}},
+				},
+				{
+					ID:    "cleanup",
+					Tasks: []tasks.Task{&TaskBuilderTaskMock{TypeName: tasks.TaskTypeCmdRun, Path: "cleanup.cmd.run[1]", Order: tasks.Order{Kind: tasks.OrderLast}}},
+				},
+			},
+			expectedErrMsg: "contradictory script orders are detected: 'report' with order '5' is required by 'bootstrap' with order 'first', " +
+				"'cleanup' with order 'last' is required by 'app' without order",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateScripts(tc.scripts)
+			if tc.expectedErrMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tc.expectedErrMsg)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package script
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+	yaml2 "gopkg.in/yaml.v2"
+)
+
+// VarsSection is the optional top-level section of a script file which defines template variables
+const VarsSection = "vars"
+
+var varsSectionStartRegex = regexp.MustCompile(`^` + VarsSection + `:\s*(#.*)?$`)
+
+// LoadVarsFiles reads variables from yaml files, the values of the later files override the former ones
+func LoadVarsFiles(paths []string) (map[string]interface{}, error) {
+	variables := map[string]interface{}{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return variables, err
+		}
+
+		fileVariables, err := parseVars(data)
+		if err != nil {
+			return variables, fmt.Errorf("failed to parse vars file '%s': %w", path, err)
+		}
+
+		mergeVariables(variables, fileVariables)
+	}
+
+	return variables, nil
+}
+
+// ParseVarFlags converts key=value pairs to variables, values are parsed as yaml scalars so
+// "replicas=3" gives a number and "debug=false" gives a boolean
+func ParseVarFlags(rawVars []string) (map[string]interface{}, error) {
+	variables := map[string]interface{}{}
+	for _, rawVar := range rawVars {
+		varParts := strings.SplitN(rawVar, "=", 2)
+		if len(varParts) != 2 || strings.TrimSpace(varParts[0]) == "" {
+			return variables, fmt.Errorf("invalid variable '%s', expected format is key=value", rawVar)
+		}
+
+		var val interface{}
+		err := yaml2.Unmarshal([]byte(varParts[1]), &val)
+		if err != nil {
+			val = varParts[1]
+		}
+
+		switch val.(type) {
+		case map[interface{}]interface{}, []interface{}, nil:
+			val = varParts[1]
+		}
+
+		variables[strings.TrimSpace(varParts[0])] = val
+	}
+
+	return variables, nil
+}
+
+func parseVars(data []byte) (map[string]interface{}, error) {
+	rawVariables := map[string]interface{}{}
+	err := yaml2.Unmarshal(data, &rawVariables)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]interface{}, len(rawVariables))
+	for key, val := range rawVariables {
+		variables[key] = utils.NormalizeYAMLValue(val)
+	}
+
+	return variables, nil
+}
+
+func mergeVariables(target, source map[string]interface{}) {
+	for key, val := range source {
+		target[key] = val
+	}
+}
+
+// extractVarsSection gives the top-level vars section of the script template, the other lines are replaced
+// with empty ones to keep line numbers of the template
+func extractVarsSection(templateData string) string {
+	lines := strings.Split(templateData, "\n")
+	isInVarsSection := false
+	hasVarsSection := false
+	for i, line := range lines {
+		if varsSectionStartRegex.MatchString(line) {
+			isInVarsSection = true
+			hasVarsSection = true
+		} else if isInVarsSection && line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "#") {
+			isInVarsSection = false
+		}
+
+		if !isInVarsSection {
+			lines[i] = ""
+		}
+	}
+
+	if !hasVarsSection {
+		return ""
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,125 @@
+package script
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVarFlags(t *testing.T) {
+	testCases := []struct {
+		Name              string
+		RawVars           []string
+		ExpectedVariables map[string]interface{}
+		ExpectedErrMsg    string
+	}{
+		{
+			Name:    "typed values",
+			RawVars: []string{"env=production", "replicas=3", "debug=false", "url=http://example.com/?a=b"},
+			ExpectedVariables: map[string]interface{}{
+				"env":      "production",
+				"replicas": 3,
+				"debug":    false,
+				"url":      "http://example.com/?a=b",
+			},
+		},
+		{
+			Name:    "non scalar values are kept as strings",
+			RawVars: []string{"list=[1, 2]", "empty=", "map={a: b}"},
+			ExpectedVariables: map[string]interface{}{
+				"list":  "[1, 2]",
+				"empty": "",
+				"map":   "{a: b}",
+			},
+		},
+		{
+			Name:           "missing value",
+			RawVars:        []string{"env"},
+			ExpectedErrMsg: "invalid variable 'env', expected format is key=value",
+		},
+		{
+			Name:           "missing key",
+			RawVars:        []string{"=prod"},
+			ExpectedErrMsg: "invalid variable '=prod', expected format is key=value",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			actualVariables, err := ParseVarFlags(tc.RawVars)
+			if tc.ExpectedErrMsg != "" {
+				assert.EqualError(t, err, tc.ExpectedErrMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ExpectedVariables, actualVariables)
+		})
+	}
+}
+
+func TestLoadVarsFiles(t *testing.T) {
+	varsDir, err := ioutil.TempDir("", "tacoscript-vars")
+	assert.NoError(t, err)
+	defer os.RemoveAll(varsDir)
+
+	commonPath := filepath.Join(varsDir, "common.yaml")
+	assert.NoError(t, ioutil.WriteFile(commonPath, []byte("env: staging\ndb:\n  host: db-staging\n  port: 5432\n"), 0600))
+
+	prodPath := filepath.Join(varsDir, "prod.yaml")
+	assert.NoError(t, ioutil.WriteFile(prodPath, []byte("env: production\ndb:\n  host: db-prod\n"), 0600))
+
+	brokenPath := filepath.Join(varsDir, "broken.yaml")
+	assert.NoError(t, ioutil.WriteFile(brokenPath, []byte("- not a map"), 0600))
+
+	actualVariables, err := LoadVarsFiles([]string{commonPath, prodPath})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"env": "production",
+		"db":  map[string]interface{}{"host": "db-prod"},
+	}, actualVariables)
+
+	_, err = LoadVarsFiles([]string{brokenPath})
+	assert.EqualError(
+		t,
+		err,
+		"failed to parse vars file '"+brokenPath+"': yaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into map[string]interface {}",
+	)
+
+	_, err = LoadVarsFiles([]string{filepath.Join(varsDir, "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestExtractVarsSection(t *testing.T) {
+	templateData := `# deployment
+vars:
+  env: staging
+  # database
+  db_host: db-{{ .env }}
+
+  replicas: 2
+deploy:
+  cmd.run:
+    - name: echo {{ .env }}
+`
+
+	varsSection := extractVarsSection(templateData)
+
+	assert.Equal(t, `
+vars:
+  env: staging
+  # database
+  db_host: db-{{ .env }}
+
+  replicas: 2
+
+
+
+`, varsSection)
+
+	assert.Equal(t, "", extractVarsSection("deploy:\n  cmd.run:\n    - name: echo 1\n"))
+}
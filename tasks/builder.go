@@ -2,7 +2,9 @@ package tasks
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudradar-monitoring/tacoscript/conv"
 )
@@ -42,40 +44,16 @@ func parseCreatesField(val interface{}, path string) (createsItems []string, err
 	return
 }
 
-func parseRequireField(val interface{}, path string) (requireItems []string, err error) {
-	requireItems = make([]string, 0)
-	switch typedVal := val.(type) {
-	case string:
-		requireItems = append(requireItems, typedVal)
-	default:
-		requireItems, err = conv.ConvertToValues(val, path)
-	}
-
-	return
+func parseOnlyIfField(val interface{}, path string) (onlyIf []string, conditions []Condition, err error) {
+	return parseConditionalField(val, path+"."+OnlyIf)
 }
 
-func parseOnlyIfField(val interface{}, path string) (onlyIf []string, err error) {
-	onlyIf = make([]string, 0)
-	switch typedVal := val.(type) {
-	case string:
-		onlyIf = append(onlyIf, typedVal)
-	default:
-		onlyIf, err = conv.ConvertToValues(val, path)
-	}
-
-	return
+func parseUnlessField(val interface{}, path string) (unless []string, conditions []Condition, err error) {
+	return parseConditionalField(val, path+"."+Unless)
 }
 
-func parseUnlessField(val interface{}, path string) (unless []string, err error) {
-	unless = make([]string, 0)
-	switch typedVal := val.(type) {
-	case string:
-		unless = append(unless, typedVal)
-	default:
-		unless, err = conv.ConvertToValues(val, path)
-	}
-
-	return
+func parseCheckCmdField(val interface{}, path string) (checkCmds []string, err error) {
+	return parseCreatesField(val, path+"."+CheckCmdField)
 }
 
 func parseBoolField(val interface{}) bool {
@@ -91,3 +69,22 @@ func parseBoolField(val interface{}) bool {
 		return true
 	}
 }
+
+// parseRefreshIntervalField accepts either a number of minutes or a duration string like "1h30m"
+func parseRefreshIntervalField(val interface{}, path string) (time.Duration, error) {
+	if minutes, ok := val.(int); ok {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+
+	valStr := strings.TrimSpace(fmt.Sprint(val))
+	if minutes, err := strconv.Atoi(valStr); err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+
+	interval, err := time.ParseDuration(valStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid refresh interval '%s' at path '%s', expected number of minutes or a duration like '1h'", valStr, path)
+	}
+
+	return interval, nil
+}
@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"bytes"
+	"fmt"
+
+	exec2 "github.com/cloudradar-monitoring/tacoscript/exec"
+)
+
+// CheckResult is the outcome of check_cmd which verifies that the task has reached the desired state after its execution
+type CheckResult struct {
+	StdOut   string
+	StdErr   string
+	ExitCode int
+}
+
+// runCheckCmd runs the check_cmd commands with the cwd, env, shell and user of the task,
+// a failed check fails the task even if its execution has succeeded
+func runCheckCmd(execCtx *exec2.Context, runner exec2.Runner, checkCmds []string, path string) (*CheckResult, error) {
+	var stdoutBuf, stderrBuf bytes.Buffer
+	checkCtx := execCtx.Copy()
+	checkCtx.StdoutWriter = &stdoutBuf
+	checkCtx.StderrWriter = &stderrBuf
+	checkCtx.Cmds = checkCmds
+
+	err := runner.Run(&checkCtx)
+
+	checkRes := &CheckResult{
+		StdOut: stdoutBuf.String(),
+		StdErr: stderrBuf.String(),
+	}
+
+	if err == nil {
+		return checkRes, nil
+	}
+
+	if runErr, isRunErr := err.(exec2.RunError); isRunErr {
+		checkRes.ExitCode = runErr.ExitCode
+		return checkRes, fmt.Errorf("%s failed for the task at path '%s': %w", CheckCmdField, path, err)
+	}
+
+	return nil, err
+}
@@ -0,0 +1,165 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cloudradar-monitoring/tacoscript/applog"
+	"github.com/cloudradar-monitoring/tacoscript/conv"
+	exec2 "github.com/cloudradar-monitoring/tacoscript/exec"
+)
+
+// CmdContext are the cwd, env, shell and user fields which are used by all commands of a task including the onlyif
+// and unless ones, a task type supports them by embedding CmdContext, adding cmdContextFieldsSchema to its schema
+// with withCmdContextFields and passing its fields to parseField
+type CmdContext struct {
+	WorkingDir string
+	User       string
+	Shell      string
+	Envs       conv.KeyValues
+	// CheckShell overrides the shell of the onlyif and unless commands
+	CheckShell string
+	// CheckUser overrides the user of the onlyif and unless commands
+	CheckUser string
+	// HideOutput replaces the output of the task commands with *** in the results and doesn't log it
+	HideOutput bool
+	// Sensitive hides the output and redacts the values of all env variables of the task
+	Sensitive bool
+	// SecretEnvs are the keys of the env variables which values are redacted in the logs, results and errors
+	SecretEnvs []string
+}
+
+var cmdContextFieldsSchema = FieldsSchema{
+	CwdField:        FieldTypeString,
+	UserField:       FieldTypeString,
+	ShellField:      FieldTypeString,
+	EnvField:        FieldTypeKeyValueList,
+	CheckShellField: FieldTypeString,
+	CheckUserField:  FieldTypeString,
+
+	HideOutputField: FieldTypeBool,
+	SensitiveField:  FieldTypeBool,
+	SecretEnvField:  FieldTypeStringOrList,
+}
+
+// CmdContextTask is implemented by the tasks which embed CmdContext
+type CmdContextTask interface {
+	GetCmdContext() CmdContext
+}
+
+// GetCmdContext is promoted to the tasks which embed CmdContext
+func (cc CmdContext) GetCmdContext() CmdContext {
+	return cc
+}
+
+// withCmdContextFields adds the fields of CmdContext to the schema of a task type
+func withCmdContextFields(schema FieldsSchema) FieldsSchema {
+	for field, fieldType := range cmdContextFieldsSchema {
+		schema[field] = fieldType
+	}
+
+	return schema
+}
+
+// parseField sets the command context field, isCmdContext is false if the key is not a field of CmdContext
+func (cc *CmdContext) parseField(key string, val interface{}, path string) (isCmdContext bool, err error) {
+	switch key {
+	case CwdField:
+		cc.WorkingDir = fmt.Sprint(val)
+	case UserField:
+		cc.User = fmt.Sprint(val)
+	case ShellField:
+		cc.Shell = fmt.Sprint(val)
+	case EnvField:
+		cc.Envs, err = conv.ConvertToKeyValues(val, path)
+	case CheckShellField:
+		cc.CheckShell = fmt.Sprint(val)
+	case CheckUserField:
+		cc.CheckUser = fmt.Sprint(val)
+	case HideOutputField:
+		cc.HideOutput = conv.ConvertToBool(val)
+	case SensitiveField:
+		cc.Sensitive = conv.ConvertToBool(val)
+	case SecretEnvField:
+		cc.SecretEnvs, err = parseCreatesField(val, path+"."+SecretEnvField)
+	default:
+		return false, nil
+	}
+
+	return true, err
+}
+
+// newExecContext gives the execution context of the task commands
+func (cc CmdContext) newExecContext(ctx context.Context, path string, stdoutWriter, stderrWriter io.Writer) *exec2.Context {
+	return &exec2.Context{
+		Ctx:          ctx,
+		StdoutWriter: stdoutWriter,
+		StderrWriter: stderrWriter,
+		WorkingDir:   cc.WorkingDir,
+		User:         cc.User,
+		Path:         path,
+		Envs:         cc.Envs,
+		Shell:        cc.Shell,
+		HideOutput:   cc.IsOutputHidden(),
+	}
+}
+
+// IsOutputHidden is true if the output of the task commands shouldn't appear in the logs and results
+func (cc CmdContext) IsOutputHidden() bool {
+	return cc.HideOutput || cc.Sensitive
+}
+
+// addSecrets registers the values of the secret env variables of the task, so they are redacted
+// in the logs, results and errors, it's called when the task is built
+func (cc CmdContext) addSecrets() {
+	secretEnvs := make(map[string]bool, len(cc.SecretEnvs))
+	for _, key := range cc.SecretEnvs {
+		secretEnvs[key] = true
+	}
+
+	for _, env := range cc.Envs {
+		if cc.Sensitive || secretEnvs[env.Key] {
+			applog.AddSecrets(env.Value)
+		}
+	}
+}
+
+// hideOutput replaces the output of the task commands in the execution result with applog.RedactedValue
+func (cc CmdContext) hideOutput(execRes *ExecutionResult) {
+	if !cc.IsOutputHidden() {
+		return
+	}
+
+	execRes.StdOut = redactOutput(execRes.StdOut)
+	execRes.StdErr = redactOutput(execRes.StdErr)
+	if execRes.Check != nil {
+		execRes.Check.StdOut = redactOutput(execRes.Check.StdOut)
+		execRes.Check.StdErr = redactOutput(execRes.Check.StdErr)
+	}
+}
+
+func redactOutput(output string) string {
+	if output == "" {
+		return output
+	}
+
+	return applog.RedactedValue
+}
+
+// checkExecContext gives a copy of the task execution context for the onlyif and unless commands,
+// check_shell and check_user replace the shell and user of the task if they're set
+func (cc CmdContext) checkExecContext(execCtx *exec2.Context) exec2.Context {
+	checkCtx := execCtx.Copy()
+	checkCtx.Cmds = nil
+
+	if cc.CheckShell != "" {
+		checkCtx.Shell = cc.CheckShell
+	}
+
+	if cc.CheckUser != "" {
+		checkCtx.User = cc.CheckUser
+	}
+
+	return checkCtx
+}
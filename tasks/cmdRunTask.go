@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	exec2 "github.com/cloudradar-monitoring/tacoscript/exec"
 
 	"github.com/cloudradar-monitoring/tacoscript/utils"
 
+	"github.com/cloudradar-monitoring/tacoscript/applog"
 	"github.com/cloudradar-monitoring/tacoscript/conv"
 
 	"github.com/sirupsen/logrus"
@@ -19,64 +21,124 @@ type CmdRunTask struct {
 	TypeName string
 	Path     string
 	NamedTask
-	WorkingDir            string
-	User                  string
-	Shell                 string
-	Envs                  conv.KeyValues
-	MissingFilesCondition []string
-	Require               []string
-	OnlyIf                []string
-	Unless                []string
+	CmdContext
+	CheckCmd []string
+	// Stateful commands report if they changed the system in the last line of their output
+	Stateful bool
+	Success  SuccessRules
+	Require  []Requisite
+	Order    Order
+	Conditionals
+	Requisites
 }
 
 type CmdRunTaskBuilder struct {
 }
 
+var cmdRunFieldsSchema = withCmdContextFields(withConditionalsFields(FieldsSchema{
+	NameField:      FieldTypeString,
+	NamesField:     FieldTypeList,
+	CheckCmdField:  FieldTypeStringOrList,
+	StatefulField:  FieldTypeBool,
+	RequireField:   FieldTypeRequisites,
+	OnChangesField: FieldTypeRequisites,
+	WatchField:     FieldTypeRequisites,
+	OnFailField:    FieldTypeRequisites,
+	OnFailAnyField: FieldTypeRequisites,
+	OnFailAllField: FieldTypeRequisites,
+	RequireInField: FieldTypeRequisites,
+	WatchInField:   FieldTypeRequisites,
+	OrderField:     FieldTypeOrder,
+
+	SuccessRetcodesField: FieldTypeIntOrList,
+	SuccessStdoutField:   FieldTypeStringOrList,
+	SuccessStderrField:   FieldTypeStringOrList,
+}))
+
 func (crtb CmdRunTaskBuilder) Build(typeName, path string, ctx []map[string]interface{}) (Task, error) {
 	t := &CmdRunTask{
 		TypeName: typeName,
 		Path:     path,
 	}
 
+	// the fields are parsed even if the schema check fails, so the requisites of the task can be checked too
+	schemaErr := cmdRunFieldsSchema.Validate(path, ctx)
+
 	errs := utils.Errors{}
 	for _, contextItem := range ctx {
 		for key, val := range contextItem {
-			var err error
+			isConditional, err := t.Conditionals.parseField(key, val, path)
+			if isConditional {
+				errs.Add(err)
+				continue
+			}
+
+			isCmdContext, err := t.CmdContext.parseField(key, val, path)
+			if isCmdContext {
+				errs.Add(err)
+				continue
+			}
+
 			switch key {
 			case NameField:
 				t.Name = fmt.Sprint(val)
-			case CwdField:
-				t.WorkingDir = fmt.Sprint(val)
-			case UserField:
-				t.User = fmt.Sprint(val)
-			case ShellField:
-				t.Shell = fmt.Sprint(val)
-			case EnvField:
-				var envs conv.KeyValues
-				envs, err = conv.ConvertToKeyValues(val, path)
-				errs.Add(err)
-				t.Envs = envs
-			case CreatesField:
-				t.MissingFilesCondition, err = parseCreatesField(val, path)
-				errs.Add(err)
 			case NamesField:
 				var names []string
 				names, err = conv.ConvertToValues(val, path)
 				errs.Add(err)
 				t.Names = names
+			case CheckCmdField:
+				t.CheckCmd, err = parseCheckCmdField(val, path)
+				errs.Add(err)
+			case StatefulField:
+				t.Stateful = conv.ConvertToBool(val)
+			case SuccessRetcodesField:
+				t.Success.Retcodes, err = parseRetcodesField(val, path)
+				errs.Add(err)
+			case SuccessStdoutField:
+				t.Success.Stdout, err = parseSuccessPatternsField(val, path+"."+SuccessStdoutField)
+				errs.Add(err)
+			case SuccessStderrField:
+				t.Success.Stderr, err = parseSuccessPatternsField(val, path+"."+SuccessStderrField)
+				errs.Add(err)
 			case RequireField:
-				t.Require, err = parseRequireField(val, path)
+				t.Require, err = parseRequisitesField(val, path)
+				errs.Add(err)
+			case OnChangesField:
+				t.OnChanges, err = parseRequisitesField(val, path)
+				errs.Add(err)
+			case WatchField:
+				t.Watch, err = parseRequisitesField(val, path)
+				errs.Add(err)
+			case OnFailField:
+				t.OnFail, err = parseRequisitesField(val, path)
 				errs.Add(err)
-			case OnlyIf:
-				t.OnlyIf, err = parseOnlyIfField(val, path)
+			case OnFailAnyField:
+				t.OnFailAny, err = parseRequisitesField(val, path)
 				errs.Add(err)
-			case Unless:
-				t.Unless, err = parseUnlessField(val, path)
+			case OnFailAllField:
+				t.OnFailAll, err = parseRequisitesField(val, path)
+				errs.Add(err)
+			case RequireInField:
+				t.RequireIn, err = parseRequisitesField(val, path)
+				errs.Add(err)
+			case WatchInField:
+				t.WatchIn, err = parseRequisitesField(val, path)
+				errs.Add(err)
+			case OrderField:
+				t.Order, err = parseOrderField(val, path)
 				errs.Add(err)
 			}
 		}
 	}
 
+	t.CmdContext.addSecrets()
+
+	if schemaErr != nil {
+		// the field errors repeat the schema ones
+		return t, schemaErr
+	}
+
 	return t, errs.ToError()
 }
 
@@ -84,10 +146,14 @@ func (crt *CmdRunTask) GetName() string {
 	return crt.TypeName
 }
 
-func (crt *CmdRunTask) GetRequirements() []string {
+func (crt *CmdRunTask) GetRequirements() []Requisite {
 	return crt.Require
 }
 
+func (crt *CmdRunTask) GetOrder() Order {
+	return crt.Order
+}
+
 func (crt *CmdRunTask) Validate() error {
 	errs := &utils.Errors{}
 	err1 := ValidateRequired(crt.Name, crt.Path+"."+NameField)
@@ -107,12 +173,47 @@ func (crt *CmdRunTask) GetPath() string {
 }
 
 func (crt *CmdRunTask) String() string {
-	return conv.ConvertSourceToJSONStrIfPossible(crt)
+	return applog.Redact(conv.ConvertSourceToJSONStrIfPossible(crt))
 }
 
 type CmdRunTaskExecutor struct {
-	Runner    exec2.Runner
-	FsManager FsManager
+	Runner           exec2.Runner
+	ConditionChecker *ConditionChecker
+}
+
+// runCmds executes the commands of the task one by one, so a failed command which is accepted by the success rules
+// doesn't stop the following ones, the first failure which is not accepted stops the execution
+func (crte *CmdRunTaskExecutor) runCmds(execCtx *exec2.Context, cmdRunTask *CmdRunTask, execRes *ExecutionResult) error {
+	for _, name := range cmdRunTask.GetNames() {
+		var cmdStdoutBuf, cmdStderrBuf bytes.Buffer
+		cmdCtx := execCtx.Copy()
+		cmdCtx.Cmds = []string{name}
+		cmdCtx.StdoutWriter = io.MultiWriter(execCtx.StdoutWriter, &cmdStdoutBuf)
+		cmdCtx.StderrWriter = io.MultiWriter(execCtx.StderrWriter, &cmdStderrBuf)
+
+		err := crte.Runner.Run(&cmdCtx)
+		execCtx.Pids = append(execCtx.Pids, cmdCtx.Pids...)
+		if err == nil {
+			continue
+		}
+
+		// a command which failed to start has no exit code which the success rules could accept
+		runErr, isRunErr := err.(exec2.RunError)
+		if !isRunErr || !runErr.HasExited() {
+			return err
+		}
+
+		successRule := cmdRunTask.Success.accept(runErr.ExitCode, cmdStdoutBuf.String(), cmdStderrBuf.String())
+		if successRule == "" {
+			return err
+		}
+
+		logrus.Debugf("exit code %d of '%s' at %s is accepted by %s", runErr.ExitCode, name, cmdRunTask.Path, successRule)
+		execRes.ExitCode = runErr.ExitCode
+		execRes.SuccessRule = successRule
+	}
+
+	return nil
 }
 
 func (crte *CmdRunTaskExecutor) Execute(ctx context.Context, task Task) ExecutionResult {
@@ -124,19 +225,10 @@ func (crte *CmdRunTaskExecutor) Execute(ctx context.Context, task Task) Executio
 	}
 
 	var stdoutBuf, stderrBuf bytes.Buffer
-	execCtx := &exec2.Context{
-		Ctx:          ctx,
-		StdoutWriter: &stdoutBuf,
-		StderrWriter: &stderrBuf,
-		WorkingDir:   cmdRunTask.WorkingDir,
-		User:         cmdRunTask.User,
-		Path:         cmdRunTask.Path,
-		Envs:         cmdRunTask.Envs,
-		Cmds:         cmdRunTask.GetNames(),
-		Shell:        cmdRunTask.Shell,
-	}
+	execCtx := cmdRunTask.newExecContext(ctx, cmdRunTask.Path, &stdoutBuf, &stderrBuf)
+	execCtx.Cmds = cmdRunTask.GetNames()
 
-	shouldBeExecuted, err := crte.shouldBeExecuted(execCtx, cmdRunTask)
+	shouldBeExecuted, err := checkConditionals(execCtx, crte.Runner, crte.ConditionChecker, cmdRunTask, cmdRunTask.Path)
 	if err != nil {
 		execRes.Err = err
 		return execRes
@@ -149,7 +241,7 @@ func (crte *CmdRunTaskExecutor) Execute(ctx context.Context, task Task) Executio
 
 	start := time.Now()
 
-	err = crte.Runner.Run(execCtx)
+	err = crte.runCmds(execCtx, cmdRunTask, &execRes)
 
 	execRes.Duration = time.Since(start)
 	if err != nil {
@@ -161,112 +253,30 @@ func (crte *CmdRunTaskExecutor) Execute(ctx context.Context, task Task) Executio
 	execRes.StdOut = stdoutBuf.String()
 	execRes.Pids = execCtx.Pids
 
-	return execRes
-}
+	// a command which exited with an error has run, so it's a change unlike a failure to start it
+	runErr, isRunErr := err.(exec2.RunError)
+	execRes.Changed = err == nil || isRunErr && runErr.HasExited()
 
-func (crte *CmdRunTaskExecutor) checkOnlyIfs(ctx *exec2.Context, cmdRunTask *CmdRunTask) (isSuccess bool, err error) {
-	if len(cmdRunTask.OnlyIf) == 0 {
-		return true, nil
+	if isRunErr {
+		execRes.ExitCode = runErr.ExitCode
 	}
 
-	newCtx := ctx.Copy()
-
-	newCtx.Cmds = cmdRunTask.OnlyIf
-	err = crte.Runner.Run(&newCtx)
-
-	if err != nil {
-		runErr, isRunErr := err.(exec2.RunError)
-		if isRunErr {
-			logrus.Debugf("will skip %s since onlyif condition has failed: %v", cmdRunTask.Path, runErr)
-			return false, nil
-		}
-
-		return false, err
-	}
-
-	return true, nil
-}
-
-func (crte *CmdRunTaskExecutor) checkUnless(ctx *exec2.Context, cmdRunTask *CmdRunTask) (isExpectationSuccess bool, err error) {
-	if len(cmdRunTask.Unless) == 0 {
-		return true, nil
-	}
-
-	newCtx := ctx.Copy()
-
-	newCtx.Cmds = cmdRunTask.Unless
-
-	err = crte.Runner.Run(&newCtx)
-
-	if err != nil {
-		runErr, isRunErr := err.(exec2.RunError)
-		if isRunErr {
-			logrus.Infof("will continue cmd since at least one unless condition has failed: %v", runErr)
-			return true, nil
+	if err == nil && cmdRunTask.Stateful {
+		var state commandState
+		state, execRes.StdOut, err = parseCommandState(execRes.StdOut)
+		if err != nil {
+			execRes.Err = fmt.Errorf("failed to read the state of the stateful command at path '%s': %w", cmdRunTask.Path, err)
+		} else {
+			execRes.Changed = state.Changed
+			execRes.Comment = state.Comment
 		}
-
-		return false, err
 	}
 
-	logrus.Debugf("any unless condition didn't fail for task '%s'", cmdRunTask.Path)
-	return false, nil
-}
-
-func (crte *CmdRunTaskExecutor) shouldBeExecuted(ctx *exec2.Context, cmdRunTask *CmdRunTask) (shouldBeExecuted bool, err error) {
-	isExists, err := crte.checkMissingFileCondition(cmdRunTask)
-	if err != nil {
-		return false, err
+	if err == nil && len(cmdRunTask.CheckCmd) > 0 {
+		execRes.Check, execRes.Err = runCheckCmd(execCtx, crte.Runner, cmdRunTask.CheckCmd, cmdRunTask.Path)
 	}
 
-	if isExists {
-		logrus.Debugf("some files exist, will skip the execution of %s", cmdRunTask.Path)
-		return false, nil
-	}
+	cmdRunTask.hideOutput(&execRes)
 
-	isSuccess, err := crte.checkOnlyIfs(ctx, cmdRunTask)
-	if err != nil {
-		return false, err
-	}
-
-	if !isSuccess {
-		return false, nil
-	}
-
-	isExpectationSuccess, err := crte.checkUnless(ctx, cmdRunTask)
-	if err != nil {
-		return false, err
-	}
-
-	if !isExpectationSuccess {
-		logrus.Debugf("check of unless section was false, will skip %s", cmdRunTask.Path)
-		return false, nil
-	}
-
-	logrus.Debugf("all execution conditions are met, will continue %s", cmdRunTask.Path)
-	return true, nil
-}
-
-func (crte *CmdRunTaskExecutor) checkMissingFileCondition(cmdRunTask *CmdRunTask) (isExists bool, err error) {
-	if len(cmdRunTask.MissingFilesCondition) == 0 {
-		return
-	}
-
-	for _, missingFileCondition := range cmdRunTask.MissingFilesCondition {
-		if missingFileCondition == "" {
-			continue
-		}
-		isExists, err = crte.FsManager.FileExists(missingFileCondition)
-		if err != nil {
-			err = fmt.Errorf("failed to check if file '%s' exists: %w", missingFileCondition, err)
-			return
-		}
-
-		if isExists {
-			logrus.Debugf("file '%s' exists", missingFileCondition)
-			return
-		}
-		logrus.Debugf("file '%s' doesn't exist", missingFileCondition)
-	}
-
-	return
+	return execRes
 }
@@ -30,29 +30,51 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 						"one": "1",
 						"two": "2",
 					}),
-					CreatesField: "somefile.txt",
-					OnlyIf:       "one condition",
+					CreatesField:         "somefile.txt",
+					CheckCmdField:        []interface{}{"test -f somefile.txt", "grep -q done somefile.txt"},
+					StatefulField:        "true",
+					SuccessRetcodesField: []interface{}{0, 1},
+					SuccessStderrField:   "already exists",
+					OnlyIf:               "one condition",
+					Unless: []interface{}{
+						"unless command",
+						map[interface{}]interface{}{FileExistsCondition: "/etc/app.conf"},
+					},
 				},
 			},
 			expectedTask: &CmdRunTask{
-				TypeName:   "someType",
-				Path:       "somePath",
-				NamedTask:  NamedTask{Name: "1"},
-				WorkingDir: "somedir",
-				User:       "someuser",
-				Shell:      "someshell",
-				Envs: conv.KeyValues{
-					{
-						Key:   "one",
-						Value: "1",
+				TypeName:  "someType",
+				Path:      "somePath",
+				NamedTask: NamedTask{Name: "1"},
+				CmdContext: CmdContext{
+					WorkingDir: "somedir",
+					User:       "someuser",
+					Shell:      "someshell",
+					Envs: conv.KeyValues{
+						{
+							Key:   "one",
+							Value: "1",
+						},
+						{
+							Key:   "two",
+							Value: "2",
+						},
 					},
-					{
-						Key:   "two",
-						Value: "2",
+				},
+				CheckCmd: []string{"test -f somefile.txt", "grep -q done somefile.txt"},
+				Stateful: true,
+				Success: SuccessRules{
+					Retcodes: []int{0, 1},
+					Stderr:   []string{"already exists"},
+				},
+				Conditionals: Conditionals{
+					Creates: []string{"somefile.txt"},
+					OnlyIf:  []string{"one condition"},
+					Unless:  []string{"unless command"},
+					UnlessConditions: []Condition{
+						{Kind: FileExistsCondition, Path: "somePath.unless[2].file_exists", Name: "/etc/app.conf"},
 					},
 				},
-				MissingFilesCondition: []string{"somefile.txt"},
-				OnlyIf:                []string{"one condition"},
 			},
 		},
 		{
@@ -66,9 +88,11 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 			expectedTask: &CmdRunTask{
 				TypeName: "someTypeWithErrors",
 				Path:     "somePathWithErrors",
-				Envs:     conv.KeyValues{},
+				CmdContext: CmdContext{
+					Envs: conv.KeyValues{},
+				},
 			},
-			expectedError: "key value array expected at 'somePathWithErrors' but got '123'",
+			expectedError: "invalid value '123' at path 'somePathWithErrors.env', expected a list of key value maps e.g. [{KEY: value}]",
 		},
 		{
 			typeName: "someTypeWithErrors2",
@@ -83,9 +107,11 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 			expectedTask: &CmdRunTask{
 				TypeName: "someTypeWithErrors2",
 				Path:     "somePathWithErrors2",
-				Envs:     conv.KeyValues{},
+				CmdContext: CmdContext{
+					Envs: conv.KeyValues{},
+				},
 			},
-			expectedError: `wrong key value element at 'somePathWithErrors2': '"one"'`,
+			expectedError: `invalid value '["one"]' at path 'somePathWithErrors2.env', expected a list of key value maps e.g. [{KEY: value}]`,
 		},
 		{
 			typeName: "manyNamesType",
@@ -102,9 +128,9 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 			expectedTask: &CmdRunTask{
 				TypeName: "manyNamesType",
 				Path:     "manyNamesPath",
-				Require: []string{
+				Require: ScriptRequisites([]string{
 					"one require field",
-				},
+				}),
 				NamedTask: NamedTask{Names: []string{
 					"name one",
 					"name two",
@@ -138,22 +164,48 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 				TypeName:  "manyCreatesType",
 				Path:      "manyCreatesPath",
 				NamedTask: NamedTask{Name: "many creates command"},
-				MissingFilesCondition: []string{
-					"create one",
-					"create two",
-					"create three",
-				},
-				Require: []string{
+				Require: ScriptRequisites([]string{
 					"req one",
 					"req two",
 					"req three",
+				}),
+				Conditionals: Conditionals{
+					Creates: []string{
+						"create one",
+						"create two",
+						"create three",
+					},
+					OnlyIf: []string{
+						"OnlyIf one",
+						"OnlyIf two",
+						"OnlyIf three",
+					},
 				},
-				OnlyIf: []string{
-					"OnlyIf one",
-					"OnlyIf two",
-					"OnlyIf three",
+			},
+		},
+		{
+			typeName: "changeRequisitesType",
+			path:     "changeRequisitesPath",
+			ctx: []map[string]interface{}{
+				{
+					NameField:      "systemctl restart nginx",
+					OnChangesField: []interface{}{map[interface{}]interface{}{"file": "/etc/nginx/nginx.conf"}},
+					WatchField:     "install-nginx",
+					OnFailAllField: []interface{}{"backup", map[interface{}]interface{}{"pkg": "nginx"}},
+					OrderField:     "last",
 				},
 			},
+			expectedTask: &CmdRunTask{
+				TypeName:  "changeRequisitesType",
+				Path:      "changeRequisitesPath",
+				NamedTask: NamedTask{Name: "systemctl restart nginx"},
+				Requisites: Requisites{
+					OnChanges: []Requisite{{Module: "file", Ref: "/etc/nginx/nginx.conf"}},
+					Watch:     []Requisite{{Ref: "install-nginx"}},
+					OnFailAll: []Requisite{{Ref: "backup"}, {Module: "pkg", Ref: "nginx"}},
+				},
+				Order: Order{Kind: OrderLast},
+			},
 		},
 		{
 			typeName: "oneUnlessValue",
@@ -168,8 +220,10 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 				TypeName:  "oneUnlessValue",
 				Path:      "oneUnlessValuePath",
 				NamedTask: NamedTask{Name: "one unless value"},
-				Unless: []string{
-					"unless one",
+				Conditionals: Conditionals{
+					Unless: []string{
+						"unless one",
+					},
 				},
 			},
 		},
@@ -190,10 +244,41 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 				TypeName:  "manyUnlessValue",
 				Path:      "manyUnlessValuePath",
 				NamedTask: NamedTask{Name: "many unless value"},
-				Unless: []string{
-					"Unless one",
-					"Unless two",
-					"Unless three",
+				Conditionals: Conditionals{
+					Unless: []string{
+						"Unless one",
+						"Unless two",
+						"Unless three",
+					},
+				},
+			},
+		},
+		{
+			typeName: "secretEnvType",
+			path:     "secretEnvPath",
+			ctx: []map[string]interface{}{
+				{
+					NameField: "mysql -u root",
+					EnvField: BuildExpectedEnvs(map[interface{}]interface{}{
+						"MYSQL_PWD": "bunny",
+					}),
+					SecretEnvField:  "MYSQL_PWD",
+					HideOutputField: "yes",
+				},
+			},
+			expectedTask: &CmdRunTask{
+				TypeName:  "secretEnvType",
+				Path:      "secretEnvPath",
+				NamedTask: NamedTask{Name: "mysql -u root"},
+				CmdContext: CmdContext{
+					Envs: conv.KeyValues{
+						{
+							Key:   "MYSQL_PWD",
+							Value: "bunny",
+						},
+					},
+					HideOutput: true,
+					SecretEnvs: []string{"MYSQL_PWD"},
 				},
 			},
 		},
@@ -229,7 +314,7 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 			AssertEnvValuesMatch(t, tc.expectedTask.Envs, actualCmdRunTask.Envs.ToEqualSignStrings())
 			assert.Equal(t, tc.expectedTask.Path, actualCmdRunTask.Path)
 			assert.Equal(t, tc.expectedTask.WorkingDir, actualCmdRunTask.WorkingDir)
-			assert.Equal(t, tc.expectedTask.MissingFilesCondition, actualCmdRunTask.MissingFilesCondition)
+			assert.Equal(t, tc.expectedTask.Creates, actualCmdRunTask.Creates)
 			assert.Equal(t, tc.expectedTask.Name, actualCmdRunTask.Name)
 			assert.Equal(t, tc.expectedTask.TypeName, actualCmdRunTask.TypeName)
 			assert.Equal(t, tc.expectedTask.Shell, actualCmdRunTask.Shell)
@@ -237,6 +322,11 @@ func TestCmdRunTaskBuilder(t *testing.T) {
 			assert.Equal(t, tc.expectedTask.Require, actualCmdRunTask.Require)
 			assert.Equal(t, tc.expectedTask.OnlyIf, actualCmdRunTask.OnlyIf)
 			assert.Equal(t, tc.expectedTask.Unless, actualCmdRunTask.Unless)
+			assert.Equal(t, tc.expectedTask.OnlyIfConditions, actualCmdRunTask.OnlyIfConditions)
+			assert.Equal(t, tc.expectedTask.UnlessConditions, actualCmdRunTask.UnlessConditions)
+			assert.Equal(t, tc.expectedTask.HideOutput, actualCmdRunTask.HideOutput)
+			assert.Equal(t, tc.expectedTask.Sensitive, actualCmdRunTask.Sensitive)
+			assert.Equal(t, tc.expectedTask.SecretEnvs, actualCmdRunTask.SecretEnvs)
 		})
 	}
 }
@@ -3,6 +3,7 @@ package tasks
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os/exec"
 	"strings"
 	"testing"
@@ -28,20 +29,25 @@ func TestTaskExecution(t *testing.T) {
 		{
 			Name: "test one name command with 2 envs",
 			Task: &CmdRunTask{
-				Path:       "somepath",
-				NamedTask:  NamedTask{Name: "some test command"},
-				WorkingDir: "/tmp/dev",
-				User:       "user",
-				Shell:      "zsh",
-				Envs: conv.KeyValues{
-					{
-						Key:   "someenvkey1",
-						Value: "someenvval2",
+				Path:      "somepath",
+				NamedTask: NamedTask{Name: "some test command"},
+				CmdContext: CmdContext{
+					WorkingDir: "/tmp/dev",
+					User:       "user",
+					Shell:      "zsh",
+					Envs: conv.KeyValues{
+						{
+							Key:   "someenvkey1",
+							Value: "someenvval2",
+						},
 					},
 				},
-				MissingFilesCondition: []string{""},
+				Conditionals: Conditionals{
+					Creates: []string{""},
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 				StdOut:    "some std out",
@@ -59,9 +65,13 @@ func TestTaskExecution(t *testing.T) {
 		{
 			Name: "test skip command if file exists",
 			Task: &CmdRunTask{
-				User:                  "some user",
-				NamedTask:             NamedTask{Name: "some parser command"},
-				MissingFilesCondition: []string{"somefile.txt"},
+				CmdContext: CmdContext{
+					User: "some user",
+				},
+				NamedTask: NamedTask{Name: "some parser command"},
+				Conditionals: Conditionals{
+					Creates: []string{"somefile.txt"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -78,7 +88,9 @@ func TestTaskExecution(t *testing.T) {
 			Name: "test setting user failure",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "echo 12345"},
-				User:      "some user",
+				CmdContext: CmdContext{
+					User: "some user",
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: false,
@@ -96,6 +108,7 @@ func TestTaskExecution(t *testing.T) {
 				NamedTask: NamedTask{Name: "lpwd"},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   false,
 				IsSkipped: false,
 				Err:       appExec.RunError{Err: errors.New("some runner error")},
 			},
@@ -113,10 +126,13 @@ func TestTaskExecution(t *testing.T) {
 					"many names cmd 2",
 					"many names cmd 3",
 				}},
-				WorkingDir: "/many/dev",
-				User:       "usermany",
+				CmdContext: CmdContext{
+					WorkingDir: "/many/dev",
+					User:       "usermany",
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 			},
@@ -130,12 +146,15 @@ func TestTaskExecution(t *testing.T) {
 			Name: "test multiple create file conditions",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd with many MissingFilesConditions"},
-				MissingFilesCondition: []string{
-					"file.one",
-					"file.two",
+				Conditionals: Conditionals{
+					Creates: []string{
+						"file.one",
+						"file.two",
+					},
 				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 			},
@@ -149,9 +168,12 @@ func TestTaskExecution(t *testing.T) {
 			Name: "executing one onlyif condition with success",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd lala"},
-				OnlyIf:    []string{"check before lala"},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check before lala"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 			},
@@ -161,11 +183,107 @@ func TestTaskExecution(t *testing.T) {
 				ErrToGive: nil,
 			}},
 		},
+		{
+			Name: "stateful command without changes",
+			Task: &CmdRunTask{
+				NamedTask: NamedTask{Name: "migrate db"},
+				Stateful:  true,
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: false,
+				StdOut:  "migrated 0 rows\n",
+				Comment: "nothing to migrate",
+			},
+			ExpectedCmdStrs: []string{"migrate db"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds:       []*exec.Cmd{},
+				StdOutText: "migrated 0 rows\nchanged=no comment=\"nothing to migrate\"\n",
+			}},
+		},
+		{
+			Name: "failed command accepted by success stdout",
+			Task: &CmdRunTask{
+				NamedTask: NamedTask{Name: "diff a.txt b.txt"},
+				Success: SuccessRules{
+					Retcodes: []int{2},
+					Stdout:   []string{"^< "},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed:     true,
+				StdOut:      "< one\n> two\n",
+				SuccessRule: "success_stdout: ^< ",
+			},
+			ExpectedCmdStrs: []string{"diff a.txt b.txt"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds:       []*exec.Cmd{},
+				StdOutText: "< one\n> two\n",
+				ErrToGive:  &exec.ExitError{},
+			}},
+		},
+		{
+			Name: "command which failed to start is not accepted by success retcodes",
+			Task: &CmdRunTask{
+				NamedTask:  NamedTask{Name: "diff a.txt b.txt"},
+				CmdContext: CmdContext{Shell: "/nonexistent/zsh"},
+				Success:    SuccessRules{Retcodes: []int{0, 1}},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: false,
+				Err:     appExec.RunError{Err: errors.New("fork/exec /nonexistent/zsh: no such file or directory")},
+			},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds:      []*exec.Cmd{},
+				ErrToGive: errors.New("fork/exec /nonexistent/zsh: no such file or directory"),
+			}},
+		},
+		{
+			Name: "command with hidden output",
+			Task: &CmdRunTask{
+				NamedTask:  NamedTask{Name: "cat /etc/app/token"},
+				CmdContext: CmdContext{HideOutput: true},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: true,
+				StdOut:  "***",
+			},
+			ExpectedCmdStrs: []string{"cat /etc/app/token"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds:       []*exec.Cmd{},
+				StdOutText: "c2VjcmV0LXRva2Vu",
+			}},
+		},
+		{
+			Name: "executing onlyif with check shell",
+			Task: &CmdRunTask{
+				NamedTask: NamedTask{Name: "cmd with check shell"},
+				CmdContext: CmdContext{
+					WorkingDir: "/tmp/dev",
+					Shell:      "zsh",
+					CheckShell: "bash",
+				},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check with bash"},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed:   true,
+				IsSkipped: false,
+				Err:       nil,
+			},
+			ExpectedCmdStrs: []string{"bash -c check with bash", "zsh -c cmd with check shell"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds:      []*exec.Cmd{},
+				ErrToGive: nil,
+			}},
+		},
 		{
 			Name: "executing one onlyif condition with failure",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd with OnlyIf failure"},
-				OnlyIf:    []string{"check OnlyIf error"},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check OnlyIf error"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -189,7 +307,9 @@ func TestTaskExecution(t *testing.T) {
 			Name: "executing multiple onlyif conditions with failure",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd with multiple OnlyIf failure"},
-				OnlyIf:    []string{"check OnlyIf success", "check OnlyIf failure"},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check OnlyIf success", "check OnlyIf failure"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -212,9 +332,12 @@ func TestTaskExecution(t *testing.T) {
 			Name: "executing multiple onlyif conditions with success",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd with multiple OnlyIf success"},
-				OnlyIf:    []string{"check OnlyIf success 1", "check OnlyIf success 2"},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check OnlyIf success 1", "check OnlyIf success 2"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 			},
@@ -227,8 +350,12 @@ func TestTaskExecution(t *testing.T) {
 			Name: "executing onlyif validation error",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd"},
-				OnlyIf:    []string{"checking onlyif validation error"},
-				User:      "some user 123",
+				CmdContext: CmdContext{
+					User: "some user 123",
+				},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"checking onlyif validation error"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: false,
@@ -247,13 +374,85 @@ func TestTaskExecution(t *testing.T) {
 				},
 			}},
 		},
+		{
+			Name: "skipping command if onlyif condition is not met",
+			Task: &CmdRunTask{
+				NamedTask: NamedTask{Name: "cmd with onlyif condition"},
+				Conditionals: Conditionals{
+					OnlyIf:           []string{"check onlyif after condition"},
+					OnlyIfConditions: []Condition{{Kind: FileExistsCondition, Name: "/etc/app.conf"}},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				IsSkipped: true,
+			},
+			ExpectedCmdStrs: []string{},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+		},
+		{
+			Name: "executing onlyif commands after met onlyif conditions",
+			Task: &CmdRunTask{
+				NamedTask: NamedTask{Name: "cmd with met onlyif condition"},
+				Conditionals: Conditionals{
+					OnlyIf:           []string{"check onlyif after condition"},
+					OnlyIfConditions: []Condition{{Kind: FileExistsCondition, Name: "/etc/app.conf"}},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: true,
+			},
+			ExpectedCmdStrs: []string{"check onlyif after condition", "cmd with met onlyif condition"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			FileShouldExist: true,
+		},
+		{
+			Name: "skipping command if unless conditions are met",
+			Task: &CmdRunTask{
+				NamedTask: NamedTask{Name: "cmd with met unless condition"},
+				Conditionals: Conditionals{
+					UnlessConditions: []Condition{{Kind: FileExistsCondition, Name: "/etc/app.conf"}},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				IsSkipped: true,
+			},
+			ExpectedCmdStrs: []string{},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			FileShouldExist: true,
+		},
+		{
+			Name: "executing command without unless commands if unless condition is not met",
+			Task: &CmdRunTask{
+				NamedTask: NamedTask{Name: "cmd with unless condition"},
+				Conditionals: Conditionals{
+					Unless:           []string{"check unless after condition"},
+					UnlessConditions: []Condition{{Kind: FileExistsCondition, Name: "/etc/app.conf"}},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: true,
+			},
+			ExpectedCmdStrs: []string{"cmd with unless condition"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+		},
 		{
 			Name: "executing one unless condition with success",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd masa"},
-				Unless:    []string{"run unless masa"},
+				Conditionals: Conditionals{
+					Unless: []string{"run unless masa"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 			},
@@ -274,7 +473,9 @@ func TestTaskExecution(t *testing.T) {
 			Name: "executing one unless condition with failure",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd with unless failure"},
-				Unless:    []string{"check unless failure"},
+				Conditionals: Conditionals{
+					Unless: []string{"check unless failure"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -289,7 +490,9 @@ func TestTaskExecution(t *testing.T) {
 			Name: "executing multiple unless conditions with all success",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd with multiple unless success"},
-				Unless:    []string{"check unless one", "check unless two"},
+				Conditionals: Conditionals{
+					Unless: []string{"check unless one", "check unless two"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -304,9 +507,12 @@ func TestTaskExecution(t *testing.T) {
 			Name: "executing multiple unless conditions with at least one failure",
 			Task: &CmdRunTask{
 				NamedTask: NamedTask{Name: "cmd with multiple unless with at least one failure"},
-				Unless:    []string{"check unless 1", "check unless 2"},
+				Conditionals: Conditionals{
+					Unless: []string{"check unless 1", "check unless 2"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 			},
@@ -326,9 +532,13 @@ func TestTaskExecution(t *testing.T) {
 		{
 			Name: "executing unless validation error",
 			Task: &CmdRunTask{
-				Unless:    []string{"checking unless validation error"},
 				NamedTask: NamedTask{Name: "executing unless validation error"},
-				User:      "some user 345",
+				CmdContext: CmdContext{
+					User: "some user 345",
+				},
+				Conditionals: Conditionals{
+					Unless: []string{"checking unless validation error"},
+				},
 			},
 			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
 				Cmds:               []*exec.Cmd{},
@@ -351,8 +561,10 @@ func TestTaskExecution(t *testing.T) {
 		t.Run(tc.Name, func(tt *testing.T) {
 			cmdRunExecutor := &CmdRunTaskExecutor{
 				Runner: tc.RunnerMock,
-				FsManager: &apptest.FsManagerMock{
-					FileExistsExistsToReturn: tc.FileShouldExist,
+				ConditionChecker: &ConditionChecker{
+					FsManager: &apptest.FsManagerMock{
+						FileExistsExistsToReturn: tc.FileShouldExist,
+					},
 				},
 			}
 
@@ -360,7 +572,10 @@ func TestTaskExecution(t *testing.T) {
 			assert.EqualValues(tt, tc.ExpectedResult.Err, res.Err)
 			assert.EqualValues(tt, tc.ExpectedResult.IsSkipped, res.IsSkipped)
 			assert.EqualValues(tt, tc.ExpectedResult.StdOut, res.StdOut)
+			assert.EqualValues(tt, tc.ExpectedResult.Changed, res.Changed)
 			assert.EqualValues(tt, tc.ExpectedResult.StdErr, res.StdErr)
+			assert.EqualValues(tt, tc.ExpectedResult.Comment, res.Comment)
+			assert.EqualValues(tt, tc.ExpectedResult.SuccessRule, res.SuccessRule)
 
 			if tc.ExpectedResult.Err != nil {
 				assert.Equal(tt, len(tc.ExpectedCmdStrs), len(systemAPIMock.Cmds))
@@ -461,3 +676,140 @@ func TestCmdRunTaskValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestCmdRunTaskCheckCmd(t *testing.T) {
+	checkErr := errors.New("exit status 1")
+
+	testCases := []struct {
+		Name             string
+		CheckCmdErr      error
+		ExpectedErr      error
+		ExpectedCheckRes *CheckResult
+		ExpectedCmdStrs  []string
+	}{
+		{
+			Name:             "successful check",
+			ExpectedCheckRes: &CheckResult{StdOut: "output of check service nginx"},
+			ExpectedCmdStrs:  []string{"service nginx start", "check service nginx"},
+		},
+		{
+			Name:             "failed check",
+			CheckCmdErr:      checkErr,
+			ExpectedErr:      fmt.Errorf("check_cmd failed for the task at path 'nginx.cmd.run[1]': %w", appExec.RunError{Err: checkErr}),
+			ExpectedCheckRes: &CheckResult{StdOut: "output of check service nginx"},
+			ExpectedCmdStrs:  []string{"service nginx start", "check service nginx"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			systemAPIMock := &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+				Callback: func(cmd *exec.Cmd) error {
+					if !strings.HasSuffix(cmd.String(), "check service nginx") {
+						return nil
+					}
+
+					_, err := cmd.Stdout.Write([]byte("output of check service nginx"))
+					assert.NoError(tt, err)
+
+					return tc.CheckCmdErr
+				},
+			}
+			cmdRunExecutor := &CmdRunTaskExecutor{
+				Runner: &appExec.SystemRunner{SystemAPI: systemAPIMock},
+			}
+
+			res := cmdRunExecutor.Execute(context.Background(), &CmdRunTask{
+				Path:      "nginx.cmd.run[1]",
+				NamedTask: NamedTask{Name: "service nginx start"},
+				CheckCmd:  []string{"check service nginx"},
+			})
+
+			assert.Equal(tt, tc.ExpectedErr, res.Err)
+			assert.True(tt, res.Changed)
+			assert.Equal(tt, tc.ExpectedCheckRes, res.Check)
+			AssertCmdsPartiallyMatch(tt, tc.ExpectedCmdStrs, systemAPIMock.Cmds)
+		})
+	}
+}
+
+func TestCmdRunTaskSuccessRulesWithManyNames(t *testing.T) {
+	copyErr := &exec.ExitError{}
+
+	testCases := []struct {
+		Name            string
+		Success         SuccessRules
+		ExpectedErr     error
+		ExpectedRule    string
+		ExpectedCmdStrs []string
+	}{
+		{
+			Name:            "accepted failure doesn't stop the next commands",
+			Success:         SuccessRules{Stdout: []string{"files copied"}},
+			ExpectedRule:    "success_stdout: files copied",
+			ExpectedCmdStrs: []string{"robocopy C:\\Data D:\\Backup", "next-step", "last-step"},
+		},
+		{
+			Name:            "rejected failure stops the next commands",
+			Success:         SuccessRules{Stdout: []string{"nothing to copy"}},
+			ExpectedErr:     appExec.RunError{Err: copyErr, ExitCode: copyErr.ExitCode()},
+			ExpectedCmdStrs: []string{"robocopy C:\\Data D:\\Backup"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			systemAPIMock := &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+				Callback: func(cmd *exec.Cmd) error {
+					if !strings.Contains(cmd.String(), "robocopy") {
+						return nil
+					}
+
+					_, err := cmd.Stdout.Write([]byte("2 files copied"))
+					assert.NoError(tt, err)
+
+					return copyErr
+				},
+			}
+			cmdRunExecutor := &CmdRunTaskExecutor{
+				Runner: &appExec.SystemRunner{SystemAPI: systemAPIMock},
+			}
+
+			res := cmdRunExecutor.Execute(context.Background(), &CmdRunTask{
+				Path:      "backup.cmd.run[1]",
+				NamedTask: NamedTask{Names: []string{"robocopy C:\\Data D:\\Backup", "next-step", "last-step"}},
+				Success:   tc.Success,
+			})
+
+			assert.Equal(tt, tc.ExpectedErr, res.Err)
+			assert.Equal(tt, tc.ExpectedRule, res.SuccessRule)
+			assert.Equal(tt, "2 files copied", res.StdOut)
+			assert.Len(tt, systemAPIMock.Cmds, len(tc.ExpectedCmdStrs))
+			AssertCmdsPartiallyMatch(tt, tc.ExpectedCmdStrs, systemAPIMock.Cmds)
+		})
+	}
+}
+
+func TestCmdRunTaskStringRedactsSecrets(t *testing.T) {
+	task, err := CmdRunTaskBuilder{}.Build("cmd.run", "db.cmd.run[1]", []map[string]interface{}{
+		{
+			NameField: "mysql -u root",
+			EnvField: BuildExpectedEnvs(map[interface{}]interface{}{
+				"MYSQL_PWD": "s3cr3t-pwd",
+				"MYSQL_DB":  "app_production",
+			}),
+			SensitiveField: true,
+		},
+	})
+	assert.NoError(t, err)
+
+	taskStr := task.(*CmdRunTask).String()
+	assert.NotContains(t, taskStr, "s3cr3t-pwd")
+	assert.NotContains(t, taskStr, "app_production")
+	assert.Contains(t, taskStr, `"Value":"***"`)
+	assert.Contains(t, taskStr, "mysql -u root")
+}
@@ -16,7 +16,7 @@ type Task interface {
 	GetName() string
 	Validate() error
 	GetPath() string
-	GetRequirements() []string
+	GetRequirements() []Requisite
 }
 
 type ExecutionResult struct {
@@ -26,6 +26,15 @@ type ExecutionResult struct {
 	StdOut    string
 	IsSkipped bool
 	Pids      []int
+	// Changed is true if the task changed the system e.g. a command was run, a file was written or a package was installed
+	Changed bool
+	// Check is the outcome of check_cmd, it's nil if the task has no check_cmd or it wasn't run
+	Check *CheckResult
+	// Comment is reported by the task itself e.g. by a stateful command
+	Comment string
+	// ExitCode is the exit code of the failed command, SuccessRule is set if one of the success rules has accepted it
+	ExitCode    int
+	SuccessRule string
 }
 
 func (tr *ExecutionResult) String() string {
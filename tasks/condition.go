@@ -0,0 +1,244 @@
+package tasks
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/cloudradar-monitoring/tacoscript/conv"
+)
+
+const (
+	FileExistsCondition   = "file_exists"
+	FileContainsCondition = "file_contains"
+	EnvEqualsCondition    = "env_equals"
+	FactEqualsCondition   = "fact_equals"
+	PortOpenCondition     = "port_open"
+	UserExistsCondition   = "user_exists"
+	PkgInstalledCondition = "pkg_installed"
+	AllCondition          = "all"
+	AnyCondition          = "any"
+	NotCondition          = "not"
+
+	conditionNameArg    = "name"
+	conditionValueArg   = "value"
+	conditionPatternArg = "pattern"
+)
+
+var conditionKinds = []string{
+	AllCondition,
+	AnyCondition,
+	EnvEqualsCondition,
+	FactEqualsCondition,
+	FileContainsCondition,
+	FileExistsCondition,
+	NotCondition,
+	PkgInstalledCondition,
+	PortOpenCondition,
+	UserExistsCondition,
+}
+
+// Condition is a structured check of onlyif and unless which is evaluated without a shell e.g. "- file_exists: /etc/hosts",
+// the all, any and not conditions combine the nested ones
+type Condition struct {
+	Kind string
+	// Path is the path of the condition in the script e.g. "nginx.cmd.run[1].onlyif[2].file_exists"
+	Path string
+	// Name is the checked file, environment variable, fact, network address, user or package
+	Name string
+	// Value is the expected value of env_equals and fact_equals or the regular expression of file_contains
+	Value string
+	// Conditions are the nested conditions of all, any and not
+	Conditions []Condition
+}
+
+// parseConditionalField splits the values of onlyif and unless into the shell commands and the structured conditions
+func parseConditionalField(val interface{}, path string) (cmds []string, conditions []Condition, err error) {
+	cmds = make([]string, 0)
+
+	rawItems, ok := val.([]interface{})
+	if !ok {
+		rawItems = []interface{}{val}
+	}
+
+	for i, rawItem := range rawItems {
+		if isScalarValue(rawItem) {
+			cmds = append(cmds, fmt.Sprint(rawItem))
+			continue
+		}
+
+		var condition Condition
+		condition, err = parseCondition(rawItem, fmt.Sprintf("%s[%d]", path, i+1))
+		if err != nil {
+			return cmds, conditions, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return cmds, conditions, nil
+}
+
+func parseCondition(rawCondition interface{}, path string) (Condition, error) {
+	typedCondition, ok := rawCondition.(map[interface{}]interface{})
+	if !ok || len(typedCondition) != 1 {
+		return Condition{}, fmt.Errorf(
+			"invalid condition '%s' at path '%s', expected a single condition e.g. {%s: /etc/hosts}",
+			conv.ConvertSourceToJSONStrIfPossible(rawCondition),
+			path,
+			FileExistsCondition,
+		)
+	}
+
+	for rawKind, args := range typedCondition {
+		kind := fmt.Sprint(rawKind)
+		condition := Condition{
+			Kind: kind,
+			Path: path + "." + kind,
+		}
+
+		var err error
+		switch kind {
+		case FileExistsCondition, UserExistsCondition, PkgInstalledCondition:
+			condition.Name, err = parseConditionName(args, condition.Path)
+		case PortOpenCondition:
+			condition.Name, err = parseConditionName(args, condition.Path)
+			if err == nil {
+				if _, _, splitErr := net.SplitHostPort(condition.Name); splitErr != nil {
+					err = fmt.Errorf("invalid address '%s' at path '%s', expected host:port e.g. 127.0.0.1:22", condition.Name, condition.Path)
+				}
+			}
+		case FileContainsCondition:
+			condition.Name, condition.Value, err = parseConditionArgs(args, condition.Path, conditionPatternArg)
+			if err == nil {
+				if _, compileErr := regexp.Compile(condition.Value); compileErr != nil {
+					err = fmt.Errorf("invalid pattern '%s' at path '%s.%s': %v", condition.Value, condition.Path, conditionPatternArg, compileErr)
+				}
+			}
+		case EnvEqualsCondition, FactEqualsCondition:
+			condition.Name, condition.Value, err = parseConditionArgs(args, condition.Path, conditionValueArg)
+		case AllCondition, AnyCondition, NotCondition:
+			condition.Conditions, err = parseNestedConditions(args, condition.Path)
+		default:
+			err = fmt.Errorf("unknown condition '%s' at path '%s', expected one of %s", kind, path, strings.Join(conditionKinds, ", "))
+		}
+
+		return condition, err
+	}
+
+	return Condition{}, nil
+}
+
+func parseConditionName(args interface{}, path string) (string, error) {
+	name := ""
+	if args != nil && isScalarValue(args) {
+		name = strings.TrimSpace(fmt.Sprint(args))
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("invalid value '%s' at path '%s', expected a non empty scalar value", conv.ConvertSourceToJSONStrIfPossible(args), path)
+	}
+
+	return name, nil
+}
+
+// parseConditionArgs parses the conditions with arguments e.g. {name: APP_ENV, value: prod}
+func parseConditionArgs(args interface{}, path, valueArg string) (name, value string, err error) {
+	typedArgs, ok := args.(map[interface{}]interface{})
+	if !ok {
+		return "", "", fmt.Errorf(
+			"invalid value '%s' at path '%s', expected a map with the %s and %s keys",
+			conv.ConvertSourceToJSONStrIfPossible(args),
+			path,
+			conditionNameArg,
+			valueArg,
+		)
+	}
+
+	hasValue := false
+	for rawKey, rawVal := range typedArgs {
+		key := fmt.Sprint(rawKey)
+		if !isScalarValue(rawVal) {
+			return "", "", fmt.Errorf("invalid value '%s' at path '%s.%s', expected a scalar value", conv.ConvertSourceToJSONStrIfPossible(rawVal), path, key)
+		}
+
+		switch key {
+		case conditionNameArg:
+			if rawVal != nil {
+				name = strings.TrimSpace(fmt.Sprint(rawVal))
+			}
+		case valueArg:
+			hasValue = true
+			if rawVal != nil {
+				value = fmt.Sprint(rawVal)
+			}
+		default:
+			return "", "", fmt.Errorf("unknown key '%s' at path '%s', expected %s or %s", key, path, conditionNameArg, valueArg)
+		}
+	}
+
+	if name == "" {
+		return "", "", fmt.Errorf("empty '%s' field at path '%s.%s'", conditionNameArg, path, conditionNameArg)
+	}
+
+	if !hasValue {
+		return "", "", fmt.Errorf("missing '%s' field at path '%s.%s'", valueArg, path, valueArg)
+	}
+
+	return name, value, nil
+}
+
+// parseNestedConditions parses the conditions of all, any and not, not accepts a single condition as well
+func parseNestedConditions(args interface{}, path string) ([]Condition, error) {
+	rawItems, ok := args.([]interface{})
+	if !ok {
+		if _, isMap := args.(map[interface{}]interface{}); !isMap {
+			return nil, fmt.Errorf(
+				"invalid value '%s' at path '%s', expected a list of conditions",
+				conv.ConvertSourceToJSONStrIfPossible(args),
+				path,
+			)
+		}
+		rawItems = []interface{}{args}
+	}
+
+	if len(rawItems) == 0 {
+		return nil, fmt.Errorf("empty list of conditions at path '%s'", path)
+	}
+
+	conditions := make([]Condition, 0, len(rawItems))
+	for i, rawItem := range rawItems {
+		condition, err := parseCondition(rawItem, fmt.Sprintf("%s[%d]", path, i+1))
+		if err != nil {
+			return conditions, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// isValidConditional accepts a command or a single condition map or a list of them
+func isValidConditional(val interface{}) bool {
+	if isScalarValue(val) {
+		return true
+	}
+
+	rawItems, ok := val.([]interface{})
+	if !ok {
+		rawItems = []interface{}{val}
+	}
+
+	for _, rawItem := range rawItems {
+		if isScalarValue(rawItem) {
+			continue
+		}
+
+		typedItem, ok := rawItem.(map[interface{}]interface{})
+		if !ok || len(typedItem) != 1 {
+			return false
+		}
+	}
+
+	return true
+}
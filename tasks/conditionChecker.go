@@ -0,0 +1,226 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultPortTimeout = 3 * time.Second
+
+// FactsProvider gives the host facts which are checked by fact_equals
+type FactsProvider interface {
+	GetTemplateVariables() (map[string]interface{}, error)
+}
+
+// InstalledPackagesProvider gives the installed packages with their versions which are checked by pkg_installed
+type InstalledPackagesProvider interface {
+	GetInstalledPackages(ctx context.Context) (map[string]string, error)
+}
+
+// ConditionChecker evaluates the structured conditions natively, so they behave the same way on all platforms,
+// the facts are collected once on the first fact_equals condition
+type ConditionChecker struct {
+	FsManager        FsManager
+	FactsProvider    FactsProvider
+	PackagesProvider InstalledPackagesProvider
+	// PortTimeout limits the connection attempt of port_open, defaultPortTimeout is used if it's zero
+	PortTimeout time.Duration
+
+	factsOnce sync.Once
+	facts     map[string]interface{}
+	factsErr  error
+}
+
+// Check is true if all conditions are met
+func (cc *ConditionChecker) Check(ctx context.Context, conditions []Condition) (bool, error) {
+	if len(conditions) == 0 {
+		return true, nil
+	}
+
+	if cc == nil {
+		return false, fmt.Errorf("cannot check the condition at path '%s', no condition checker is configured", conditions[0].Path)
+	}
+
+	for _, condition := range conditions {
+		isMet, err := cc.checkCondition(ctx, condition)
+		if err != nil {
+			return false, err
+		}
+
+		if !isMet {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (cc *ConditionChecker) checkCondition(ctx context.Context, condition Condition) (isMet bool, err error) {
+	switch condition.Kind {
+	case AllCondition:
+		return cc.Check(ctx, condition.Conditions)
+	case AnyCondition:
+		for _, nestedCondition := range condition.Conditions {
+			isMet, err = cc.checkCondition(ctx, nestedCondition)
+			if err != nil || isMet {
+				return isMet, err
+			}
+		}
+		return false, nil
+	case NotCondition:
+		isMet, err = cc.Check(ctx, condition.Conditions)
+		return !isMet && err == nil, err
+	case FileExistsCondition:
+		isMet, err = cc.FsManager.FileExists(condition.Name)
+	case FileContainsCondition:
+		isMet, err = cc.fileContains(condition.Name, condition.Value)
+	case EnvEqualsCondition:
+		envValue, isSet := os.LookupEnv(condition.Name)
+		isMet = isSet && envValue == condition.Value
+	case FactEqualsCondition:
+		isMet, err = cc.factEquals(condition.Name, condition.Value)
+	case PortOpenCondition:
+		isMet = cc.isPortOpen(ctx, condition.Name)
+	case UserExistsCondition:
+		isMet, err = userExists(condition.Name)
+	case PkgInstalledCondition:
+		isMet, err = cc.isPkgInstalled(ctx, condition.Name)
+	default:
+		err = fmt.Errorf("unknown condition '%s'", condition.Kind)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check the condition at path '%s': %w", condition.Path, err)
+	}
+
+	logrus.Debugf("condition at path '%s' is met: %v", condition.Path, isMet)
+
+	return isMet, nil
+}
+
+// fileContains matches the pattern in the multiline mode, so ^ and $ match the beginning and the end of each line
+func (cc *ConditionChecker) fileContains(filePath, pattern string) (bool, error) {
+	fileExists, err := cc.FsManager.FileExists(filePath)
+	if err != nil || !fileExists {
+		return false, err
+	}
+
+	contents, err := cc.FsManager.ReadFile(filePath)
+	if err != nil {
+		return false, err
+	}
+
+	rgx, err := regexp.Compile("(?m)" + pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return rgx.MatchString(contents), nil
+}
+
+// factEquals compares the fact with the expected value, the name is a dot separated path for nested facts
+// e.g. taco_local.app.env, a list fact equals the value if one of its items equals it
+func (cc *ConditionChecker) factEquals(name, value string) (bool, error) {
+	if cc.FactsProvider == nil {
+		return false, errors.New("no facts provider is configured")
+	}
+
+	cc.factsOnce.Do(func() {
+		cc.facts, cc.factsErr = cc.FactsProvider.GetTemplateVariables()
+	})
+	if cc.factsErr != nil {
+		return false, cc.factsErr
+	}
+
+	var fact interface{} = cc.facts
+	for _, key := range strings.Split(name, ".") {
+		switch typedFact := fact.(type) {
+		case map[string]interface{}:
+			fact = typedFact[key]
+		case map[interface{}]interface{}:
+			fact = typedFact[key]
+		case map[string][]string:
+			fact = typedFact[key]
+		case map[string]string:
+			fact = typedFact[key]
+		default:
+			return false, nil
+		}
+	}
+
+	switch typedFact := fact.(type) {
+	case nil:
+		return false, nil
+	case []string:
+		for _, item := range typedFact {
+			if item == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	case []interface{}:
+		for _, item := range typedFact {
+			if fmt.Sprint(item) == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return fmt.Sprint(fact) == value, nil
+	}
+}
+
+func (cc *ConditionChecker) isPortOpen(ctx context.Context, address string) bool {
+	timeout := cc.PortTimeout
+	if timeout == 0 {
+		timeout = defaultPortTimeout
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		logrus.Debugf("failed to connect to '%s': %v", address, err)
+		return false
+	}
+	conn.Close()
+
+	return true
+}
+
+func (cc *ConditionChecker) isPkgInstalled(ctx context.Context, packageName string) (bool, error) {
+	if cc.PackagesProvider == nil {
+		return false, errors.New("no packages provider is configured")
+	}
+
+	installedPackages, err := cc.PackagesProvider.GetInstalledPackages(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	_, isInstalled := installedPackages[packageName]
+
+	return isInstalled, nil
+}
+
+func userExists(userName string) (bool, error) {
+	_, err := user.Lookup(userName)
+	if err == nil {
+		return true, nil
+	}
+
+	if errors.As(err, new(user.UnknownUserError)) {
+		return false, nil
+	}
+
+	return false, err
+}
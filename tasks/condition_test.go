@@ -0,0 +1,317 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+)
+
+type FactsProviderMock struct {
+	facts     map[string]interface{}
+	errToGive error
+	calls     int
+}
+
+func (fpm *FactsProviderMock) GetTemplateVariables() (map[string]interface{}, error) {
+	fpm.calls++
+	return fpm.facts, fpm.errToGive
+}
+
+type PackagesProviderMock struct {
+	packages map[string]string
+}
+
+func (ppm PackagesProviderMock) GetInstalledPackages(ctx context.Context) (map[string]string, error) {
+	return ppm.packages, nil
+}
+
+func TestParseConditionalField(t *testing.T) {
+	testCases := []struct {
+		name               string
+		val                interface{}
+		expectedCmds       []string
+		expectedConditions []Condition
+		expectedErrMsg     string
+	}{
+		{
+			name:         "single_command",
+			val:          "test -f /etc/hosts",
+			expectedCmds: []string{"test -f /etc/hosts"},
+		},
+		{
+			name: "commands_and_conditions",
+			val: []interface{}{
+				"test -f /etc/hosts",
+				map[interface{}]interface{}{FileExistsCondition: "/etc/hosts"},
+				map[interface{}]interface{}{EnvEqualsCondition: map[interface{}]interface{}{"name": "APP_ENV", "value": "prod"}},
+				map[interface{}]interface{}{FileContainsCondition: map[interface{}]interface{}{"name": "/etc/hosts", "pattern": "^127"}},
+			},
+			expectedCmds: []string{"test -f /etc/hosts"},
+			expectedConditions: []Condition{
+				{Kind: FileExistsCondition, Path: "task.onlyif[2].file_exists", Name: "/etc/hosts"},
+				{Kind: EnvEqualsCondition, Path: "task.onlyif[3].env_equals", Name: "APP_ENV", Value: "prod"},
+				{Kind: FileContainsCondition, Path: "task.onlyif[4].file_contains", Name: "/etc/hosts", Value: "^127"},
+			},
+		},
+		{
+			name: "nested_conditions",
+			val: map[interface{}]interface{}{
+				AnyCondition: []interface{}{
+					map[interface{}]interface{}{PortOpenCondition: "127.0.0.1:5432"},
+					map[interface{}]interface{}{NotCondition: map[interface{}]interface{}{UserExistsCondition: "postgres"}},
+					map[interface{}]interface{}{AllCondition: []interface{}{
+						map[interface{}]interface{}{PkgInstalledCondition: "postgresql"},
+						map[interface{}]interface{}{FactEqualsCondition: map[interface{}]interface{}{"name": "taco_os_kernel", "value": "linux"}},
+					}},
+				},
+			},
+			expectedCmds: []string{},
+			expectedConditions: []Condition{
+				{
+					Kind: AnyCondition,
+					Path: "task.onlyif[1].any",
+					Conditions: []Condition{
+						{Kind: PortOpenCondition, Path: "task.onlyif[1].any[1].port_open", Name: "127.0.0.1:5432"},
+						{
+							Kind:       NotCondition,
+							Path:       "task.onlyif[1].any[2].not",
+							Conditions: []Condition{{Kind: UserExistsCondition, Path: "task.onlyif[1].any[2].not[1].user_exists", Name: "postgres"}},
+						},
+						{
+							Kind: AllCondition,
+							Path: "task.onlyif[1].any[3].all",
+							Conditions: []Condition{
+								{Kind: PkgInstalledCondition, Path: "task.onlyif[1].any[3].all[1].pkg_installed", Name: "postgresql"},
+								{Kind: FactEqualsCondition, Path: "task.onlyif[1].any[3].all[2].fact_equals", Name: "taco_os_kernel", Value: "linux"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "unknown_condition",
+			val:  []interface{}{map[interface{}]interface{}{"file_exist": "/etc/hosts"}},
+			expectedErrMsg: "unknown condition 'file_exist' at path 'task.onlyif[1]', expected one of " +
+				"all, any, env_equals, fact_equals, file_contains, file_exists, not, pkg_installed, port_open, user_exists",
+		},
+		{
+			name:           "invalid_port_address",
+			val:            []interface{}{map[interface{}]interface{}{PortOpenCondition: "5432"}},
+			expectedErrMsg: "invalid address '5432' at path 'task.onlyif[1].port_open', expected host:port e.g. 127.0.0.1:22",
+		},
+		{
+			name: "invalid_pattern",
+			val: []interface{}{map[interface{}]interface{}{
+				FileContainsCondition: map[interface{}]interface{}{"name": "/etc/hosts", "pattern": "[a-"},
+			}},
+			expectedErrMsg: "invalid pattern '[a-' at path 'task.onlyif[1].file_contains.pattern': " +
+				"error parsing regexp: missing closing ]: `[a-`",
+		},
+		{
+			name:           "missing_value",
+			val:            []interface{}{map[interface{}]interface{}{EnvEqualsCondition: map[interface{}]interface{}{"name": "APP_ENV"}}},
+			expectedErrMsg: "missing 'value' field at path 'task.onlyif[1].env_equals.value'",
+		},
+		{
+			name:           "empty_nested_conditions",
+			val:            []interface{}{map[interface{}]interface{}{AllCondition: []interface{}{}}},
+			expectedErrMsg: "empty list of conditions at path 'task.onlyif[1].all'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			cmds, conditions, err := parseOnlyIfField(tc.val, "task")
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCmds, cmds)
+			assert.Equal(t, tc.expectedConditions, conditions)
+		})
+	}
+}
+
+func TestConditionChecker(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "conditions")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	hostsPath := filepath.Join(tempDir, "hosts")
+	err = ioutil.WriteFile(hostsPath, []byte("127.0.0.1 localhost\n10.0.0.5 web01\n"), 0600)
+	assert.NoError(t, err)
+	missingPath := filepath.Join(tempDir, "missing")
+
+	err = os.Setenv("TACO_CONDITION_ENV", "prod")
+	assert.NoError(t, err)
+	defer os.Unsetenv("TACO_CONDITION_ENV")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	openAddress := listener.Addr().String()
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closedAddress := closedListener.Addr().String()
+	closedListener.Close()
+	defer listener.Close()
+
+	currentUser, err := user.Current()
+	assert.NoError(t, err)
+
+	checker := &ConditionChecker{
+		FsManager: &utils.FsManager{},
+		FactsProvider: &FactsProviderMock{facts: map[string]interface{}{
+			utils.OSKernel:    "linux",
+			utils.IPAddresses: []string{"10.0.0.5", "172.17.0.1"},
+			utils.LocalFacts:  map[string]interface{}{"app": map[interface{}]interface{}{"env": "prod"}},
+		}},
+		PackagesProvider: PackagesProviderMock{packages: map[string]string{"nginx": "1.18.0"}},
+	}
+
+	testCases := []struct {
+		name          string
+		conditions    []Condition
+		expectedIsMet bool
+	}{
+		{
+			name:          "no_conditions",
+			expectedIsMet: true,
+		},
+		{
+			name:          "file_exists",
+			conditions:    []Condition{{Kind: FileExistsCondition, Name: hostsPath}},
+			expectedIsMet: true,
+		},
+		{
+			name:       "file_doesnt_exist",
+			conditions: []Condition{{Kind: FileExistsCondition, Name: missingPath}},
+		},
+		{
+			name:          "file_contains_line",
+			conditions:    []Condition{{Kind: FileContainsCondition, Name: hostsPath, Value: `^10\.0\.0\.5\s+web01$`}},
+			expectedIsMet: true,
+		},
+		{
+			name:       "file_doesnt_contain",
+			conditions: []Condition{{Kind: FileContainsCondition, Name: hostsPath, Value: "db01"}},
+		},
+		{
+			name:       "missing_file_doesnt_contain",
+			conditions: []Condition{{Kind: FileContainsCondition, Name: missingPath, Value: "localhost"}},
+		},
+		{
+			name:          "env_equals",
+			conditions:    []Condition{{Kind: EnvEqualsCondition, Name: "TACO_CONDITION_ENV", Value: "prod"}},
+			expectedIsMet: true,
+		},
+		{
+			name:       "env_doesnt_equal",
+			conditions: []Condition{{Kind: EnvEqualsCondition, Name: "TACO_CONDITION_ENV", Value: "dev"}},
+		},
+		{
+			name:       "empty_value_of_missing_env",
+			conditions: []Condition{{Kind: EnvEqualsCondition, Name: "TACO_CONDITION_MISSING_ENV", Value: ""}},
+		},
+		{
+			name: "fact_equals",
+			conditions: []Condition{
+				{Kind: FactEqualsCondition, Name: utils.OSKernel, Value: "linux"},
+				{Kind: FactEqualsCondition, Name: utils.IPAddresses, Value: "172.17.0.1"},
+				{Kind: FactEqualsCondition, Name: "taco_local.app.env", Value: "prod"},
+			},
+			expectedIsMet: true,
+		},
+		{
+			name:       "missing_fact",
+			conditions: []Condition{{Kind: FactEqualsCondition, Name: "taco_local.db.env", Value: "prod"}},
+		},
+		{
+			name:          "port_open",
+			conditions:    []Condition{{Kind: PortOpenCondition, Name: openAddress}},
+			expectedIsMet: true,
+		},
+		{
+			name:       "port_closed",
+			conditions: []Condition{{Kind: PortOpenCondition, Name: closedAddress}},
+		},
+		{
+			name:          "user_exists",
+			conditions:    []Condition{{Kind: UserExistsCondition, Name: currentUser.Username}},
+			expectedIsMet: true,
+		},
+		{
+			name:       "user_doesnt_exist",
+			conditions: []Condition{{Kind: UserExistsCondition, Name: "tacoscript-missing-user"}},
+		},
+		{
+			name:          "pkg_installed",
+			conditions:    []Condition{{Kind: PkgInstalledCondition, Name: "nginx"}},
+			expectedIsMet: true,
+		},
+		{
+			name:       "pkg_not_installed",
+			conditions: []Condition{{Kind: PkgInstalledCondition, Name: "apache2"}},
+		},
+		{
+			name: "any_not_all",
+			conditions: []Condition{
+				{
+					Kind: AnyCondition,
+					Conditions: []Condition{
+						{Kind: FileExistsCondition, Name: missingPath},
+						{Kind: NotCondition, Conditions: []Condition{
+							{Kind: PkgInstalledCondition, Name: "nginx"},
+							{Kind: PkgInstalledCondition, Name: "apache2"},
+						}},
+					},
+				},
+			},
+			expectedIsMet: true,
+		},
+		{
+			name: "all_conditions_should_be_met",
+			conditions: []Condition{
+				{Kind: AllCondition, Conditions: []Condition{
+					{Kind: FileExistsCondition, Name: hostsPath},
+					{Kind: FileExistsCondition, Name: missingPath},
+				}},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			isMet, err := checker.Check(context.Background(), tc.conditions)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedIsMet, isMet)
+		})
+	}
+
+	assert.Equal(t, 1, checker.FactsProvider.(*FactsProviderMock).calls)
+}
+
+func TestConditionCheckerErrors(t *testing.T) {
+	conditions := []Condition{{Kind: FactEqualsCondition, Path: "task.onlyif[1].fact_equals", Name: utils.OSKernel, Value: "linux"}}
+
+	var nilChecker *ConditionChecker
+	_, err := nilChecker.Check(context.Background(), conditions)
+	assert.EqualError(t, err, "cannot check the condition at path 'task.onlyif[1].fact_equals', no condition checker is configured")
+
+	checker := &ConditionChecker{FactsProvider: &FactsProviderMock{errToGive: errors.New("no host info")}}
+	_, err = checker.Check(context.Background(), conditions)
+	assert.EqualError(t, err, "failed to check the condition at path 'task.onlyif[1].fact_equals': no host info")
+}
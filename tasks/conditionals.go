@@ -0,0 +1,225 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	exec2 "github.com/cloudradar-monitoring/tacoscript/exec"
+)
+
+// Conditionals are the creates, onlyif and unless fields which decide if a task should be executed,
+// a task type supports all of them by embedding Conditionals, adding conditionalsFieldsSchema to its schema
+// with withConditionalsFields and passing its fields to parseField
+type Conditionals struct {
+	// Creates skips the task if one of the files exists
+	Creates []string
+	// OnlyIf runs the task only if all commands succeed and all conditions are met
+	OnlyIf           []string
+	OnlyIfConditions []Condition
+	// Unless skips the task if all commands succeed and all conditions are met
+	Unless           []string
+	UnlessConditions []Condition
+}
+
+var conditionalsFieldsSchema = FieldsSchema{
+	CreatesField: FieldTypeStringOrList,
+	OnlyIf:       FieldTypeConditional,
+	Unless:       FieldTypeConditional,
+}
+
+// ConditionalTask is implemented by the tasks which embed Conditionals
+type ConditionalTask interface {
+	GetConditionals() Conditionals
+}
+
+// GetConditionals is promoted to the tasks which embed Conditionals
+func (c Conditionals) GetConditionals() Conditionals {
+	return c
+}
+
+// withConditionalsFields adds the fields of Conditionals to the schema of a task type
+func withConditionalsFields(schema FieldsSchema) FieldsSchema {
+	for field, fieldType := range conditionalsFieldsSchema {
+		schema[field] = fieldType
+	}
+
+	return schema
+}
+
+// parseField sets the conditional field, isConditional is false if the key is not a field of Conditionals
+func (c *Conditionals) parseField(key string, val interface{}, path string) (isConditional bool, err error) {
+	switch key {
+	case CreatesField:
+		c.Creates, err = parseCreatesField(val, path)
+	case OnlyIf:
+		c.OnlyIf, c.OnlyIfConditions, err = parseOnlyIfField(val, path)
+	case Unless:
+		c.Unless, c.UnlessConditions, err = parseUnlessField(val, path)
+	default:
+		return false, nil
+	}
+
+	return true, err
+}
+
+// checkedTask is a task with the conditionals which are checked in its command context
+type checkedTask interface {
+	ConditionalTask
+	CmdContextTask
+}
+
+// checkConditionals checks the creates, onlyif and unless fields of the task, it's shared by all executors,
+// the commands are executed by the runner with a copy of the task execution context which uses check_shell
+// and check_user if they're set
+func checkConditionals(
+	execCtx *exec2.Context,
+	runner exec2.Runner,
+	checker *ConditionChecker,
+	task checkedTask,
+	path string,
+) (bool, error) {
+	conditionals := task.GetConditionals()
+	checkCtx := task.GetCmdContext().checkExecContext(execCtx)
+
+	createsConditions := conditionals.createsConditions(path)
+	if len(createsConditions) > 0 {
+		isExists, err := checker.Check(execCtx.Ctx, createsConditions)
+		if err != nil {
+			return false, err
+		}
+
+		if isExists {
+			logrus.Debugf("some files exist, will skip the execution of %s", path)
+			return false, nil
+		}
+	}
+
+	isSuccess, err := checkOnlyIfs(&checkCtx, runner, checker, conditionals, path)
+	if err != nil {
+		return false, err
+	}
+
+	if !isSuccess {
+		return false, nil
+	}
+
+	isExpectationSuccess, err := checkUnless(&checkCtx, runner, checker, conditionals, path)
+	if err != nil {
+		return false, err
+	}
+
+	if !isExpectationSuccess {
+		logrus.Debugf("check of unless section was false, will skip %s", path)
+		return false, nil
+	}
+
+	logrus.Debugf("all execution conditions are met, will continue %s", path)
+	return true, nil
+}
+
+// createsConditions converts the creates files to a condition which is met if one of the files exists
+func (c Conditionals) createsConditions(path string) []Condition {
+	fileConditions := make([]Condition, 0, len(c.Creates))
+	for i, fileName := range c.Creates {
+		if fileName == "" {
+			continue
+		}
+
+		fileConditions = append(fileConditions, Condition{
+			Kind: FileExistsCondition,
+			Path: fmt.Sprintf("%s.%s[%d]", path, CreatesField, i+1),
+			Name: fileName,
+		})
+	}
+
+	if len(fileConditions) == 0 {
+		return nil
+	}
+
+	return []Condition{{Kind: AnyCondition, Path: path + "." + CreatesField, Conditions: fileConditions}}
+}
+
+func checkOnlyIfs(
+	execCtx *exec2.Context,
+	runner exec2.Runner,
+	checker *ConditionChecker,
+	conditionals Conditionals,
+	path string,
+) (isSuccess bool, err error) {
+	isSuccess, err = checker.Check(execCtx.Ctx, conditionals.OnlyIfConditions)
+	if err != nil {
+		return false, err
+	}
+
+	if !isSuccess {
+		logrus.Debugf("will skip %s since onlyif condition is not met", path)
+		return false, nil
+	}
+
+	if len(conditionals.OnlyIf) == 0 {
+		return true, nil
+	}
+
+	newCtx := execCtx.Copy()
+
+	newCtx.Cmds = conditionals.OnlyIf
+	err = runner.Run(&newCtx)
+
+	if err != nil {
+		runErr, isRunErr := err.(exec2.RunError)
+		if isRunErr {
+			logrus.Debugf("will skip %s since onlyif condition has failed: %v", path, runErr)
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func checkUnless(
+	execCtx *exec2.Context,
+	runner exec2.Runner,
+	checker *ConditionChecker,
+	conditionals Conditionals,
+	path string,
+) (isExpectationSuccess bool, err error) {
+	isMet, err := checker.Check(execCtx.Ctx, conditionals.UnlessConditions)
+	if err != nil {
+		return false, err
+	}
+
+	if !isMet {
+		logrus.Debugf("will continue %s since at least one unless condition is not met", path)
+		return true, nil
+	}
+
+	if len(conditionals.Unless) == 0 {
+		isExpectationSuccess = len(conditionals.UnlessConditions) == 0
+		if !isExpectationSuccess {
+			logrus.Debugf("all unless conditions are met for task '%s'", path)
+		}
+		return isExpectationSuccess, nil
+	}
+
+	newCtx := execCtx.Copy()
+
+	newCtx.Cmds = conditionals.Unless
+
+	err = runner.Run(&newCtx)
+
+	if err != nil {
+		runErr, isRunErr := err.(exec2.RunError)
+		if isRunErr {
+			logrus.Infof("will continue cmd since at least one unless condition has failed: %v", runErr)
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	logrus.Debugf("any unless condition didn't fail for task '%s'", path)
+	return false, nil
+}
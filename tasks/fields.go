@@ -6,6 +6,8 @@ const (
 	PkgInstalled   = "pkg.installed"
 	PkgRemoved     = "pkg.removed"
 	PkgUpgraded    = "pkg.uptodate"
+	PkgRepoManaged = "pkgrepo.managed"
+	PkgRepoAbsent  = "pkgrepo.absent"
 
 	NameField       = "name"
 	NamesField      = "names"
@@ -13,8 +15,20 @@ const (
 	UserField       = "user"
 	ShellField      = "shell"
 	EnvField        = "env"
+	CheckShellField = "check_shell"
+	CheckUserField  = "check_user"
 	CreatesField    = "creates"
+	CheckCmdField   = "check_cmd"
+	StatefulField   = "stateful"
 	RequireField    = "require"
+	OnChangesField  = "onchanges"
+	WatchField      = "watch"
+	OnFailField     = "onfail"
+	OnFailAnyField  = "onfail_any"
+	OnFailAllField  = "onfail_all"
+	RequireInField  = "require_in"
+	WatchInField    = "watch_in"
+	OrderField      = "order"
 	OnlyIf          = "onlyif"
 	Unless          = "unless"
 	SourceField     = "source"
@@ -28,4 +42,21 @@ const (
 	EncodingField   = "encoding"
 	Version         = "version"
 	Refresh         = "refresh"
+	RefreshInterval = "refresh_interval"
+	HumanNameField  = "humanname"
+	RepoField       = "repo"
+	BaseURLField    = "baseurl"
+	FileField       = "file"
+	KeyURLField     = "key_url"
+	EnabledField    = "enabled"
+	GpgCheckField   = "gpgcheck"
+	Deb822Field     = "deb822"
+
+	SuccessRetcodesField = "success_retcodes"
+	SuccessStdoutField   = "success_stdout"
+	SuccessStderrField   = "success_stderr"
+
+	SensitiveField  = "sensitive"
+	HideOutputField = "hide_output"
+	SecretEnvField  = "secret_env"
 )
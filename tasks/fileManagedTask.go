@@ -22,6 +22,29 @@ const DefaultFileMode = 0744
 type FileManagedTaskBuilder struct {
 }
 
+var fileManagedFieldsSchema = withCmdContextFields(withConditionalsFields(FieldsSchema{
+	NameField:       FieldTypeString,
+	CheckCmdField:   FieldTypeStringOrList,
+	RequireField:    FieldTypeRequisites,
+	OnChangesField:  FieldTypeRequisites,
+	WatchField:      FieldTypeRequisites,
+	OnFailField:     FieldTypeRequisites,
+	OnFailAnyField:  FieldTypeRequisites,
+	OnFailAllField:  FieldTypeRequisites,
+	RequireInField:  FieldTypeRequisites,
+	WatchInField:    FieldTypeRequisites,
+	OrderField:      FieldTypeOrder,
+	SkipVerifyField: FieldTypeBool,
+	SourceField:     FieldTypeString,
+	SourceHashField: FieldTypeString,
+	MakeDirsField:   FieldTypeBool,
+	GroupField:      FieldTypeString,
+	ModeField:       FieldTypeFileMode,
+	EncodingField:   FieldTypeString,
+	ContentsField:   FieldTypeString,
+	ReplaceField:    FieldTypeBool,
+}))
+
 type contextProc func(t *FileManagedTask, path string, val interface{}) error
 
 var contextProcMap = map[string]contextProc{
@@ -29,23 +52,54 @@ var contextProcMap = map[string]contextProc{
 		t.Name = fmt.Sprint(val)
 		return nil
 	},
-	UserField: func(t *FileManagedTask, path string, val interface{}) error {
-		t.User = fmt.Sprint(val)
-		return nil
-	},
-	CreatesField: func(t *FileManagedTask, path string, val interface{}) error {
+	CheckCmdField: func(t *FileManagedTask, path string, val interface{}) error {
 		var err error
-		t.Creates, err = parseCreatesField(val, path)
+		t.CheckCmd, err = parseCheckCmdField(val, path)
 		return err
 	},
 	RequireField: func(t *FileManagedTask, path string, val interface{}) error {
 		var err error
-		t.Require, err = parseRequireField(val, path)
+		t.Require, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnChangesField: func(t *FileManagedTask, path string, val interface{}) error {
+		var err error
+		t.OnChanges, err = parseRequisitesField(val, path)
+		return err
+	},
+	WatchField: func(t *FileManagedTask, path string, val interface{}) error {
+		var err error
+		t.Watch, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailField: func(t *FileManagedTask, path string, val interface{}) error {
+		var err error
+		t.OnFail, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailAnyField: func(t *FileManagedTask, path string, val interface{}) error {
+		var err error
+		t.OnFailAny, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailAllField: func(t *FileManagedTask, path string, val interface{}) error {
+		var err error
+		t.OnFailAll, err = parseRequisitesField(val, path)
+		return err
+	},
+	RequireInField: func(t *FileManagedTask, path string, val interface{}) error {
+		var err error
+		t.RequireIn, err = parseRequisitesField(val, path)
 		return err
 	},
-	OnlyIf: func(t *FileManagedTask, path string, val interface{}) error {
+	WatchInField: func(t *FileManagedTask, path string, val interface{}) error {
 		var err error
-		t.OnlyIf, err = parseOnlyIfField(val, path)
+		t.WatchIn, err = parseRequisitesField(val, path)
+		return err
+	},
+	OrderField: func(t *FileManagedTask, path string, val interface{}) error {
+		var err error
+		t.Order, err = parseOrderField(val, path)
 		return err
 	},
 	SkipVerifyField: func(t *FileManagedTask, path string, val interface{}) error {
@@ -94,9 +148,24 @@ func (fmtb FileManagedTaskBuilder) Build(typeName, path string, ctx []map[string
 		Replace:  true,
 	}
 
+	// the fields are parsed even if the schema check fails, so the requisites of the task can be checked too
+	schemaErr := fileManagedFieldsSchema.Validate(path, ctx)
+
 	errs := &utils.Errors{}
 	for _, contextItem := range ctx {
 		for key, val := range contextItem {
+			isConditional, err := t.Conditionals.parseField(key, val, path)
+			if isConditional {
+				errs.Add(err)
+				continue
+			}
+
+			isCmdContext, err := t.CmdContext.parseField(key, val, path)
+			if isCmdContext {
+				errs.Add(err)
+				continue
+			}
+
 			f, ok := contextProcMap[key]
 			if !ok {
 				continue
@@ -105,6 +174,13 @@ func (fmtb FileManagedTaskBuilder) Build(typeName, path string, ctx []map[string
 		}
 	}
 
+	t.CmdContext.addSecrets()
+
+	if schemaErr != nil {
+		// the field errors repeat the schema ones
+		return t, schemaErr
+	}
+
 	return t, errs.ToError()
 }
 
@@ -130,23 +206,34 @@ type FileManagedTask struct {
 	Name         string
 	SourceHash   string
 	Contents     sql.NullString
-	User         string
 	Group        string
 	Encoding     string
 	Source       utils.Location
-	Creates      []string
-	OnlyIf       []string
-	Require      []string
+	CheckCmd     []string
+	Require      []Requisite
+	Order        Order
+	// CmdContext is used by the onlyif and unless commands, its user is the owner of the file as well
+	CmdContext
+	Conditionals
+	Requisites
 }
 
 func (crt *FileManagedTask) GetName() string {
 	return crt.TypeName
 }
 
-func (crt *FileManagedTask) GetRequirements() []string {
+func (crt *FileManagedTask) GetRequirements() []Requisite {
 	return crt.Require
 }
 
+func (crt *FileManagedTask) GetOrder() Order {
+	return crt.Order
+}
+
+func (crt *FileManagedTask) GetNames() []string {
+	return []string{crt.Name}
+}
+
 func (crt *FileManagedTask) Validate() error {
 	errs := &utils.Errors{}
 
@@ -189,9 +276,10 @@ type HashManager interface {
 }
 
 type FileManagedTaskExecutor struct {
-	FsManager   FsManager
-	HashManager HashManager
-	Runner      exec2.Runner
+	FsManager        FsManager
+	HashManager      HashManager
+	Runner           exec2.Runner
+	ConditionChecker *ConditionChecker
 }
 
 func (fmte *FileManagedTaskExecutor) Execute(ctx context.Context, task Task) ExecutionResult {
@@ -205,13 +293,7 @@ func (fmte *FileManagedTaskExecutor) Execute(ctx context.Context, task Task) Exe
 	}
 
 	var stdoutBuf, stderrBuf bytes.Buffer
-	execCtx := &exec2.Context{
-		Ctx:          ctx,
-		StdoutWriter: &stdoutBuf,
-		StderrWriter: &stderrBuf,
-		User:         fileManagedTask.User,
-		Path:         fileManagedTask.Path,
-	}
+	execCtx := fileManagedTask.newExecContext(ctx, fileManagedTask.Path, &stdoutBuf, &stderrBuf)
 	logrus.Debugf("will check if the task '%s' should be executed", task.GetPath())
 	shouldBeExecuted, err := fmte.shouldBeExecuted(execCtx, fileManagedTask)
 	if err != nil {
@@ -240,23 +322,31 @@ func (fmte *FileManagedTaskExecutor) Execute(ctx context.Context, task Task) Exe
 			return execRes
 		}
 
-		err = fmte.copySourceToTarget(ctx, fileManagedTask)
+		var isCopied, isWritten bool
+		isCopied, err = fmte.copySourceToTarget(ctx, fileManagedTask)
 		if err != nil {
 			execRes.Err = err
 			return execRes
 		}
 
-		err = fmte.copyContentToTarget(fileManagedTask)
+		isWritten, err = fmte.copyContentToTarget(fileManagedTask)
 		if err != nil {
 			execRes.Err = err
 			return execRes
 		}
+		execRes.Changed = isCopied || isWritten
 	}
-	err = fmte.applyFileAttributesToTarget(fileManagedTask)
+	isModeChanged, err := fmte.applyFileAttributesToTarget(fileManagedTask)
 	if err != nil {
 		execRes.Err = err
 		return execRes
 	}
+	execRes.Changed = execRes.Changed || isModeChanged
+
+	if len(fileManagedTask.CheckCmd) > 0 {
+		execRes.Check, execRes.Err = runCheckCmd(execCtx, fmte.Runner, fileManagedTask.CheckCmd, fileManagedTask.Path)
+		fileManagedTask.hideOutput(&execRes)
+	}
 
 	execRes.Duration = time.Since(start)
 
@@ -282,43 +372,15 @@ func (fmte *FileManagedTaskExecutor) fileShouldBeReplaced(fileManagedTask *FileM
 	return true, nil
 }
 
-func (fmte *FileManagedTaskExecutor) checkOnlyIfs(ctx *exec2.Context, fileManagedTask *FileManagedTask) (isSuccess bool, err error) {
-	if len(fileManagedTask.OnlyIf) == 0 {
-		return true, nil
-	}
-
-	newCtx := ctx.Copy()
-
-	newCtx.Cmds = fileManagedTask.OnlyIf
-	err = fmte.Runner.Run(&newCtx)
-
-	if err != nil {
-		runErr, isRunErr := err.(exec2.RunError)
-		if isRunErr {
-			logrus.Debugf("will skip %s since onlyif condition has failed: %v", fileManagedTask, runErr)
-			return false, nil
-		}
-
-		return false, err
-	}
-
-	return true, nil
-}
-
 func (fmte *FileManagedTaskExecutor) shouldBeExecuted(
 	ctx *exec2.Context,
 	fileManagedTask *FileManagedTask,
 ) (shouldBeExecuted bool, err error) {
-	isExists, err := fmte.checkMissingFileCondition(fileManagedTask)
-	if err != nil {
+	isConditionsSuccess, err := checkConditionals(ctx, fmte.Runner, fmte.ConditionChecker, fileManagedTask, fileManagedTask.Path)
+	if err != nil || !isConditionsSuccess {
 		return false, err
 	}
 
-	if isExists {
-		logrus.Debugf("some files exist, will skip the execution of %s", fileManagedTask)
-		return false, nil
-	}
-
 	if fileManagedTask.SourceHash != "" {
 		var hashEquals bool
 		hashEquals, _, err = fmte.HashManager.HashEquals(fileManagedTask.SourceHash, fileManagedTask.Name)
@@ -331,15 +393,6 @@ func (fmte *FileManagedTaskExecutor) shouldBeExecuted(
 		}
 	}
 
-	isSuccess, err := fmte.checkOnlyIfs(ctx, fileManagedTask)
-	if err != nil {
-		return false, err
-	}
-
-	if !isSuccess {
-		return false, nil
-	}
-
 	shouldSkip, err := fmte.shouldSkipForContentExpectation(fileManagedTask)
 	if err != nil {
 		return false, err
@@ -352,36 +405,12 @@ func (fmte *FileManagedTaskExecutor) shouldBeExecuted(
 	return true, nil
 }
 
-func (fmte *FileManagedTaskExecutor) checkMissingFileCondition(fileManagedTask *FileManagedTask) (isExists bool, err error) {
-	if len(fileManagedTask.Creates) == 0 {
-		return
-	}
-
-	for _, missingFileCondition := range fileManagedTask.Creates {
-		if missingFileCondition == "" {
-			continue
-		}
-		isExists, err = fmte.FsManager.FileExists(missingFileCondition)
-		if err != nil {
-			err = fmt.Errorf("failed to check if file '%s' exists: %w", missingFileCondition, err)
-			return
-		}
-
-		if isExists {
-			logrus.Debugf("file '%s' exists", missingFileCondition)
-			return
-		}
-		logrus.Debugf("file '%s' doesn't exist", missingFileCondition)
-	}
-
-	return
-}
-
-func (fmte *FileManagedTaskExecutor) copySourceToTarget(ctx context.Context, fileManagedTask *FileManagedTask) error {
+// copySourceToTarget gives true if the target file was replaced with the source
+func (fmte *FileManagedTaskExecutor) copySourceToTarget(ctx context.Context, fileManagedTask *FileManagedTask) (bool, error) {
 	source := fileManagedTask.Source
 	if source.RawLocation == "" {
 		logrus.Debug("source location is empty will ignore it")
-		return nil
+		return false, nil
 	}
 
 	if !source.IsURL {
@@ -391,7 +420,7 @@ func (fmte *FileManagedTaskExecutor) copySourceToTarget(ctx context.Context, fil
 	return fmte.handleRemoteSource(ctx, fileManagedTask)
 }
 
-func (fmte *FileManagedTaskExecutor) handleRemoteSource(ctx context.Context, fileManagedTask *FileManagedTask) error {
+func (fmte *FileManagedTaskExecutor) handleRemoteSource(ctx context.Context, fileManagedTask *FileManagedTask) (bool, error) {
 	tempTargetPath := fileManagedTask.Name + "_temp"
 
 	defer func(f string) {
@@ -407,7 +436,7 @@ func (fmte *FileManagedTaskExecutor) handleRemoteSource(ctx context.Context, fil
 	}(tempTargetPath)
 	err := fmte.FsManager.DownloadFile(ctx, tempTargetPath, fileManagedTask.Source.URL, fileManagedTask.SkipTLSCheck)
 	if err != nil {
-		return err
+		return false, err
 	}
 	logrus.Debugf(
 		"copied remove source '%s' to a temp location '%s'",
@@ -417,15 +446,15 @@ func (fmte *FileManagedTaskExecutor) handleRemoteSource(ctx context.Context, fil
 
 	shouldBeCopied, err := fmte.checkIfLocalFileShouldBeCopied(fileManagedTask, tempTargetPath)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if !shouldBeCopied {
-		return nil
+		return false, nil
 	}
 
 	err = fmte.FsManager.MoveFile(tempTargetPath, fileManagedTask.Name)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	logrus.Debugf(
@@ -434,19 +463,19 @@ func (fmte *FileManagedTaskExecutor) handleRemoteSource(ctx context.Context, fil
 		fileManagedTask.Name,
 	)
 
-	return nil
+	return true, nil
 }
 
-func (fmte *FileManagedTaskExecutor) handleLocalSource(fileManagedTask *FileManagedTask, sourcePath string) error {
+func (fmte *FileManagedTaskExecutor) handleLocalSource(fileManagedTask *FileManagedTask, sourcePath string) (bool, error) {
 	logrus.Debug("source location is a local file path")
 	source := fileManagedTask.Source
 
 	shouldBeCopied, err := fmte.checkIfLocalFileShouldBeCopied(fileManagedTask, sourcePath)
 	if err != nil {
-		return err
+		return false, err
 	}
 	if !shouldBeCopied {
-		return nil
+		return false, nil
 	}
 
 	mode := os.FileMode(DefaultFileMode)
@@ -454,7 +483,12 @@ func (fmte *FileManagedTaskExecutor) handleLocalSource(fileManagedTask *FileMana
 		mode = fileManagedTask.Mode
 	}
 
-	return fmte.FsManager.CopyLocalFile(source.LocalPath, fileManagedTask.Name, mode)
+	err = fmte.FsManager.CopyLocalFile(source.LocalPath, fileManagedTask.Name, mode)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 func (fmte *FileManagedTaskExecutor) checkIfLocalFileShouldBeCopied(fileManagedTask *FileManagedTask, sourcePath string) (bool, error) {
@@ -527,10 +561,11 @@ func (fmte *FileManagedTaskExecutor) checkIfLocalFileShouldBeCopied(fileManagedT
 	return false, nil
 }
 
-func (fmte *FileManagedTaskExecutor) copyContentToTarget(fileManagedTask *FileManagedTask) error {
+// copyContentToTarget gives true if the contents were written to the target file
+func (fmte *FileManagedTaskExecutor) copyContentToTarget(fileManagedTask *FileManagedTask) (bool, error) {
 	if !fileManagedTask.Contents.Valid {
 		logrus.Debug("contents field is empty, will not manage content")
-		return nil
+		return false, nil
 	}
 
 	mode := os.FileMode(DefaultFileMode)
@@ -548,11 +583,13 @@ func (fmte *FileManagedTaskExecutor) copyContentToTarget(fileManagedTask *FileMa
 		err = fmte.FsManager.WriteFile(fileManagedTask.Name, fileManagedTask.Contents.String, mode)
 	}
 
-	if err == nil {
-		logrus.Debugf("written contents to '%s'", fileManagedTask.Name)
+	if err != nil {
+		return false, err
 	}
 
-	return err
+	logrus.Debugf("written contents to '%s'", fileManagedTask.Name)
+
+	return true, nil
 }
 
 func (fmte *FileManagedTaskExecutor) shouldSkipForContentExpectation(fileManagedTask *FileManagedTask) (bool, error) {
@@ -587,6 +624,11 @@ func (fmte *FileManagedTaskExecutor) shouldSkipForContentExpectation(fileManaged
 		return true, nil
 	}
 
+	if fileManagedTask.IsOutputHidden() {
+		logrus.Infof(`file '%s' differs from the expected content field, will copy diff to file`, fileManagedTask.Name)
+		return false, nil
+	}
+
 	logrus.WithFields(
 		logrus.Fields{
 			"multiline": contentDiff,
@@ -612,19 +654,22 @@ func (fmte *FileManagedTaskExecutor) createDirPathIfNeeded(fileManagedTask *File
 	return fmte.FsManager.CreateDirPathIfNeeded(fileManagedTask.Name, mode)
 }
 
-func (fmte *FileManagedTaskExecutor) applyFileAttributesToTarget(fileManagedTask *FileManagedTask) error {
+// applyFileAttributesToTarget gives true if the file mode was changed, the owner is applied on every run,
+// so it's not reported as a change
+func (fmte *FileManagedTaskExecutor) applyFileAttributesToTarget(fileManagedTask *FileManagedTask) (isModeChanged bool, err error) {
 	logrus.Debugf("will change file attributes '%s'", fileManagedTask.Name)
 
 	info, err := fmte.FsManager.Stat(fileManagedTask.Name)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	if fileManagedTask.Mode > 0 && fileManagedTask.Mode != info.Mode() {
 		err = fmte.FsManager.Chmod(fileManagedTask.Name, fileManagedTask.Mode)
 		if err != nil {
-			return err
+			return false, err
 		}
+		isModeChanged = true
 		logrus.Debugf("changed mode of '%s' to '%v'", fileManagedTask.Name, fileManagedTask.Mode)
 	}
 
@@ -632,9 +677,9 @@ func (fmte *FileManagedTaskExecutor) applyFileAttributesToTarget(fileManagedTask
 		logrus.Debugf("will change user '%s' or group '%s' of file '%s'", fileManagedTask.User, fileManagedTask.Group, fileManagedTask.Name)
 		err = fmte.FsManager.Chown(fileManagedTask.Name, fileManagedTask.User, fileManagedTask.Group)
 		if err != nil {
-			return err
+			return isModeChanged, err
 		}
 	}
 
-	return nil
+	return isModeChanged, nil
 }
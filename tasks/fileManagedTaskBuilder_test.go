@@ -32,6 +32,7 @@ func TestFileManagedTaskBuilder(t *testing.T) {
 					ReplaceField:    false,
 					SkipVerifyField: true,
 					CreatesField:    "C:\\Program Files\notepad++\notepad++.exe",
+					CheckCmdField:   "C:\\temp\\verify.bat",
 				},
 			},
 			expectedTask: &FileManagedTask{
@@ -51,7 +52,10 @@ func TestFileManagedTaskBuilder(t *testing.T) {
 				MakeDirs:   true,
 				Replace:    false,
 				SkipVerify: true,
-				Creates:    []string{"C:\\Program Files\notepad++\notepad++.exe"},
+				CheckCmd:   []string{"C:\\temp\\verify.bat"},
+				Conditionals: Conditionals{
+					Creates: []string{"C:\\Program Files\notepad++\notepad++.exe"},
+				},
 			},
 		},
 		{
@@ -80,7 +84,9 @@ Funny file`,
 goes here
 Funny file`,
 				},
-				User:     "root",
+				CmdContext: CmdContext{
+					User: "root",
+				},
 				Group:    "www-data",
 				Mode:     0755,
 				Encoding: "UTF-8",
@@ -108,28 +114,32 @@ Funny file`,
 						"OnlyIf two",
 						"OnlyIf three",
 					},
+					Unless: "Unless one",
 				},
 			},
 			expectedTask: &FileManagedTask{
 				TypeName: "manyCreatesType",
 				Path:     "manyCreatesPath",
 				Name:     "many creates command",
-				Creates: []string{
-					"create one",
-					"create two",
-					"create three",
-				},
-				Require: []string{
+				Require: ScriptRequisites([]string{
 					"req one",
 					"req two",
 					"req three",
-				},
-				OnlyIf: []string{
-					"OnlyIf one",
-					"OnlyIf two",
-					"OnlyIf three",
-				},
+				}),
 				Replace: true,
+				Conditionals: Conditionals{
+					Creates: []string{
+						"create one",
+						"create two",
+						"create three",
+					},
+					OnlyIf: []string{
+						"OnlyIf one",
+						"OnlyIf two",
+						"OnlyIf three",
+					},
+					Unless: []string{"Unless one"},
+				},
 			},
 		},
 		{
@@ -219,7 +229,7 @@ Funny file`,
 					ModeField: "dfasdf",
 				},
 			},
-			expectedError: fmt.Sprintf("invalid file mode value 'dfasdf' at path 'invalid_filemode_path.%s'", ModeField),
+			expectedError: fmt.Sprintf("invalid value '\"dfasdf\"' at path 'invalid_filemode_path.%s', expected an octal file mode e.g. 0755", ModeField),
 		},
 		{
 			typeName: "correct_string_mode",
@@ -310,6 +320,8 @@ func assertFileManagedTaskEquals(t *testing.T, expectedTask, actualTask *FileMan
 	assert.Equal(t, expectedTask.MakeDirs, actualTask.MakeDirs)
 	assert.Equal(t, expectedTask.Require, actualTask.Require)
 	assert.Equal(t, expectedTask.OnlyIf, actualTask.OnlyIf)
+	assert.Equal(t, expectedTask.Unless, actualTask.Unless)
 	assert.Equal(t, expectedTask.Encoding, actualTask.Encoding)
 	assert.Equal(t, expectedTask.Contents, actualTask.Contents)
+	assert.Equal(t, expectedTask.CheckCmd, actualTask.CheckCmd)
 }
@@ -106,9 +106,11 @@ func TestFileManagedTaskExecution(t *testing.T) {
 		{
 			Name: "test creates field",
 			Task: &FileManagedTask{
-				Path:    "somepath",
-				Name:    "some test command",
-				Creates: []string{"some_file.123", "sourceFileAtLocal.txt"},
+				Path: "somepath",
+				Name: "some test command",
+				Conditionals: Conditionals{
+					Creates: []string{"some_file.123", "sourceFileAtLocal.txt"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -150,7 +152,7 @@ func TestFileManagedTaskExecution(t *testing.T) {
 				},
 				Mode: 0777,
 			},
-			ExpectedResult: ExecutionResult{},
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "targetFileAtLocalCopy.txt",
 				ShouldExist:     true,
@@ -170,7 +172,7 @@ func TestFileManagedTaskExecution(t *testing.T) {
 					RawLocation: httpSrvURL.String(),
 				},
 			},
-			ExpectedResult: ExecutionResult{},
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "targetFileFromHttp.txt",
 				ShouldExist:     true,
@@ -210,7 +212,7 @@ func TestFileManagedTaskExecution(t *testing.T) {
 				},
 				SkipTLSCheck: true,
 			},
-			ExpectedResult: ExecutionResult{IsSkipped: false},
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "targetFileFromHttps.txt",
 				ShouldExist:     true,
@@ -229,7 +231,7 @@ func TestFileManagedTaskExecution(t *testing.T) {
 					RawLocation: ftpURL.String(),
 				},
 			},
-			ExpectedResult: ExecutionResult{IsSkipped: false},
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "targetFileFromFtp.txt",
 				ShouldExist:     true,
@@ -239,8 +241,10 @@ func TestFileManagedTaskExecution(t *testing.T) {
 		{
 			Name: "executing_onlyif_condition_failure",
 			Task: &FileManagedTask{
-				Name:   "cmd with OnlyIf failure",
-				OnlyIf: []string{"check OnlyIf error"},
+				Name: "cmd with OnlyIf failure",
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check OnlyIf error"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -263,16 +267,18 @@ func TestFileManagedTaskExecution(t *testing.T) {
 		{
 			Name: "executing_onlyif_condition_success",
 			Task: &FileManagedTask{
-				Name:   "onlyIfConditionTrue.txt",
-				OnlyIf: []string{"check OnlyIf success 1", "check OnlyIf success 2"},
+				Name: "onlyIfConditionTrue.txt",
 				Source: utils.Location{
 					IsURL:       false,
 					LocalPath:   "sourceFileAtLocal.txt",
 					RawLocation: "sourceFileAtLocal.txt",
 				},
 				SourceHash: "md5=5e4fe0155703dde467f3ab234e6f966f",
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check OnlyIf success 1", "check OnlyIf success 2"},
+				},
 			},
-			ExpectedResult:  ExecutionResult{},
+			ExpectedResult:  ExecutionResult{Changed: true},
 			ExpectedCmdStrs: []string{"check OnlyIf success 1", "check OnlyIf success 2"},
 			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
 				Cmds: []*exec.Cmd{},
@@ -283,6 +289,32 @@ func TestFileManagedTaskExecution(t *testing.T) {
 				ExpectedContent: "one two three",
 			},
 		},
+		{
+			Name: "executing_check_cmd_failure",
+			Task: &FileManagedTask{
+				Path: "somepath",
+				Name: "checkCmdFailure.txt",
+				Contents: sql.NullString{
+					Valid:  true,
+					String: "one two three",
+				},
+				CheckCmd: []string{"check file after change"},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: true,
+				Err:     errors.New("check_cmd failed for the task at path 'somepath': some check failure"),
+			},
+			ExpectedCmdStrs: []string{"check file after change"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds:      []*exec.Cmd{},
+				ErrToGive: errors.New("some check failure"),
+			}},
+			FileExpectation: &apptest.FileExpectation{
+				FilePath:        "checkCmdFailure.txt",
+				ShouldExist:     true,
+				ExpectedContent: "one two three",
+			},
+		},
 		{
 			Name: "saving_contents_to_file",
 			Task: &FileManagedTask{
@@ -298,7 +330,7 @@ three`,
 				Mode:    0777,
 			},
 			ContentToWrite: "one two three",
-			ExpectedResult: ExecutionResult{},
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:     "contentsToFile.txt",
 				ShouldExist:  true,
@@ -350,7 +382,7 @@ three`,
 					RawLocation: "sourceFileAtLocal.txt",
 				},
 			},
-			ExpectedResult: ExecutionResult{},
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "sub/dir/sourceFileAtLocal.txt",
 				ShouldExist:     true,
@@ -392,6 +424,7 @@ three`,
 				Mode: 0777,
 			},
 			ContentToWrite: "one two three",
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "existingFileToReplace.txt",
 				ShouldExist:     true,
@@ -412,6 +445,7 @@ three`,
 				},
 			},
 			ContentToWrite: "one",
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "existingFileToReplace2.txt",
 				ShouldExist:     true,
@@ -432,6 +466,7 @@ three`,
 				},
 			},
 			ContentToWrite: " ",
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "skipVerifyFileSuccess.txt",
 				ShouldExist:     true,
@@ -473,6 +508,7 @@ three`,
 				Mode: 0777,
 			},
 			ContentToWrite: " ",
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:        "skipVerifyFileLocalSuccess.txt",
 				ShouldExist:     true,
@@ -493,6 +529,7 @@ three`,
 				},
 				Encoding: "gb18030",
 			},
+			ExpectedResult: ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:         "encodingSuccessFile.txt",
 				ShouldExist:      true,
@@ -515,7 +552,7 @@ three`,
 			},
 			ContentToWrite:         "一些中文内",
 			ContentEncodingToWrite: "gb18030",
-			ExpectedResult:         ExecutionResult{},
+			ExpectedResult:         ExecutionResult{Changed: true},
 			FileExpectation: &apptest.FileExpectation{
 				FilePath:         "encodingWithContentCompare.txt",
 				ShouldExist:      true,
@@ -553,9 +590,10 @@ three`,
 			}
 
 			fileManagedExecutor := &FileManagedTaskExecutor{
-				Runner:      runner,
-				FsManager:   &utils.FsManager{},
-				HashManager: &utils.HashManager{},
+				Runner:           runner,
+				FsManager:        &utils.FsManager{},
+				HashManager:      &utils.HashManager{},
+				ConditionChecker: &ConditionChecker{FsManager: &utils.FsManager{}},
 			}
 
 			lc.Messages = []string{}
@@ -588,6 +626,7 @@ func assertTestCase(t *testing.T, tc *fileManagedTestCase, res *ExecutionResult,
 	assert.EqualValues(t, tc.ExpectedResult.IsSkipped, res.IsSkipped)
 	assert.EqualValues(t, tc.ExpectedResult.StdOut, res.StdOut)
 	assert.EqualValues(t, tc.ExpectedResult.StdErr, res.StdErr)
+	assert.EqualValues(t, tc.ExpectedResult.Changed, res.Changed)
 
 	var cmds []*exec.Cmd
 	if tc.RunnerMock != nil {
@@ -651,7 +690,9 @@ func TestFileManagedUserAndGroup(t *testing.T) {
 					Valid:  true,
 					String: `onetwothree`,
 				},
-				User:  "someuser",
+				CmdContext: CmdContext{
+					User: "someuser",
+				},
 				Group: "somegroup",
 			},
 			expectedChownInput: &apptest.ChownInput{
@@ -669,7 +710,9 @@ func TestFileManagedUserAndGroup(t *testing.T) {
 					Valid:  true,
 					String: `onetwothree`,
 				},
-				User:  "someuser",
+				CmdContext: CmdContext{
+					User: "someuser",
+				},
 				Group: "somegroup",
 			},
 			expectedChownInput: &apptest.ChownInput{
@@ -689,7 +732,9 @@ func TestFileManagedUserAndGroup(t *testing.T) {
 					Valid:  true,
 					String: `onetwothree`,
 				},
-				User:  "someuser",
+				CmdContext: CmdContext{
+					User: "someuser",
+				},
 				Group: "",
 			},
 			fileStatError: "some stat error",
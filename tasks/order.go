@@ -0,0 +1,99 @@
+package tasks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	OrderFirstValue = "first"
+	OrderLastValue  = "last"
+)
+
+type OrderKind int
+
+const (
+	// OrderNone is the order of the scripts without the order field, they go after the numbered ones
+	OrderNone OrderKind = iota
+	OrderFirst
+	OrderNumbered
+	OrderLast
+)
+
+// Order is the explicit position of a script in the execution order e.g. "order: 1", "order: first" or "order: last",
+// the scripts with order first go before the numbered ones, then the scripts without order and the ones with order last
+type Order struct {
+	Kind   OrderKind
+	Number int
+}
+
+// OrderedTask is implemented by the tasks which support the order field
+type OrderedTask interface {
+	GetOrder() Order
+}
+
+func (o Order) IsSet() bool {
+	return o.Kind != OrderNone
+}
+
+// Before is true if the order goes before the other one
+func (o Order) Before(other Order) bool {
+	if o.rank() != other.rank() {
+		return o.rank() < other.rank()
+	}
+
+	return o.Kind == OrderNumbered && o.Number < other.Number
+}
+
+func (o Order) rank() int {
+	switch o.Kind {
+	case OrderFirst:
+		return 0
+	case OrderNumbered:
+		return 1
+	case OrderLast:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func (o Order) String() string {
+	switch o.Kind {
+	case OrderFirst:
+		return OrderFirstValue
+	case OrderNumbered:
+		return strconv.Itoa(o.Number)
+	case OrderLast:
+		return OrderLastValue
+	default:
+		return ""
+	}
+}
+
+func parseOrderField(val interface{}, path string) (Order, error) {
+	rawOrder := strings.ToLower(strings.TrimSpace(fmt.Sprint(val)))
+	switch rawOrder {
+	case OrderFirstValue:
+		return Order{Kind: OrderFirst}, nil
+	case OrderLastValue:
+		return Order{Kind: OrderLast}, nil
+	}
+
+	number, err := strconv.Atoi(rawOrder)
+	if err != nil || number < 0 {
+		return Order{}, fmt.Errorf("invalid order '%v' at path '%s', expected a non negative number, %s or %s", val, path, OrderFirstValue, OrderLastValue)
+	}
+
+	return Order{Kind: OrderNumbered, Number: number}, nil
+}
+
+func isValidOrder(val interface{}) bool {
+	if !isScalarValue(val) {
+		return false
+	}
+	_, err := parseOrderField(val, "")
+
+	return err == nil
+}
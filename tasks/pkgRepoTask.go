@@ -0,0 +1,293 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	exec2 "github.com/cloudradar-monitoring/tacoscript/exec"
+
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+type PkgRepoActionType int
+
+const (
+	ActionRepoManage PkgRepoActionType = iota + 1
+	ActionRepoRemove
+)
+
+type PkgRepoTaskBuilder struct {
+}
+
+var pkgRepoFieldsSchema = withCmdContextFields(withConditionalsFields(FieldsSchema{
+	NameField:      FieldTypeString,
+	HumanNameField: FieldTypeString,
+	RepoField:      FieldTypeString,
+	BaseURLField:   FieldTypeString,
+	FileField:      FieldTypeString,
+	KeyURLField:    FieldTypeString,
+	EnabledField:   FieldTypeBool,
+	GpgCheckField:  FieldTypeBool,
+	Deb822Field:    FieldTypeBool,
+	Refresh:        FieldTypeBool,
+	RequireField:   FieldTypeRequisites,
+	OnChangesField: FieldTypeRequisites,
+	WatchField:     FieldTypeRequisites,
+	OnFailField:    FieldTypeRequisites,
+	OnFailAnyField: FieldTypeRequisites,
+	OnFailAllField: FieldTypeRequisites,
+	RequireInField: FieldTypeRequisites,
+	WatchInField:   FieldTypeRequisites,
+	OrderField:     FieldTypeOrder,
+}))
+
+type pkgRepoContextProc func(t *PkgRepoTask, path string, val interface{}) error
+
+var pkgRepoContextProcMap = map[string]pkgRepoContextProc{
+	NameField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.Name = fmt.Sprint(val)
+		return nil
+	},
+	HumanNameField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.HumanName = fmt.Sprint(val)
+		return nil
+	},
+	RepoField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.Repo = fmt.Sprint(val)
+		return nil
+	},
+	BaseURLField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.BaseURL = fmt.Sprint(val)
+		return nil
+	},
+	FileField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.File = fmt.Sprint(val)
+		return nil
+	},
+	KeyURLField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.Key = utils.ParseLocation(fmt.Sprint(val))
+		return nil
+	},
+	EnabledField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.Enabled = parseBoolField(val)
+		return nil
+	},
+	GpgCheckField: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.GpgCheck = parseBoolField(val)
+		return nil
+	},
+	Deb822Field: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.Deb822 = parseBoolField(val)
+		return nil
+	},
+	Refresh: func(t *PkgRepoTask, path string, val interface{}) error {
+		t.ShouldRefresh = parseBoolField(val)
+		return nil
+	},
+	RequireField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.Require, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnChangesField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.OnChanges, err = parseRequisitesField(val, path)
+		return err
+	},
+	WatchField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.Watch, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.OnFail, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailAnyField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.OnFailAny, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailAllField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.OnFailAll, err = parseRequisitesField(val, path)
+		return err
+	},
+	RequireInField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.RequireIn, err = parseRequisitesField(val, path)
+		return err
+	},
+	WatchInField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.WatchIn, err = parseRequisitesField(val, path)
+		return err
+	},
+	OrderField: func(t *PkgRepoTask, path string, val interface{}) error {
+		var err error
+		t.Order, err = parseOrderField(val, path)
+		return err
+	},
+}
+
+func (prtb PkgRepoTaskBuilder) Build(typeName, path string, ctx []map[string]interface{}) (Task, error) {
+	t := &PkgRepoTask{
+		TypeName:      typeName,
+		Path:          path,
+		Enabled:       true,
+		ShouldRefresh: true,
+	}
+
+	switch typeName {
+	case PkgRepoManaged:
+		t.ActionType = ActionRepoManage
+	case PkgRepoAbsent:
+		t.ActionType = ActionRepoRemove
+	}
+
+	// the fields are parsed even if the schema check fails, so the requisites of the task can be checked too
+	schemaErr := pkgRepoFieldsSchema.Validate(path, ctx)
+
+	errs := &utils.Errors{}
+	for _, contextItem := range ctx {
+		for key, val := range contextItem {
+			isConditional, err := t.Conditionals.parseField(key, val, path)
+			if isConditional {
+				errs.Add(err)
+				continue
+			}
+
+			isCmdContext, err := t.CmdContext.parseField(key, val, path)
+			if isCmdContext {
+				errs.Add(err)
+				continue
+			}
+
+			f, ok := pkgRepoContextProcMap[key]
+			if !ok {
+				continue
+			}
+			errs.Add(f(t, path, val))
+		}
+	}
+
+	t.CmdContext.addSecrets()
+
+	if schemaErr != nil {
+		// the field errors repeat the schema ones
+		return t, schemaErr
+	}
+
+	return t, errs.ToError()
+}
+
+// PkgRepoTask describes a package repository (apt source, yum/dnf repo, brew tap etc.) which should be present or absent
+type PkgRepoTask struct {
+	ActionType    PkgRepoActionType
+	TypeName      string
+	Path          string
+	Name          string
+	HumanName     string
+	Repo          string
+	BaseURL       string
+	File          string
+	Key           utils.Location
+	Enabled       bool
+	GpgCheck      bool
+	Deb822        bool
+	ShouldRefresh bool
+	CmdContext
+	Require []Requisite
+	Order   Order
+	Conditionals
+	Requisites
+}
+
+func (prt *PkgRepoTask) GetName() string {
+	return prt.TypeName
+}
+
+func (prt *PkgRepoTask) GetRequirements() []Requisite {
+	return prt.Require
+}
+
+func (prt *PkgRepoTask) GetOrder() Order {
+	return prt.Order
+}
+
+func (prt *PkgRepoTask) GetNames() []string {
+	return []string{prt.Name}
+}
+
+func (prt *PkgRepoTask) Validate() error {
+	errs := &utils.Errors{}
+
+	errs.Add(ValidateRequired(prt.Name, prt.Path+"."+NameField))
+
+	if prt.ActionType == 0 {
+		errs.Add(fmt.Errorf("unknown pkgrepo task type: %s", prt.TypeName))
+	}
+
+	return errs.ToError()
+}
+
+func (prt *PkgRepoTask) GetPath() string {
+	return prt.Path
+}
+
+func (prt *PkgRepoTask) String() string {
+	return fmt.Sprintf("task '%s' at path '%s'", prt.TypeName, prt.GetPath())
+}
+
+type RepoManager interface {
+	ExecuteRepoTask(ctx context.Context, t *PkgRepoTask) (isChanged bool, output string, err error)
+}
+
+type PkgRepoTaskExecutor struct {
+	RepoManager      RepoManager
+	Runner           exec2.Runner
+	ConditionChecker *ConditionChecker
+}
+
+func (prte *PkgRepoTaskExecutor) Execute(ctx context.Context, task Task) ExecutionResult {
+	logrus.Debugf("will trigger '%s' task", task.GetPath())
+	execRes := ExecutionResult{}
+
+	pkgRepoTask, ok := task.(*PkgRepoTask)
+	if !ok {
+		execRes.Err = fmt.Errorf("cannot convert task '%v' to PkgRepoTask", task)
+		return execRes
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	execCtx := pkgRepoTask.newExecContext(ctx, pkgRepoTask.Path, &stdoutBuf, &stderrBuf)
+	logrus.Debugf("will check if the task '%s' should be executed", task.GetPath())
+	shouldBeExecuted, err := checkConditionals(execCtx, prte.Runner, prte.ConditionChecker, pkgRepoTask, pkgRepoTask.Path)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	if !shouldBeExecuted {
+		logrus.Debugf("the task '%s' will be be skipped", task.GetPath())
+		execRes.IsSkipped = true
+		return execRes
+	}
+
+	start := time.Now()
+
+	isChanged, output, err := prte.RepoManager.ExecuteRepoTask(ctx, pkgRepoTask)
+	execRes.Err = err
+	execRes.StdOut = output
+	execRes.Changed = isChanged && err == nil
+	execRes.Duration = time.Since(start)
+	pkgRepoTask.hideOutput(&execRes)
+
+	logrus.Debugf("the task '%s' is finished for %v", task.GetPath(), execRes.Duration)
+	return execRes
+}
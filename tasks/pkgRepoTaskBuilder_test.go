@@ -0,0 +1,138 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPkgRepoTaskBuilder(t *testing.T) {
+	testCases := []struct {
+		typeName      string
+		path          string
+		ctx           []map[string]interface{}
+		expectedTask  *PkgRepoTask
+		expectedError string
+	}{
+		{
+			typeName: PkgRepoManaged,
+			path:     "internal-apt",
+			ctx: []map[string]interface{}{
+				{
+					NameField:     "internal",
+					RepoField:     "deb https://repo.example.com/apt focal main",
+					KeyURLField:   "https://repo.example.com/key.asc",
+					Deb822Field:   true,
+					GpgCheckField: 1,
+					ShellField:    "bash",
+					RequireField:  "req one",
+					OnlyIf:        "OnlyIf one",
+					Unless:        []interface{}{"Unless one"},
+				},
+			},
+			expectedTask: &PkgRepoTask{
+				ActionType:    ActionRepoManage,
+				TypeName:      PkgRepoManaged,
+				Path:          "internal-apt",
+				Name:          "internal",
+				Repo:          "deb https://repo.example.com/apt focal main",
+				Key:           utils.ParseLocation("https://repo.example.com/key.asc"),
+				Enabled:       true,
+				GpgCheck:      true,
+				Deb822:        true,
+				ShouldRefresh: true,
+				CmdContext: CmdContext{
+					Shell: "bash",
+				},
+				Require: ScriptRequisites([]string{"req one"}),
+				Conditionals: Conditionals{
+					OnlyIf: []string{"OnlyIf one"},
+					Unless: []string{"Unless one"},
+				},
+			},
+		},
+		{
+			typeName: PkgRepoManaged,
+			path:     "internal-yum",
+			ctx: []map[string]interface{}{
+				{
+					NameField:      "internal",
+					HumanNameField: "Internal packages",
+					BaseURLField:   "https://repo.example.com/yum/$releasever",
+					FileField:      "/etc/yum.repos.d/custom.repo",
+					EnabledField:   "false",
+					Refresh:        false,
+				},
+			},
+			expectedTask: &PkgRepoTask{
+				ActionType:    ActionRepoManage,
+				TypeName:      PkgRepoManaged,
+				Path:          "internal-yum",
+				Name:          "internal",
+				HumanName:     "Internal packages",
+				BaseURL:       "https://repo.example.com/yum/$releasever",
+				File:          "/etc/yum.repos.d/custom.repo",
+				Enabled:       false,
+				ShouldRefresh: false,
+			},
+		},
+		{
+			typeName: PkgRepoAbsent,
+			path:     "old-repo",
+			ctx: []map[string]interface{}{
+				{
+					NameField: "old",
+				},
+			},
+			expectedTask: &PkgRepoTask{
+				ActionType:    ActionRepoRemove,
+				TypeName:      PkgRepoAbsent,
+				Path:          "old-repo",
+				Name:          "old",
+				Enabled:       true,
+				ShouldRefresh: true,
+			},
+		},
+		{
+			typeName: PkgRepoManaged,
+			path:     "invalid-require",
+			ctx: []map[string]interface{}{
+				{
+					NameField:    "invalid",
+					RequireField: map[string]interface{}{"some": "map"},
+				},
+			},
+			expectedError: `invalid value '{"some":"map"}' at path 'invalid-require.require', expected a script id or a list of script ids and task references e.g. [{file: /etc/hosts}]`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.path, func(t *testing.T) {
+			taskBuilder := PkgRepoTaskBuilder{}
+			actualTaskI, err := taskBuilder.Build(
+				tc.typeName,
+				tc.path,
+				tc.ctx,
+			)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			if err != nil {
+				return
+			}
+
+			actualTask, ok := actualTaskI.(*PkgRepoTask)
+			assert.True(t, ok)
+			if !ok {
+				return
+			}
+
+			assert.Equal(t, tc.expectedTask, actualTask)
+		})
+	}
+}
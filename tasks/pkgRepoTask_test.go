@@ -0,0 +1,204 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	appExec "github.com/cloudradar-monitoring/tacoscript/exec"
+	"github.com/stretchr/testify/assert"
+)
+
+type RepoManagerMock struct {
+	givenTask       *PkgRepoTask
+	isChangedToGive bool
+	outputToGive    string
+	errToGive       error
+}
+
+func (rmm *RepoManagerMock) ExecuteRepoTask(ctx context.Context, t *PkgRepoTask) (isChanged bool, output string, err error) {
+	rmm.givenTask = t
+
+	return rmm.isChangedToGive, rmm.outputToGive, rmm.errToGive
+}
+
+func TestPkgRepoTaskValidation(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		ExpectedError string
+		Task          PkgRepoTask
+	}{
+		{
+			Name: "missing_name",
+			Task: PkgRepoTask{
+				Path:       "somepath",
+				ActionType: ActionRepoManage,
+			},
+			ExpectedError: fmt.Sprintf("empty required value at path 'somepath.%s'", NameField),
+		},
+		{
+			Name: "valid_task",
+			Task: PkgRepoTask{
+				Name:       "internal",
+				ActionType: ActionRepoRemove,
+			},
+		},
+		{
+			Name: "invalid_action_name",
+			Task: PkgRepoTask{
+				TypeName: "unknown type name",
+				Name:     "internal",
+			},
+			ExpectedError: "unknown pkgrepo task type: unknown type name",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.Task.Validate()
+			if tc.ExpectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.ExpectedError)
+			}
+		})
+	}
+}
+
+func TestPkgRepoTaskExecution(t *testing.T) {
+	testCases := []struct {
+		Task            *PkgRepoTask
+		ExpectedResult  ExecutionResult
+		RunnerMock      *appExec.SystemRunner
+		RepoManagerMock *RepoManagerMock
+		ExpectedCmdStrs []string
+		Name            string
+	}{
+		{
+			Name: "execute repo task",
+			Task: &PkgRepoTask{
+				ActionType: ActionRepoManage,
+				TypeName:   PkgRepoManaged,
+				Path:       "repo path",
+				Name:       "internal",
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: true,
+				StdOut:  "repo updated",
+			},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			RepoManagerMock: &RepoManagerMock{
+				isChangedToGive: true,
+				outputToGive:    "repo updated",
+			},
+		},
+		{
+			Name: "up to date repo",
+			Task: &PkgRepoTask{
+				ActionType: ActionRepoManage,
+				TypeName:   PkgRepoManaged,
+				Path:       "repo path",
+				Name:       "internal",
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: false,
+			},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			RepoManagerMock: &RepoManagerMock{},
+		},
+		{
+			Name: "repo manager failure",
+			Task: &PkgRepoTask{
+				ActionType: ActionRepoRemove,
+				Name:       "internal",
+			},
+			ExpectedResult: ExecutionResult{
+				Err: errors.New("cannot remove repo"),
+			},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			RepoManagerMock: &RepoManagerMock{
+				errToGive: errors.New("cannot remove repo"),
+			},
+		},
+		{
+			Name: "skip on failed onlyif",
+			Task: &PkgRepoTask{
+				Name: "internal",
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check repo onlyif"},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				IsSkipped: true,
+			},
+			ExpectedCmdStrs: []string{"check repo onlyif"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds:      []*exec.Cmd{},
+				ErrToGive: errors.New("onlyif failed"),
+			}},
+			RepoManagerMock: &RepoManagerMock{},
+		},
+		{
+			Name: "skip on successful unless",
+			Task: &PkgRepoTask{
+				Name: "internal",
+				Conditionals: Conditionals{
+					Unless: []string{"check repo unless"},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				IsSkipped: true,
+			},
+			ExpectedCmdStrs: []string{"check repo unless"},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			RepoManagerMock: &RepoManagerMock{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			executor := &PkgRepoTaskExecutor{
+				Runner:      tc.RunnerMock,
+				RepoManager: tc.RepoManagerMock,
+			}
+
+			res := executor.Execute(context.Background(), tc.Task)
+			assert.EqualValues(tt, tc.ExpectedResult.Err, res.Err)
+			assert.EqualValues(tt, tc.ExpectedResult.IsSkipped, res.IsSkipped)
+			assert.EqualValues(tt, tc.ExpectedResult.StdOut, res.StdOut)
+			assert.EqualValues(tt, tc.ExpectedResult.Changed, res.Changed)
+
+			systemAPIMock := tc.RunnerMock.SystemAPI.(*appExec.SystemAPIMock)
+			AssertCmdsPartiallyMatch(tt, tc.ExpectedCmdStrs, systemAPIMock.Cmds)
+
+			if tc.ExpectedResult.IsSkipped {
+				assert.Nil(tt, tc.RepoManagerMock.givenTask)
+				return
+			}
+
+			assert.Equal(tt, tc.Task, tc.RepoManagerMock.givenTask)
+		})
+	}
+}
+
+func TestInvalidTaskTypeRepoExecution(t *testing.T) {
+	executor := &PkgRepoTaskExecutor{
+		Runner:      &appExec.RunnerMock{},
+		RepoManager: &RepoManagerMock{},
+	}
+
+	res := executor.Execute(context.TODO(), &CmdRunTask{Path: "some path"})
+	assert.Contains(t, res.Err.Error(), "to PkgRepoTask")
+}
@@ -26,6 +26,23 @@ const (
 type PkgTaskBuilder struct {
 }
 
+var pkgFieldsSchema = withCmdContextFields(withConditionalsFields(FieldsSchema{
+	NameField:       FieldTypeString,
+	NamesField:      FieldTypeList,
+	RequireField:    FieldTypeRequisites,
+	OnChangesField:  FieldTypeRequisites,
+	WatchField:      FieldTypeRequisites,
+	OnFailField:     FieldTypeRequisites,
+	OnFailAnyField:  FieldTypeRequisites,
+	OnFailAllField:  FieldTypeRequisites,
+	RequireInField:  FieldTypeRequisites,
+	WatchInField:    FieldTypeRequisites,
+	OrderField:      FieldTypeOrder,
+	Version:         FieldTypeString,
+	Refresh:         FieldTypeBool,
+	RefreshInterval: FieldTypeDuration,
+}))
+
 type pkgContextProc func(t *PkgTask, path string, val interface{}) error
 
 var pkgContextProcMap = map[string]pkgContextProc{
@@ -33,23 +50,49 @@ var pkgContextProcMap = map[string]pkgContextProc{
 		t.Name = fmt.Sprint(val)
 		return nil
 	},
-	ShellField: func(t *PkgTask, path string, val interface{}) error {
-		t.Shell = fmt.Sprint(val)
-		return nil
-	},
 	RequireField: func(t *PkgTask, path string, val interface{}) error {
 		var err error
-		t.Require, err = parseRequireField(val, path)
+		t.Require, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnChangesField: func(t *PkgTask, path string, val interface{}) error {
+		var err error
+		t.OnChanges, err = parseRequisitesField(val, path)
+		return err
+	},
+	WatchField: func(t *PkgTask, path string, val interface{}) error {
+		var err error
+		t.Watch, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailField: func(t *PkgTask, path string, val interface{}) error {
+		var err error
+		t.OnFail, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailAnyField: func(t *PkgTask, path string, val interface{}) error {
+		var err error
+		t.OnFailAny, err = parseRequisitesField(val, path)
+		return err
+	},
+	OnFailAllField: func(t *PkgTask, path string, val interface{}) error {
+		var err error
+		t.OnFailAll, err = parseRequisitesField(val, path)
 		return err
 	},
-	OnlyIf: func(t *PkgTask, path string, val interface{}) error {
+	RequireInField: func(t *PkgTask, path string, val interface{}) error {
 		var err error
-		t.OnlyIf, err = parseOnlyIfField(val, path)
+		t.RequireIn, err = parseRequisitesField(val, path)
 		return err
 	},
-	Unless: func(t *PkgTask, path string, val interface{}) error {
+	WatchInField: func(t *PkgTask, path string, val interface{}) error {
 		var err error
-		t.Unless, err = parseUnlessField(val, path)
+		t.WatchIn, err = parseRequisitesField(val, path)
+		return err
+	},
+	OrderField: func(t *PkgTask, path string, val interface{}) error {
+		var err error
+		t.Order, err = parseOrderField(val, path)
 		return err
 	},
 	Version: func(t *PkgTask, path string, val interface{}) error {
@@ -60,6 +103,11 @@ var pkgContextProcMap = map[string]pkgContextProc{
 		t.ShouldRefresh = parseBoolField(val)
 		return nil
 	},
+	RefreshInterval: func(t *PkgTask, path string, val interface{}) error {
+		var err error
+		t.RefreshInterval, err = parseRefreshIntervalField(val, path+"."+RefreshInterval)
+		return err
+	},
 	NamesField: func(t *PkgTask, path string, val interface{}) error {
 		var names []string
 		var err error
@@ -84,9 +132,24 @@ func (fmtb PkgTaskBuilder) Build(typeName, path string, ctx []map[string]interfa
 		t.ActionType = ActionUpdate
 	}
 
+	// the fields are parsed even if the schema check fails, so the requisites of the task can be checked too
+	schemaErr := pkgFieldsSchema.Validate(path, ctx)
+
 	errs := &utils.Errors{}
 	for _, contextItem := range ctx {
 		for key, val := range contextItem {
+			isConditional, err := t.Conditionals.parseField(key, val, path)
+			if isConditional {
+				errs.Add(err)
+				continue
+			}
+
+			isCmdContext, err := t.CmdContext.parseField(key, val, path)
+			if isCmdContext {
+				errs.Add(err)
+				continue
+			}
+
 			f, ok := pkgContextProcMap[key]
 			if !ok {
 				continue
@@ -95,6 +158,13 @@ func (fmtb PkgTaskBuilder) Build(typeName, path string, ctx []map[string]interfa
 		}
 	}
 
+	t.CmdContext.addSecrets()
+
+	if schemaErr != nil {
+		// the field errors repeat the schema ones
+		return t, schemaErr
+	}
+
 	return t, errs.ToError()
 }
 
@@ -103,22 +173,28 @@ type PkgTask struct {
 	TypeName   string
 	Path       string
 	NamedTask
-	Shell         string
-	Version       string
-	ShouldRefresh bool
-	Require       []string
-	OnlyIf        []string
-	Unless        []string
+	CmdContext
+	Version         string
+	ShouldRefresh   bool
+	RefreshInterval time.Duration
+	Require         []Requisite
+	Order           Order
+	Conditionals
+	Requisites
 }
 
 func (pt *PkgTask) GetName() string {
 	return pt.TypeName
 }
 
-func (pt *PkgTask) GetRequirements() []string {
+func (pt *PkgTask) GetRequirements() []Requisite {
 	return pt.Require
 }
 
+func (pt *PkgTask) GetOrder() Order {
+	return pt.Order
+}
+
 func (pt *PkgTask) Validate() error {
 	errs := &utils.Errors{}
 
@@ -146,12 +222,13 @@ func (pt *PkgTask) String() string {
 }
 
 type PackageManager interface {
-	ExecuteTask(ctx context.Context, t *PkgTask) (output string, err error)
+	ExecuteTask(ctx context.Context, t *PkgTask) (isChanged bool, output string, err error)
 }
 
 type PkgTaskExecutor struct {
-	PackageManager PackageManager
-	Runner         exec2.Runner
+	PackageManager   PackageManager
+	Runner           exec2.Runner
+	ConditionChecker *ConditionChecker
 }
 
 func (pte *PkgTaskExecutor) Execute(ctx context.Context, task Task) ExecutionResult {
@@ -165,13 +242,9 @@ func (pte *PkgTaskExecutor) Execute(ctx context.Context, task Task) ExecutionRes
 	}
 
 	var stdoutBuf, stderrBuf bytes.Buffer
-	execCtx := &exec2.Context{
-		Ctx:          ctx,
-		StdoutWriter: &stdoutBuf,
-		StderrWriter: &stderrBuf,
-	}
+	execCtx := pkgTask.newExecContext(ctx, pkgTask.Path, &stdoutBuf, &stderrBuf)
 	logrus.Debugf("will check if the task '%s' should be executed", task.GetPath())
-	shouldBeExecuted, err := pte.shouldBeExecuted(execCtx, pkgTask)
+	shouldBeExecuted, err := checkConditionals(execCtx, pte.Runner, pte.ConditionChecker, pkgTask, pkgTask.Path)
 	if err != nil {
 		execRes.Err = err
 		return execRes
@@ -185,89 +258,14 @@ func (pte *PkgTaskExecutor) Execute(ctx context.Context, task Task) ExecutionRes
 
 	start := time.Now()
 
-	output, err := pte.PackageManager.ExecuteTask(ctx, pkgTask)
+	isChanged, output, err := pte.PackageManager.ExecuteTask(ctx, pkgTask)
 	execRes.Err = err
 	execRes.StdOut = output
 	execRes.IsSkipped = false
+	execRes.Changed = isChanged && err == nil
 	execRes.Duration = time.Since(start)
+	pkgTask.hideOutput(&execRes)
 
 	logrus.Debugf("the task '%s' is finished for %v", task.GetPath(), execRes.Duration)
 	return execRes
 }
-
-func (pte *PkgTaskExecutor) checkOnlyIfs(ctx *exec2.Context, pkgTask *PkgTask) (isSuccess bool, err error) {
-	if len(pkgTask.OnlyIf) == 0 {
-		return true, nil
-	}
-
-	newCtx := ctx.Copy()
-
-	newCtx.Cmds = pkgTask.OnlyIf
-	err = pte.Runner.Run(&newCtx)
-
-	if err != nil {
-		runErr, isRunErr := err.(exec2.RunError)
-		if isRunErr {
-			logrus.Debugf("will skip %s since onlyif condition has failed: %v", pkgTask, runErr)
-			return false, nil
-		}
-
-		return false, err
-	}
-
-	return true, nil
-}
-
-func (pte *PkgTaskExecutor) shouldBeExecuted(
-	ctx *exec2.Context,
-	pkgTask *PkgTask,
-) (shouldBeExecuted bool, err error) {
-	isSuccess, err := pte.checkOnlyIfs(ctx, pkgTask)
-	if err != nil {
-		return false, err
-	}
-
-	if !isSuccess {
-		return false, nil
-	}
-
-	isExpectationSuccess, err := pte.checkUnless(ctx, pkgTask)
-	if err != nil {
-		return false, err
-	}
-
-	if !isExpectationSuccess {
-		logrus.Debugf("check of unless section was false, will skip %s", pkgTask.Path)
-		return false, nil
-	}
-
-	logrus.Debugf("all execution conditions are met, will continue %s", pkgTask)
-	return true, nil
-}
-
-func (pte *PkgTaskExecutor) checkUnless(ctx *exec2.Context, pkgTask *PkgTask) (isExpectationSuccess bool, err error) {
-	if len(pkgTask.Unless) == 0 {
-		isExpectationSuccess = true
-		err = nil
-		return
-	}
-
-	newCtx := ctx.Copy()
-
-	newCtx.Cmds = pkgTask.Unless
-
-	err = pte.Runner.Run(&newCtx)
-
-	if err != nil {
-		runErr, isRunErr := err.(exec2.RunError)
-		if isRunErr {
-			logrus.Infof("will continue cmd since at least one unless condition has failed: %v", runErr)
-			return true, nil
-		}
-
-		return false, err
-	}
-
-	logrus.Infof("any unless condition didn't fail for task '%s'", pkgTask.Path)
-	return false, nil
-}
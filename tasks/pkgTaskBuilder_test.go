@@ -2,6 +2,9 @@ package tasks
 
 import (
 	"testing"
+	"time"
+
+	"github.com/cloudradar-monitoring/tacoscript/conv"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,10 +22,11 @@ func TestPkgTaskBuilder(t *testing.T) {
 			path:     "vim",
 			ctx: []map[string]interface{}{
 				{
-					NameField:  "vim",
-					ShellField: "cmd.exe",
-					Version:    "1.0.1",
-					Refresh:    1,
+					NameField:       "vim",
+					ShellField:      "cmd.exe",
+					Version:         "1.0.1",
+					Refresh:         1,
+					RefreshInterval: 30,
 					RequireField: []interface{}{
 						"req one",
 						"req two",
@@ -36,28 +40,35 @@ func TestPkgTaskBuilder(t *testing.T) {
 					Unless: []interface{}{
 						"Unless one",
 					},
+					CreatesField: "/usr/bin/vim",
 				},
 			},
 			expectedTask: &PkgTask{
-				ActionType:    ActionInstall,
-				TypeName:      PkgInstalled,
-				Path:          "vim",
-				NamedTask:     NamedTask{Name: "vim"},
-				Shell:         "cmd.exe",
-				Version:       "1.0.1",
-				ShouldRefresh: true,
-				Unless: []string{
-					"Unless one",
+				ActionType: ActionInstall,
+				TypeName:   PkgInstalled,
+				Path:       "vim",
+				NamedTask:  NamedTask{Name: "vim"},
+				CmdContext: CmdContext{
+					Shell: "cmd.exe",
 				},
-				Require: []string{
+				Version:         "1.0.1",
+				ShouldRefresh:   true,
+				RefreshInterval: 30 * time.Minute,
+				Require: ScriptRequisites([]string{
 					"req one",
 					"req two",
 					"req three",
-				},
-				OnlyIf: []string{
-					"OnlyIf one",
-					"OnlyIf two",
-					"OnlyIf three",
+				}),
+				Conditionals: Conditionals{
+					Creates: []string{"/usr/bin/vim"},
+					Unless: []string{
+						"Unless one",
+					},
+					OnlyIf: []string{
+						"OnlyIf one",
+						"OnlyIf two",
+						"OnlyIf three",
+					},
 				},
 			},
 		},
@@ -66,18 +77,20 @@ func TestPkgTaskBuilder(t *testing.T) {
 			path:     "git",
 			ctx: []map[string]interface{}{
 				{
-					NameField: "git",
-					Version:   "2.0.2",
-					Refresh:   "false",
+					NameField:       "git",
+					Version:         "2.0.2",
+					Refresh:         "false",
+					RefreshInterval: "1h30m",
 				},
 			},
 			expectedTask: &PkgTask{
-				ActionType:    ActionUpdate,
-				TypeName:      PkgUpgraded,
-				Path:          "git",
-				NamedTask:     NamedTask{Name: "git"},
-				Version:       "2.0.2",
-				ShouldRefresh: false,
+				ActionType:      ActionUpdate,
+				TypeName:        PkgUpgraded,
+				Path:            "git",
+				NamedTask:       NamedTask{Name: "git"},
+				Version:         "2.0.2",
+				ShouldRefresh:   false,
+				RefreshInterval: 90 * time.Minute,
 			},
 		},
 		{
@@ -89,8 +102,12 @@ func TestPkgTaskBuilder(t *testing.T) {
 						"nano",
 						"git",
 					},
-					Refresh:     "",
-					"someField": "someValue",
+					Refresh:         "",
+					CwdField:        "/tmp",
+					UserField:       "root",
+					EnvField:        []interface{}{map[interface{}]interface{}{"DEBIAN_FRONTEND": "noninteractive"}},
+					CheckShellField: "sh",
+					CheckUserField:  "nobody",
 				},
 			},
 			expectedTask: &PkgTask{
@@ -101,9 +118,28 @@ func TestPkgTaskBuilder(t *testing.T) {
 					"nano",
 					"git",
 				}},
+				CmdContext: CmdContext{
+					WorkingDir: "/tmp",
+					User:       "root",
+					Envs:       conv.KeyValues{{Key: "DEBIAN_FRONTEND", Value: "noninteractive"}},
+					CheckShell: "sh",
+					CheckUser:  "nobody",
+				},
 				ShouldRefresh: false,
 			},
 		},
+		{
+			typeName: PkgInstalled,
+			path:     "invalid-interval",
+			ctx: []map[string]interface{}{
+				{
+					NameField:       "vim",
+					RefreshInterval: "sometimes",
+				},
+			},
+			expectedError: "invalid value '\"sometimes\"' at path 'invalid-interval.refresh_interval', " +
+				"expected a number of minutes or a duration e.g. 1h30m",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -142,10 +178,10 @@ func assertPkgTaskEquals(t *testing.T, expectedTask, actualTask *PkgTask) {
 	assert.Equal(t, expectedTask.Name, actualTask.Name)
 	assert.Equal(t, expectedTask.Names, actualTask.Names)
 	assert.Equal(t, expectedTask.Require, actualTask.Require)
-	assert.Equal(t, expectedTask.OnlyIf, actualTask.OnlyIf)
-	assert.Equal(t, expectedTask.Unless, actualTask.Unless)
+	assert.Equal(t, expectedTask.Conditionals, actualTask.Conditionals)
 	assert.Equal(t, expectedTask.ActionType, actualTask.ActionType)
 	assert.Equal(t, expectedTask.ShouldRefresh, actualTask.ShouldRefresh)
+	assert.Equal(t, expectedTask.RefreshInterval, actualTask.RefreshInterval)
 	assert.Equal(t, expectedTask.Version, actualTask.Version)
-	assert.Equal(t, expectedTask.Shell, actualTask.Shell)
+	assert.Equal(t, expectedTask.CmdContext, actualTask.CmdContext)
 }
@@ -8,22 +8,25 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/cloudradar-monitoring/tacoscript/apptest"
+	"github.com/cloudradar-monitoring/tacoscript/conv"
 	appExec "github.com/cloudradar-monitoring/tacoscript/exec"
 	"github.com/stretchr/testify/assert"
 )
 
 type PackageManagerMock struct {
-	givenCtx     context.Context
-	givenTask    *PkgTask
-	outputToGive string
-	errToGive    error
+	givenCtx        context.Context
+	givenTask       *PkgTask
+	isChangedToGive bool
+	outputToGive    string
+	errToGive       error
 }
 
-func (pmm *PackageManagerMock) ExecuteTask(ctx context.Context, t *PkgTask) (output string, err error) {
+func (pmm *PackageManagerMock) ExecuteTask(ctx context.Context, t *PkgTask) (isChanged bool, output string, err error) {
 	pmm.givenCtx = ctx
 	pmm.givenTask = t
 
-	return pmm.outputToGive, pmm.errToGive
+	return pmm.isChangedToGive, pmm.outputToGive, pmm.errToGive
 }
 
 func TestPkgTaskValidation(t *testing.T) {
@@ -103,10 +106,10 @@ func TestPkgTaskName(t *testing.T) {
 
 func TestPkgTaskRequire(t *testing.T) {
 	task := PkgTask{
-		Require: []string{"require one", "require two"},
+		Require: ScriptRequisites([]string{"require one", "require two"}),
 	}
 
-	assert.Equal(t, []string{"require one", "require two"}, task.GetRequirements())
+	assert.Equal(t, ScriptRequisites([]string{"require one", "require two"}), task.GetRequirements())
 }
 
 func TestPkgTaskString(t *testing.T) {
@@ -125,7 +128,9 @@ func TestPkgTaskExecution(t *testing.T) {
 		RunnerMock         *appExec.SystemRunner
 		PackageManagerMock *PackageManagerMock
 		ExpectedCmdStrs    []string
+		ExpectedCheckUser  string
 		Name               string
+		FileShouldExist    bool
 	}{
 		{
 			Name: "execute one name install",
@@ -136,6 +141,7 @@ func TestPkgTaskExecution(t *testing.T) {
 				NamedTask:  NamedTask{Name: "vim"},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 				StdOut:    "installation success",
@@ -144,16 +150,39 @@ func TestPkgTaskExecution(t *testing.T) {
 				Cmds: []*exec.Cmd{},
 			}},
 			PackageManagerMock: &PackageManagerMock{
-				outputToGive: "installation success",
+				isChangedToGive: true,
+				outputToGive:    "installation success",
+			},
+		},
+		{
+			Name: "already installed package",
+			Task: &PkgTask{
+				ActionType: ActionInstall,
+				TypeName:   PkgInstalled,
+				Path:       "installed path",
+				NamedTask:  NamedTask{Name: "vim"},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed: false,
+				StdOut:  "vim is already the newest version",
+			},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			PackageManagerMock: &PackageManagerMock{
+				outputToGive: "vim is already the newest version",
 			},
 		},
 		{
 			Name: "executing one onlyif condition with success",
 			Task: &PkgTask{
 				NamedTask: NamedTask{Name: "cmd lala"},
-				OnlyIf:    []string{"check before lala"},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check before lala"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 				StdOut:    "installation success",
@@ -163,14 +192,48 @@ func TestPkgTaskExecution(t *testing.T) {
 				Cmds: []*exec.Cmd{},
 			}},
 			PackageManagerMock: &PackageManagerMock{
-				outputToGive: "installation success",
+				isChangedToGive: true,
+				outputToGive:    "installation success",
+			},
+		},
+		{
+			Name: "executing onlyif in the task context with check user",
+			Task: &PkgTask{
+				NamedTask: NamedTask{Name: "vim"},
+				CmdContext: CmdContext{
+					WorkingDir: "/tmp/pkg",
+					User:       "root",
+					Shell:      "bash",
+					Envs:       conv.KeyValues{{Key: "DEBIAN_FRONTEND", Value: "noninteractive"}},
+					CheckUser:  "nobody",
+				},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check as nobody"},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				Changed:   true,
+				IsSkipped: false,
+				Err:       nil,
+				StdOut:    "installation success",
+			},
+			ExpectedCmdStrs:   []string{"bash -c check as nobody"},
+			ExpectedCheckUser: "nobody",
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			PackageManagerMock: &PackageManagerMock{
+				isChangedToGive: true,
+				outputToGive:    "installation success",
 			},
 		},
 		{
 			Name: "executing one onlyif condition with skip execution",
 			Task: &PkgTask{
 				NamedTask: NamedTask{Name: "cmd with OnlyIf skipped"},
-				OnlyIf:    []string{"check OnlyIf error"},
+				Conditionals: Conditionals{
+					OnlyIf: []string{"check OnlyIf error"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -189,9 +252,12 @@ func TestPkgTaskExecution(t *testing.T) {
 			Name: "executing one unless condition with success",
 			Task: &PkgTask{
 				NamedTask: NamedTask{Name: "cmd stop"},
-				Unless:    []string{"run unless stop"},
+				Conditionals: Conditionals{
+					Unless: []string{"run unless stop"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
+				Changed:   true,
 				IsSkipped: false,
 				Err:       nil,
 			},
@@ -207,13 +273,15 @@ func TestPkgTaskExecution(t *testing.T) {
 					return nil
 				},
 			}},
-			PackageManagerMock: &PackageManagerMock{},
+			PackageManagerMock: &PackageManagerMock{isChangedToGive: true},
 		},
 		{
 			Name: "executing one unless condition with failure",
 			Task: &PkgTask{
 				NamedTask: NamedTask{Name: "cmd with unless failure"},
-				Unless:    []string{"check unless failure"},
+				Conditionals: Conditionals{
+					Unless: []string{"check unless failure"},
+				},
 			},
 			ExpectedResult: ExecutionResult{
 				IsSkipped: true,
@@ -224,6 +292,25 @@ func TestPkgTaskExecution(t *testing.T) {
 			}},
 			PackageManagerMock: &PackageManagerMock{},
 		},
+		{
+			Name: "skipping if created file exists",
+			Task: &PkgTask{
+				NamedTask: NamedTask{Name: "cmd with creates"},
+				Conditionals: Conditionals{
+					Creates: []string{"/usr/bin/app"},
+					OnlyIf:  []string{"check onlyif after creates"},
+				},
+			},
+			ExpectedResult: ExecutionResult{
+				IsSkipped: true,
+			},
+			ExpectedCmdStrs: []string{},
+			RunnerMock: &appExec.SystemRunner{SystemAPI: &appExec.SystemAPIMock{
+				Cmds: []*exec.Cmd{},
+			}},
+			PackageManagerMock: &PackageManagerMock{},
+			FileShouldExist:    true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -232,12 +319,18 @@ func TestPkgTaskExecution(t *testing.T) {
 			executor := &PkgTaskExecutor{
 				Runner:         tc.RunnerMock,
 				PackageManager: tc.PackageManagerMock,
+				ConditionChecker: &ConditionChecker{
+					FsManager: &apptest.FsManagerMock{
+						FileExistsExistsToReturn: tc.FileShouldExist,
+					},
+				},
 			}
 
 			res := executor.Execute(context.Background(), tc.Task)
 			assert.EqualValues(tt, tc.ExpectedResult.Err, res.Err)
 			assert.EqualValues(tt, tc.ExpectedResult.IsSkipped, res.IsSkipped)
 			assert.EqualValues(tt, tc.ExpectedResult.StdOut, res.StdOut)
+			assert.EqualValues(tt, tc.ExpectedResult.Changed, res.Changed)
 			assert.EqualValues(tt, tc.ExpectedResult.StdErr, res.StdErr)
 
 			if tc.ExpectedResult.Err != nil {
@@ -253,6 +346,11 @@ func TestPkgTaskExecution(t *testing.T) {
 			}
 
 			assert.Equal(tt, len(tc.ExpectedCmdStrs), len(cmds))
+			for _, cmd := range cmds {
+				assert.Equal(tt, tc.Task.WorkingDir, cmd.Dir)
+				AssertEnvValuesMatch(tt, tc.Task.Envs, cmd.Env)
+			}
+			assert.Equal(tt, tc.ExpectedCheckUser, systemAPIMock.UserNameInput)
 			assertPkgTaskEquals(tt, tc.Task, tc.PackageManagerMock.givenTask)
 		})
 	}
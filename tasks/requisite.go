@@ -0,0 +1,209 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudradar-monitoring/tacoscript/conv"
+)
+
+// Requisite references the tasks which a task depends on, it's either a script id e.g. "- install-nginx"
+// which references all tasks of the script or a typed reference e.g. "- file: /etc/nginx/nginx.conf"
+// which references the tasks of the module with the same script id or name
+type Requisite struct {
+	// Module is the task type or its prefix e.g. file for file.managed, it's empty for script references
+	Module string
+	// Ref is the script id or the task name
+	Ref string
+	// Path references the exact task e.g. nginx.pkg.installed[1], it's set for the requisites
+	// which are injected by require_in and watch_in, so they don't match the sibling tasks of the same type
+	Path string
+}
+
+// Requisites are the requisites of a task besides require, they order the tasks the same way as require does
+type Requisites struct {
+	// OnChanges runs the task only if one of the referenced tasks reported changes
+	OnChanges []Requisite
+	// Watch works as require and runs the task only if one of the referenced tasks reported changes
+	Watch []Requisite
+	// OnFail runs the task only if one of the referenced tasks failed, OnFailAny is the same
+	OnFail    []Requisite
+	OnFailAny []Requisite
+	// OnFailAll runs the task only if all referenced tasks failed
+	OnFailAll []Requisite
+	// RequireIn and WatchIn add the task to the require and watch requisites of the referenced tasks
+	RequireIn []Requisite
+	WatchIn   []Requisite
+	// InjectedRequire and InjectedWatch are the requisites which were added by require_in and watch_in of other tasks
+	InjectedRequire []Requisite
+	InjectedWatch   []Requisite
+}
+
+// HasOnFail is true for the recovery tasks which run only if the referenced tasks failed
+func (r Requisites) HasOnFail() bool {
+	return len(r.OnFail) > 0 || len(r.OnFailAny) > 0 || len(r.OnFailAll) > 0
+}
+
+// RequisitesTask is implemented by the tasks which support the requisites besides require
+type RequisitesTask interface {
+	GetRequisites() Requisites
+}
+
+// GetRequisites is promoted to the tasks which embed Requisites
+func (r Requisites) GetRequisites() Requisites {
+	return r
+}
+
+// RequisitesInjector is implemented by the tasks which can be referenced by require_in and watch_in of other tasks
+type RequisitesInjector interface {
+	InjectRequisites(require, watch []Requisite)
+}
+
+// InjectRequisites replaces the requisites which were added by require_in and watch_in of other tasks,
+// it's promoted to the tasks which embed Requisites
+func (r *Requisites) InjectRequisites(require, watch []Requisite) {
+	r.InjectedRequire = require
+	r.InjectedWatch = watch
+}
+
+var requisitesFields = map[string]bool{
+	RequireField:   true,
+	OnChangesField: true,
+	WatchField:     true,
+	OnFailField:    true,
+	OnFailAnyField: true,
+	OnFailAllField: true,
+	RequireInField: true,
+	WatchInField:   true,
+}
+
+// IsRequisitesField is true for the fields which reference scripts or tasks e.g. require or watch_in
+func IsRequisitesField(field string) bool {
+	return requisitesFields[field]
+}
+
+// ScriptRequisites converts script ids to requisites
+func ScriptRequisites(scriptIDs []string) []Requisite {
+	requisites := make([]Requisite, 0, len(scriptIDs))
+	for _, scriptID := range scriptIDs {
+		requisites = append(requisites, Requisite{Ref: scriptID})
+	}
+
+	return requisites
+}
+
+func (r Requisite) String() string {
+	if r.Module == "" {
+		return r.Ref
+	}
+
+	return fmt.Sprintf("%s: %s", r.Module, r.Ref)
+}
+
+// IsTyped is true if the requisite references tasks of a module rather than the whole script
+func (r Requisite) IsTyped() bool {
+	return r.Module != ""
+}
+
+// Matches is true if the task of the script is referenced by the requisite
+func (r Requisite) Matches(scriptID string, task Task) bool {
+	if r.Path != "" {
+		return r.Path == task.GetPath()
+	}
+
+	if !r.IsTyped() {
+		return r.Ref == scriptID
+	}
+
+	taskType := task.GetName()
+	if taskType != r.Module && strings.SplitN(taskType, ".", 2)[0] != r.Module {
+		return false
+	}
+
+	if r.Ref == scriptID {
+		return true
+	}
+
+	if namedTask, ok := task.(interface{ GetNames() []string }); ok {
+		for _, name := range namedTask.GetNames() {
+			if name == r.Ref {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func parseRequisitesField(val interface{}, path string) (requisites []Requisite, err error) {
+	requisites = make([]Requisite, 0)
+
+	rawItems, ok := val.([]interface{})
+	if !ok {
+		rawItems = []interface{}{val}
+	}
+
+	for _, rawItem := range rawItems {
+		requisite, err := parseRequisite(rawItem, path)
+		if err != nil {
+			return requisites, err
+		}
+		requisites = append(requisites, requisite)
+	}
+
+	return requisites, nil
+}
+
+func parseRequisite(rawItem interface{}, path string) (Requisite, error) {
+	typedItem, ok := rawItem.(map[interface{}]interface{})
+	if !ok {
+		return Requisite{Ref: fmt.Sprint(rawItem)}, nil
+	}
+
+	if len(typedItem) != 1 {
+		return Requisite{}, fmt.Errorf(
+			"invalid requisite '%s' at path '%s', expected a script id or a single task reference e.g. {file: /etc/hosts}",
+			conv.ConvertSourceToJSONStrIfPossible(rawItem),
+			path,
+		)
+	}
+
+	for module, ref := range typedItem {
+		return Requisite{Module: fmt.Sprint(module), Ref: fmt.Sprint(ref)}, nil
+	}
+
+	return Requisite{}, nil
+}
+
+// isValidRequisites accepts a script id or a list of script ids and single key maps of scalar values
+func isValidRequisites(val interface{}) bool {
+	if isScalarValue(val) {
+		return true
+	}
+
+	items, ok := val.([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, item := range items {
+		typedItem, ok := item.(map[interface{}]interface{})
+		if !ok {
+			if !isScalarValue(item) {
+				return false
+			}
+			continue
+		}
+
+		if len(typedItem) != 1 {
+			return false
+		}
+		for module, ref := range typedItem {
+			if !isScalarValue(module) || !isScalarValue(ref) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequisitesField(t *testing.T) {
+	testCases := []struct {
+		name               string
+		val                interface{}
+		expectedRequisites []Requisite
+		expectedErrMsg     string
+	}{
+		{
+			name:               "script id",
+			val:                "install-nginx",
+			expectedRequisites: []Requisite{{Ref: "install-nginx"}},
+		},
+		{
+			name: "script ids and task references",
+			val: []interface{}{
+				"install-nginx",
+				map[interface{}]interface{}{"file": "/etc/nginx/nginx.conf"},
+				map[interface{}]interface{}{"cmd.run": "build-step"},
+			},
+			expectedRequisites: []Requisite{
+				{Ref: "install-nginx"},
+				{Module: "file", Ref: "/etc/nginx/nginx.conf"},
+				{Module: "cmd.run", Ref: "build-step"},
+			},
+		},
+		{
+			name:           "many keys in a task reference",
+			val:            []interface{}{map[interface{}]interface{}{"file": "/etc/hosts", "cmd": "build-step"}},
+			expectedErrMsg: `invalid requisite '{"cmd":"build-step","file":"/etc/hosts"}' at path 'some.path', expected a script id or a single task reference e.g. {file: /etc/hosts}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			actualRequisites, err := parseRequisitesField(tc.val, "some.path")
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRequisites, actualRequisites)
+		})
+	}
+}
+
+func TestRequisiteMatches(t *testing.T) {
+	fileTask := &FileManagedTask{TypeName: FileManaged, Name: "/etc/nginx/nginx.conf"}
+	cmdTask := &CmdRunTask{TypeName: TaskTypeCmdRun, NamedTask: NamedTask{Names: []string{"make", "make install"}}}
+
+	testCases := []struct {
+		name          string
+		requisite     Requisite
+		scriptID      string
+		task          Task
+		expectedMatch bool
+	}{
+		{name: "script id", requisite: Requisite{Ref: "nginx"}, scriptID: "nginx", task: fileTask, expectedMatch: true},
+		{name: "other script id", requisite: Requisite{Ref: "nginx"}, scriptID: "apache", task: fileTask},
+		{name: "module and name", requisite: Requisite{Module: "file", Ref: "/etc/nginx/nginx.conf"}, scriptID: "nginx", task: fileTask, expectedMatch: true},
+		{name: "module and script id", requisite: Requisite{Module: "file", Ref: "nginx"}, scriptID: "nginx", task: fileTask, expectedMatch: true},
+		{name: "task type and name", requisite: Requisite{Module: FileManaged, Ref: "/etc/nginx/nginx.conf"}, scriptID: "nginx", task: fileTask, expectedMatch: true},
+		{name: "other module", requisite: Requisite{Module: "cmd", Ref: "nginx"}, scriptID: "nginx", task: fileTask},
+		{name: "module prefix", requisite: Requisite{Module: "fil", Ref: "nginx"}, scriptID: "nginx", task: fileTask},
+		{name: "one of names", requisite: Requisite{Module: "cmd", Ref: "make install"}, scriptID: "build", task: cmdTask, expectedMatch: true},
+		{name: "other name", requisite: Requisite{Module: "cmd", Ref: "make test"}, scriptID: "build", task: cmdTask},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedMatch, tc.requisite.Matches(tc.scriptID, tc.task))
+		})
+	}
+}
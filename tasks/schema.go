@@ -0,0 +1,212 @@
+package tasks
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudradar-monitoring/tacoscript/conv"
+	"github.com/cloudradar-monitoring/tacoscript/utils"
+)
+
+type FieldType int
+
+const (
+	// FieldTypeString is any scalar value which is converted to string
+	FieldTypeString FieldType = iota + 1
+	FieldTypeBool
+	// FieldTypeList is a list of scalar values
+	FieldTypeList
+	// FieldTypeStringOrList is a scalar value or a list of scalar values
+	FieldTypeStringOrList
+	// FieldTypeKeyValueList is a list of maps e.g. env: [{PASSWORD: bunny}]
+	FieldTypeKeyValueList
+	// FieldTypeFileMode is an octal file mode e.g. 0755
+	FieldTypeFileMode
+	// FieldTypeDuration is a number of minutes or a duration string e.g. 1h30m
+	FieldTypeDuration
+	// FieldTypeRequisites is a script id or a list of script ids and task references e.g. [{file: /etc/hosts}]
+	FieldTypeRequisites
+	// FieldTypeOrder is a non negative number, first or last
+	FieldTypeOrder
+	// FieldTypeConditional is a command or a condition or a list of them e.g. [{file_exists: /etc/hosts}]
+	FieldTypeConditional
+	// FieldTypeIntOrList is an integer or a list of integers e.g. [0, 1]
+	FieldTypeIntOrList
+)
+
+const maxSuggestionDistance = 2
+
+// maxFileMode is the highest file mode which is accepted as the integer or the string
+const maxFileMode = 0777
+
+var fieldTypeNames = map[FieldType]string{
+	FieldTypeString:       "a scalar value",
+	FieldTypeBool:         "a boolean value e.g. true, false",
+	FieldTypeList:         "a list of scalar values",
+	FieldTypeStringOrList: "a scalar value or a list of scalar values",
+	FieldTypeKeyValueList: "a list of key value maps e.g. [{KEY: value}]",
+	FieldTypeFileMode:     "an octal file mode e.g. 0755",
+	FieldTypeDuration:     "a number of minutes or a duration e.g. 1h30m",
+	FieldTypeRequisites:   "a script id or a list of script ids and task references e.g. [{file: /etc/hosts}]",
+	FieldTypeOrder:        "a non negative number, first or last",
+	FieldTypeConditional:  "a command or a condition or a list of them e.g. [{file_exists: /etc/hosts}]",
+	FieldTypeIntOrList:    "a number or a list of numbers e.g. [0, 1]",
+}
+
+var boolStrValues = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true, "1": true, "0": true, "": true, "null": true,
+}
+
+// FieldsSchema defines the fields which are accepted by a task builder together with their types
+type FieldsSchema map[string]FieldType
+
+// Validate checks the task context against the schema, it reports unknown fields with suggestions of the similar known ones
+// and the values of wrong types with their paths
+func (fs FieldsSchema) Validate(path string, ctx []map[string]interface{}) error {
+	errs := &utils.Errors{}
+	for _, contextItem := range ctx {
+		keys := make([]string, 0, len(contextItem))
+		for key := range contextItem {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fieldPath := path + "." + key
+			fieldType, ok := fs[key]
+			if !ok {
+				errs.Add(fs.newUnknownFieldError(key, fieldPath))
+				continue
+			}
+
+			if !isValidFieldValue(fieldType, contextItem[key]) {
+				errs.Add(fmt.Errorf(
+					"invalid value '%s' at path '%s', expected %s",
+					conv.ConvertSourceToJSONStrIfPossible(contextItem[key]),
+					fieldPath,
+					fieldTypeNames[fieldType],
+				))
+			}
+		}
+	}
+
+	return errs.ToError()
+}
+
+func (fs FieldsSchema) newUnknownFieldError(key, fieldPath string) error {
+	suggestion := fs.suggestField(key)
+	if suggestion == "" {
+		return fmt.Errorf("unknown field '%s' at path '%s'", key, fieldPath)
+	}
+
+	return fmt.Errorf("unknown field '%s' at path '%s', did you mean '%s'?", key, fieldPath, suggestion)
+}
+
+// suggestField gives the most similar known field name or an empty string if there are no similar fields
+func (fs FieldsSchema) suggestField(key string) string {
+	suggestion := ""
+	bestDistance := maxSuggestionDistance + 1
+	for fieldName := range fs {
+		distance := utils.LevenshteinDistance(strings.ToLower(key), fieldName)
+		if distance < bestDistance || (distance == bestDistance && fieldName < suggestion) {
+			suggestion = fieldName
+			bestDistance = distance
+		}
+	}
+
+	return suggestion
+}
+
+func isScalarValue(val interface{}) bool {
+	switch val.(type) {
+	case []interface{}, map[interface{}]interface{}, map[string]interface{}:
+		return false
+	default:
+		return true
+	}
+}
+
+func isValidFieldValue(fieldType FieldType, val interface{}) bool {
+	if val == nil {
+		return fieldType != FieldTypeList && fieldType != FieldTypeKeyValueList
+	}
+
+	switch fieldType {
+	case FieldTypeString:
+		return isScalarValue(val)
+	case FieldTypeBool:
+		switch typedVal := val.(type) {
+		case bool:
+			return true
+		case int:
+			return typedVal == 0 || typedVal == 1
+		case string:
+			return boolStrValues[strings.ToLower(strings.TrimSpace(typedVal))]
+		default:
+			return false
+		}
+	case FieldTypeList:
+		return isScalarList(val)
+	case FieldTypeStringOrList:
+		return isScalarValue(val) || isScalarList(val)
+	case FieldTypeKeyValueList:
+		items, ok := val.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if _, ok := item.(map[interface{}]interface{}); !ok {
+				return false
+			}
+		}
+		return true
+	case FieldTypeFileMode:
+		return isValidFileMode(val)
+	case FieldTypeDuration:
+		_, err := parseRefreshIntervalField(val, "")
+		return err == nil
+	case FieldTypeRequisites:
+		return isValidRequisites(val)
+	case FieldTypeOrder:
+		return isValidOrder(val)
+	case FieldTypeConditional:
+		return isValidConditional(val)
+	case FieldTypeIntOrList:
+		_, err := parseRetcodesField(val, "")
+		return err == nil
+	default:
+		return false
+	}
+}
+
+func isScalarList(val interface{}) bool {
+	items, ok := val.([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, item := range items {
+		if !isScalarValue(item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isValidFileMode accepts yaml octal numbers like 0755 which are parsed as integers and octal strings like "755",
+// modes above 0777 are rejected for both: the integers are most likely decimal numbers like 755 which give unexpected
+// permissions and the setuid, setgid and sticky bits are not applied to the files anyway
+func isValidFileMode(val interface{}) bool {
+	switch typedVal := val.(type) {
+	case int:
+		return typedVal >= 0 && typedVal <= maxFileMode
+	case string:
+		mode, err := strconv.ParseUint(strings.TrimSpace(typedVal), 8, 32)
+		return err == nil && mode <= maxFileMode
+	default:
+		return false
+	}
+}
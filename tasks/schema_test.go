@@ -0,0 +1,180 @@
+package tasks
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldsSchemaValidate(t *testing.T) {
+	testCases := []struct {
+		name           string
+		schema         FieldsSchema
+		ctx            []map[string]interface{}
+		expectedErrMsg string
+	}{
+		{
+			name:   "valid_fields",
+			schema: cmdRunFieldsSchema,
+			ctx: []map[string]interface{}{
+				{NameField: "echo 1"},
+				{OnlyIf: []interface{}{"test -f /tmp/a", "test -f /tmp/b"}},
+				{EnvField: []interface{}{map[interface{}]interface{}{"KEY": "value"}}},
+				{RequireField: []interface{}{"script", map[interface{}]interface{}{"file": "/etc/hosts"}}},
+			},
+		},
+		{
+			name:           "invalid_requisite",
+			schema:         cmdRunFieldsSchema,
+			ctx:            []map[string]interface{}{{RequireField: []interface{}{map[interface{}]interface{}{"file": []interface{}{"/etc/hosts"}}}}},
+			expectedErrMsg: `invalid value '[{"file":["/etc/hosts"]}]' at path 'invalid_requisite.require', expected a script id or a list of script ids and task references e.g. [{file: /etc/hosts}]`,
+		},
+		{
+			name:           "misspelled_field",
+			schema:         cmdRunFieldsSchema,
+			ctx:            []map[string]interface{}{{NameField: "echo 1"}, {"onlyiff": "test -f /tmp/a"}},
+			expectedErrMsg: "unknown field 'onlyiff' at path 'misspelled_field.onlyiff', did you mean 'onlyif'?",
+		},
+		{
+			name:           "misspelled_source_hash",
+			schema:         fileManagedFieldsSchema,
+			ctx:            []map[string]interface{}{{"source_hsh": "md5=5e4fe0beafaa1e2d4ad1a3b1fa6cd8e7"}},
+			expectedErrMsg: "unknown field 'source_hsh' at path 'misspelled_source_hash.source_hsh', did you mean 'source_hash'?",
+		},
+		{
+			name:           "unknown_field",
+			schema:         pkgFieldsSchema,
+			ctx:            []map[string]interface{}{{"something": "else"}},
+			expectedErrMsg: "unknown field 'something' at path 'unknown_field.something'",
+		},
+		{
+			name:           "invalid_bool",
+			schema:         fileManagedFieldsSchema,
+			ctx:            []map[string]interface{}{{MakeDirsField: "sometimes"}},
+			expectedErrMsg: `invalid value '"sometimes"' at path 'invalid_bool.makedirs', expected a boolean value e.g. true, false`,
+		},
+		{
+			name:           "decimal_mode",
+			schema:         fileManagedFieldsSchema,
+			ctx:            []map[string]interface{}{{ModeField: 755}},
+			expectedErrMsg: "invalid value '755' at path 'decimal_mode.mode', expected an octal file mode e.g. 0755",
+		},
+		{
+			name:   "conditionals",
+			schema: cmdRunFieldsSchema,
+			ctx: []map[string]interface{}{{
+				OnlyIf: map[interface{}]interface{}{FileExistsCondition: "/etc/hosts"},
+				Unless: []interface{}{"test -f /etc/hosts", map[interface{}]interface{}{UserExistsCondition: "nginx"}},
+			}},
+		},
+		{
+			name:   "invalid_conditional",
+			schema: pkgFieldsSchema,
+			ctx:    []map[string]interface{}{{OnlyIf: []interface{}{[]interface{}{"test -f /etc/hosts"}}}},
+			expectedErrMsg: `invalid value '[["test -f /etc/hosts"]]' at path 'invalid_conditional.onlyif', ` +
+				`expected a command or a condition or a list of them e.g. [{file_exists: /etc/hosts}]`,
+		},
+		{
+			name:   "orders",
+			schema: pkgFieldsSchema,
+			ctx:    []map[string]interface{}{{OrderField: 1}, {OrderField: "first"}, {OrderField: "Last"}},
+		},
+		{
+			name:           "invalid_order",
+			schema:         pkgRepoFieldsSchema,
+			ctx:            []map[string]interface{}{{OrderField: "early"}},
+			expectedErrMsg: `invalid value '"early"' at path 'invalid_order.order', expected a non negative number, first or last`,
+		},
+		{
+			name:           "invalid_retcodes",
+			schema:         cmdRunFieldsSchema,
+			ctx:            []map[string]interface{}{{SuccessRetcodesField: []interface{}{0, "ok"}}},
+			expectedErrMsg: `invalid value '[0,"ok"]' at path 'invalid_retcodes.success_retcodes', expected a number or a list of numbers e.g. [0, 1]`,
+		},
+		{
+			name:   "octal_modes",
+			schema: fileManagedFieldsSchema,
+			ctx:    []map[string]interface{}{{ModeField: 0755}, {ModeField: "0644"}, {ModeField: "755"}},
+		},
+		{
+			name:           "setuid_mode",
+			schema:         fileManagedFieldsSchema,
+			ctx:            []map[string]interface{}{{ModeField: 04755}},
+			expectedErrMsg: "invalid value '2541' at path 'setuid_mode.mode', expected an octal file mode e.g. 0755",
+		},
+		{
+			name:           "setuid_string_mode",
+			schema:         fileManagedFieldsSchema,
+			ctx:            []map[string]interface{}{{ModeField: "4755"}},
+			expectedErrMsg: `invalid value '"4755"' at path 'setuid_string_mode.mode', expected an octal file mode e.g. 0755`,
+		},
+		{
+			name:           "env_is_not_a_list",
+			schema:         cmdRunFieldsSchema,
+			ctx:            []map[string]interface{}{{EnvField: map[interface{}]interface{}{"KEY": "value"}}},
+			expectedErrMsg: `invalid value '{"KEY":"value"}' at path 'env_is_not_a_list.env', expected a list of key value maps e.g. [{KEY: value}]`,
+		},
+		{
+			name:   "many_errors",
+			schema: pkgRepoFieldsSchema,
+			ctx:    []map[string]interface{}{{"nam": "repo", EnabledField: "maybe"}},
+			expectedErrMsg: `invalid value '"maybe"' at path 'many_errors.enabled', expected a boolean value e.g. true, false, ` +
+				"unknown field 'nam' at path 'many_errors.nam', did you mean 'name'?",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.schema.Validate(tc.name, tc.ctx)
+			if tc.expectedErrMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tc.expectedErrMsg)
+		})
+	}
+}
+
+// the conditional fields are parsed by Conditionals rather than by the context processors
+func TestFieldsSchemaMatchesContextProcessors(t *testing.T) {
+	schemaFields := func(schema FieldsSchema) []string {
+		fields := make([]string, 0, len(schema))
+		for field := range schema {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		return fields
+	}
+
+	fileManagedFields := append(schemaFields(conditionalsFieldsSchema), schemaFields(cmdContextFieldsSchema)...)
+	for field := range contextProcMap {
+		fileManagedFields = append(fileManagedFields, field)
+	}
+	sort.Strings(fileManagedFields)
+	assert.Equal(t, fileManagedFields, schemaFields(fileManagedFieldsSchema))
+
+	pkgFields := append(schemaFields(conditionalsFieldsSchema), schemaFields(cmdContextFieldsSchema)...)
+	for field := range pkgContextProcMap {
+		pkgFields = append(pkgFields, field)
+	}
+	sort.Strings(pkgFields)
+	assert.Equal(t, pkgFields, schemaFields(pkgFieldsSchema))
+
+	pkgRepoFields := append(schemaFields(conditionalsFieldsSchema), schemaFields(cmdContextFieldsSchema)...)
+	for field := range pkgRepoContextProcMap {
+		pkgRepoFields = append(pkgRepoFields, field)
+	}
+	sort.Strings(pkgRepoFields)
+	assert.Equal(t, pkgRepoFields, schemaFields(pkgRepoFieldsSchema))
+
+	for field := range conditionalsFieldsSchema {
+		assert.Contains(t, cmdRunFieldsSchema, field)
+	}
+
+	for field := range cmdContextFieldsSchema {
+		assert.Contains(t, cmdRunFieldsSchema, field)
+	}
+}
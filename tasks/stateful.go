@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	stateChangedKey = "changed"
+	stateCommentKey = "comment"
+)
+
+// commandState is reported by a stateful command e.g. changed=no comment="nothing to migrate"
+type commandState struct {
+	Changed bool
+	Comment string
+}
+
+// parseCommandState reads the state of a stateful command from its stdout, it's either a JSON document
+// e.g. {"changed": false, "comment": "nothing to migrate"} or the last line with key=value pairs
+// e.g. changed=no comment="nothing to migrate", the state line is removed from the returned output
+func parseCommandState(stdout string) (state commandState, output string, err error) {
+	stateDoc := map[string]interface{}{}
+	if json.Unmarshal([]byte(strings.TrimSpace(stdout)), &stateDoc) == nil {
+		if _, ok := stateDoc[stateChangedKey]; ok {
+			state, err = stateFromValues(stateDoc)
+			return state, stdout, err
+		}
+	}
+
+	trimmedStdout := strings.TrimRight(stdout, "\r\n")
+	lastLineStart := strings.LastIndex(trimmedStdout, "\n") + 1
+	stateLine := strings.TrimSpace(trimmedStdout[lastLineStart:])
+
+	fields, err := splitStateLine(stateLine)
+	if err != nil {
+		return state, stdout, err
+	}
+
+	stateValues := map[string]interface{}{}
+	for _, field := range fields {
+		keyValue := strings.SplitN(field, "=", 2)
+		if len(keyValue) == 2 {
+			stateValues[keyValue[0]] = keyValue[1]
+		}
+	}
+
+	if _, ok := stateValues[stateChangedKey]; !ok {
+		return state, stdout, fmt.Errorf(
+			`no state in the output, expected a last line like %s=yes %s="some text" or a JSON document`,
+			stateChangedKey,
+			stateCommentKey,
+		)
+	}
+
+	state, err = stateFromValues(stateValues)
+	if err != nil {
+		return state, stdout, err
+	}
+
+	return state, trimmedStdout[:lastLineStart], nil
+}
+
+func stateFromValues(stateValues map[string]interface{}) (state commandState, err error) {
+	switch changed := stateValues[stateChangedKey].(type) {
+	case bool:
+		state.Changed = changed
+	case string:
+		switch strings.ToLower(changed) {
+		case "yes", "true":
+			state.Changed = true
+		case "no", "false":
+			state.Changed = false
+		default:
+			return state, fmt.Errorf("invalid %s value '%s', expected yes or no", stateChangedKey, changed)
+		}
+	default:
+		return state, fmt.Errorf("invalid %s value '%v', expected yes or no", stateChangedKey, changed)
+	}
+
+	if comment, ok := stateValues[stateCommentKey]; ok && comment != nil {
+		state.Comment = fmt.Sprint(comment)
+	}
+
+	return state, nil
+}
+
+// splitStateLine splits the state line by spaces, the values with spaces can be quoted e.g. comment="nothing to migrate"
+func splitStateLine(stateLine string) ([]string, error) {
+	fields := make([]string, 0)
+	var field strings.Builder
+	var quote rune
+
+	for _, r := range stateLine {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case unicode.IsSpace(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unclosed quote in the state line")
+	}
+
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,79 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCommandState(t *testing.T) {
+	testCases := []struct {
+		name           string
+		stdout         string
+		expectedState  commandState
+		expectedOutput string
+		expectedErrMsg string
+	}{
+		{
+			name:           "state_line",
+			stdout:         "migrated 0 rows\nchanged=no comment=\"nothing to migrate\"\n",
+			expectedState:  commandState{Changed: false, Comment: "nothing to migrate"},
+			expectedOutput: "migrated 0 rows\n",
+		},
+		{
+			name:           "state_line_with_other_keys",
+			stdout:         "changed=yes version=2 comment='migrated to version 2'",
+			expectedState:  commandState{Changed: true, Comment: "migrated to version 2"},
+			expectedOutput: "",
+		},
+		{
+			name:           "windows_line_endings",
+			stdout:         "done\r\nchanged=true\r\n",
+			expectedState:  commandState{Changed: true},
+			expectedOutput: "done\r\n",
+		},
+		{
+			name:           "json_document",
+			stdout:         `{"changed": false, "comment": "already up to date"}`,
+			expectedState:  commandState{Changed: false, Comment: "already up to date"},
+			expectedOutput: `{"changed": false, "comment": "already up to date"}`,
+		},
+		{
+			name:           "json_document_with_string_value",
+			stdout:         "{\n  \"changed\": \"yes\"\n}\n",
+			expectedState:  commandState{Changed: true},
+			expectedOutput: "{\n  \"changed\": \"yes\"\n}\n",
+		},
+		{
+			name:           "no_state",
+			stdout:         "migrated 0 rows\n",
+			expectedErrMsg: `no state in the output, expected a last line like changed=yes comment="some text" or a JSON document`,
+		},
+		{
+			name:           "invalid_changed_value",
+			stdout:         "changed=maybe",
+			expectedErrMsg: "invalid changed value 'maybe', expected yes or no",
+		},
+		{
+			name:           "unclosed_quote",
+			stdout:         `changed=no comment="nothing to migrate`,
+			expectedErrMsg: "unclosed quote in the state line",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			state, output, err := parseCommandState(tc.stdout)
+			if tc.expectedErrMsg != "" {
+				assert.EqualError(t, err, tc.expectedErrMsg)
+				assert.Equal(t, tc.stdout, output)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedState, state)
+			assert.Equal(t, tc.expectedOutput, output)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package tasks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SuccessRules accept the commands which exit with a non zero code but still succeed,
+// e.g. robocopy exits with 1 if it has copied files and grep exits with 1 if nothing is found
+type SuccessRules struct {
+	Retcodes []int
+	// Stdout and Stderr are the regular expressions which accept the command if they match its output
+	Stdout []string
+	Stderr []string
+}
+
+// accept gives the rule which accepts the failed command e.g. "success_retcodes: 1",
+// it's empty if no rule accepts it, the rules are checked in the order retcodes, stdout and stderr
+func (sr SuccessRules) accept(exitCode int, stdout, stderr string) string {
+	for _, retcode := range sr.Retcodes {
+		if retcode == exitCode {
+			return fmt.Sprintf("%s: %d", SuccessRetcodesField, retcode)
+		}
+	}
+
+	for _, pattern := range sr.Stdout {
+		if isMatched, _ := regexp.MatchString(pattern, stdout); isMatched {
+			return fmt.Sprintf("%s: %s", SuccessStdoutField, pattern)
+		}
+	}
+
+	for _, pattern := range sr.Stderr {
+		if isMatched, _ := regexp.MatchString(pattern, stderr); isMatched {
+			return fmt.Sprintf("%s: %s", SuccessStderrField, pattern)
+		}
+	}
+
+	return ""
+}
+
+func parseRetcodesField(val interface{}, path string) ([]int, error) {
+	rawRetcodes, ok := val.([]interface{})
+	if !ok {
+		rawRetcodes = []interface{}{val}
+	}
+
+	retcodes := make([]int, 0, len(rawRetcodes))
+	for _, rawRetcode := range rawRetcodes {
+		retcode, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(rawRetcode)))
+		if err != nil || !isScalarValue(rawRetcode) {
+			return nil, fmt.Errorf("invalid exit code '%v' at path '%s.%s', expected a number", rawRetcode, path, SuccessRetcodesField)
+		}
+		retcodes = append(retcodes, retcode)
+	}
+
+	return retcodes, nil
+}
+
+// parseSuccessPatternsField validates the regular expressions of success_stdout and success_stderr
+func parseSuccessPatternsField(val interface{}, path string) ([]string, error) {
+	patterns, err := parseCreatesField(val, path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range patterns {
+		if _, compileErr := regexp.Compile(pattern); compileErr != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' at path '%s': %v", pattern, path, compileErr)
+		}
+	}
+
+	return patterns, nil
+}
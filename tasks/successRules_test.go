@@ -0,0 +1,70 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuccessRulesAccept(t *testing.T) {
+	rules := SuccessRules{
+		Retcodes: []int{1, 3},
+		Stdout:   []string{`^\d+ files? copied$`},
+		Stderr:   []string{"(?i)already exists"},
+	}
+
+	testCases := []struct {
+		name         string
+		exitCode     int
+		stdout       string
+		stderr       string
+		expectedRule string
+	}{
+		{
+			name:         "accepted_retcode",
+			exitCode:     3,
+			expectedRule: "success_retcodes: 3",
+		},
+		{
+			name:         "accepted_stdout",
+			exitCode:     8,
+			stdout:       "2 files copied",
+			expectedRule: `success_stdout: ^\d+ files? copied$`,
+		},
+		{
+			name:         "accepted_stderr",
+			exitCode:     8,
+			stderr:       "Database ALREADY EXISTS",
+			expectedRule: "success_stderr: (?i)already exists",
+		},
+		{
+			name:     "not_accepted",
+			exitCode: 2,
+			stdout:   "copy failed",
+			stderr:   "access denied",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedRule, rules.accept(tc.exitCode, tc.stdout, tc.stderr))
+		})
+	}
+}
+
+func TestParseSuccessFields(t *testing.T) {
+	retcodes, err := parseRetcodesField([]interface{}{0, "1", 16}, "task")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 16}, retcodes)
+
+	retcodes, err = parseRetcodesField(1, "task")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, retcodes)
+
+	_, err = parseRetcodesField([]interface{}{0, "one"}, "task")
+	assert.EqualError(t, err, "invalid exit code 'one' at path 'task.success_retcodes', expected a number")
+
+	_, err = parseSuccessPatternsField("[a-", "task.success_stdout")
+	assert.EqualError(t, err, "invalid pattern '[a-' at path 'task.success_stdout': error parsing regexp: missing closing ]: `[a-`")
+}
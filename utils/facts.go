@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"io/ioutil"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-sysinfo"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	Hostname       = "taco_hostname"       // web01
+	FQDN           = "taco_fqdn"           // web01.example.com
+	IPAddresses    = "taco_ip_addresses"   // all IP addresses of the host
+	IPInterfaces   = "taco_ip_interfaces"  // map of network interface to its IP addresses, e.g. eth0: [10.0.0.5]
+	MACAddresses   = "taco_mac_addresses"  // map of network interface to its MAC address, e.g. eth0: 02:42:ac:11:00:02
+	CPUCount       = "taco_cpu_count"      // 4
+	CPUModel       = "taco_cpu_model"      // Intel(R) Core(TM) i7-8565U CPU @ 1.80GHz
+	MemTotal       = "taco_mem_total"      // total memory in megabytes, e.g. 7976
+	KernelVersion  = "taco_kernel_version" // 5.4.0-54-generic, 19.6.0, 10.0.19041.630
+	BootTime       = "taco_boot_time"      // RFC3339 time, e.g. 2020-11-20T08:15:00Z
+	Timezone       = "taco_timezone"       // UTC, CET
+	Virtual        = "taco_virtual"        // physical, kvm, vmware, virtualbox, xen, hyperv, docker, lxc
+	Containerized  = "taco_containerized"  // true if tacoscript runs in a container
+	InitSystem     = "taco_init_system"    // systemd, upstart, openrc, sysvinit, launchd, windows
+	physicalHost   = "physical"
+	unknownFactVal = "unknown"
+)
+
+var systemFactNames = []string{
+	Hostname,
+	FQDN,
+	IPAddresses,
+	IPInterfaces,
+	MACAddresses,
+	CPUCount,
+	CPUModel,
+	MemTotal,
+	KernelVersion,
+	BootTime,
+	Timezone,
+	Virtual,
+	Containerized,
+	InitSystem,
+}
+
+// LazySystemFacts gives a function per system fact which returns its value, since collecting of the facts
+// takes some time e.g. for the FQDN lookup, all facts are collected once on the first call of any function
+func LazySystemFacts() map[string]func() (interface{}, error) {
+	var (
+		collectOnce sync.Once
+		facts       map[string]interface{}
+		factsErr    error
+	)
+
+	lazyFacts := make(map[string]func() (interface{}, error), len(systemFactNames))
+	for _, factName := range systemFactNames {
+		name := factName
+		lazyFacts[name] = func() (interface{}, error) {
+			collectOnce.Do(func() {
+				facts, factsErr = CollectSystemFacts()
+			})
+			if factsErr != nil {
+				return nil, factsErr
+			}
+
+			return facts[name], nil
+		}
+	}
+
+	return lazyFacts
+}
+
+// CollectSystemFacts gives hardware, network and runtime facts about the host system
+func CollectSystemFacts() (map[string]interface{}, error) {
+	h, err := sysinfo.Host()
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+	hostInfo := h.Info()
+
+	facts := map[string]interface{}{
+		Hostname:      hostInfo.Hostname,
+		FQDN:          lookupFQDN(hostInfo.Hostname),
+		CPUCount:      runtime.NumCPU(),
+		CPUModel:      detectCPUModel(),
+		KernelVersion: hostInfo.KernelVersion,
+		BootTime:      hostInfo.BootTime.UTC().Format(time.RFC3339),
+		Timezone:      hostInfo.Timezone,
+		InitSystem:    detectInitSystem(),
+	}
+
+	ipAddresses, ipInterfaces, macAddresses := collectInterfaces()
+	facts[IPAddresses] = ipAddresses
+	facts[IPInterfaces] = ipInterfaces
+	facts[MACAddresses] = macAddresses
+
+	mem, err := h.Memory()
+	if err != nil {
+		logrus.Warnf("failed to read memory info: %v", err)
+		facts[MemTotal] = 0
+	} else {
+		facts[MemTotal] = mem.Total / 1024 / 1024
+	}
+
+	isContainerized := hostInfo.Containerized != nil && *hostInfo.Containerized
+	facts[Containerized] = isContainerized
+	facts[Virtual] = detectVirtual(isContainerized)
+
+	return facts, nil
+}
+
+func lookupFQDN(hostname string) string {
+	addrs, err := net.LookupHost(hostname)
+	if err != nil {
+		logrus.Debugf("failed to resolve hostname '%s': %v", hostname, err)
+		return hostname
+	}
+
+	for _, addr := range addrs {
+		names, err := net.LookupAddr(addr)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			name = strings.TrimSuffix(name, ".")
+			if strings.Contains(name, ".") {
+				return name
+			}
+		}
+	}
+
+	return hostname
+}
+
+func collectInterfaces() (ipAddresses []string, ipInterfaces map[string][]string, macAddresses map[string]string) {
+	ipAddresses = []string{}
+	ipInterfaces = map[string][]string{}
+	macAddresses = map[string]string{}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		logrus.Warnf("failed to read network interfaces: %v", err)
+		return
+	}
+
+	for _, iface := range interfaces {
+		if len(iface.HardwareAddr) > 0 {
+			macAddresses[iface.Name] = iface.HardwareAddr.String()
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			logrus.Debugf("failed to read addresses of network interface '%s': %v", iface.Name, err)
+			continue
+		}
+
+		ips := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			ip, _, err := net.ParseCIDR(addr.String())
+			if err != nil {
+				continue
+			}
+			ips = append(ips, ip.String())
+		}
+		ipInterfaces[iface.Name] = ips
+		ipAddresses = append(ipAddresses, ips...)
+	}
+
+	return ipAddresses, ipInterfaces, macAddresses
+}
+
+var virtualVendors = map[string]string{
+	"virtualbox":            "virtualbox",
+	"vmware":                "vmware",
+	"kvm":                   "kvm",
+	"qemu":                  "kvm",
+	"xen":                   "xen",
+	"microsoft corporation": "hyperv",
+	"amazon ec2":            "kvm",
+	"google compute engine": "kvm",
+	"parallels":             "parallels",
+	"bochs":                 "bochs",
+	"openstack":             "kvm",
+	"digitalocean":          "kvm",
+	"hetzner vserver":       "kvm",
+	"virtual machine":       "hyperv",
+}
+
+// detectVirtualByHardwareName gives virtualization type by known vendor or product names of virtual hardware
+func detectVirtualByHardwareName(hardwareNames ...string) string {
+	for _, hardwareName := range hardwareNames {
+		hardwareName = strings.ToLower(strings.TrimSpace(hardwareName))
+		if hardwareName == "" {
+			continue
+		}
+		for vendor, virtualType := range virtualVendors {
+			if strings.Contains(hardwareName, vendor) {
+				return virtualType
+			}
+		}
+	}
+
+	return physicalHost
+}
+
+func readTrimmedFile(filePath string) string {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,43 @@
+// +build darwin
+
+package utils
+
+import (
+	"os/exec"
+	"strings"
+)
+
+func sysctlValue(name string) string {
+	output, err := exec.Command("sysctl", "-n", name).Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+func detectCPUModel() string {
+	cpuModel := sysctlValue("machdep.cpu.brand_string")
+	if cpuModel == "" {
+		return unknownFactVal
+	}
+
+	return cpuModel
+}
+
+func detectVirtual(isContainerized bool) string {
+	if sysctlValue("kern.hv_vmm_present") != "1" {
+		return physicalHost
+	}
+
+	virtualType := detectVirtualByHardwareName(sysctlValue("hw.model"))
+	if virtualType == physicalHost {
+		return "virtual"
+	}
+
+	return virtualType
+}
+
+func detectInitSystem() string {
+	return "launchd"
+}
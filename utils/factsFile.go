@@ -0,0 +1,23 @@
+package utils
+
+import "fmt"
+
+// FactsFileProvider gives template variables from a yaml or json file e.g. the saved output of 'tacoscript facts',
+// it allows to render scripts for a host which differs from the current one
+type FactsFileProvider struct {
+	Path string
+}
+
+func (ffp FactsFileProvider) GetTemplateVariables() (map[string]interface{}, error) {
+	facts, err := readYAMLFactFile(ffp.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read facts file '%s': %w", ffp.Path, err)
+	}
+
+	variables, ok := facts.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("facts file '%s' should contain a map of facts", ffp.Path)
+	}
+
+	return variables, nil
+}
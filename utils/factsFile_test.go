@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFactsFileProvider(t *testing.T) {
+	factsDir, err := ioutil.TempDir("", "tacoscript-facts-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(factsDir)
+
+	windowsFactsPath := filepath.Join(factsDir, "windows.yaml")
+	assert.NoError(t, ioutil.WriteFile(windowsFactsPath, []byte("taco_os_kernel: windows\ntaco_local:\n  app:\n    port: 8080\n"), 0600))
+
+	jsonFactsPath := filepath.Join(factsDir, "debian.json")
+	assert.NoError(t, ioutil.WriteFile(jsonFactsPath, []byte(`{"taco_os_family": "debian"}`), 0600))
+
+	listFactsPath := filepath.Join(factsDir, "list.yaml")
+	assert.NoError(t, ioutil.WriteFile(listFactsPath, []byte("- windows"), 0600))
+
+	actualFacts, err := FactsFileProvider{Path: windowsFactsPath}.GetTemplateVariables()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		OSKernel:   "windows",
+		LocalFacts: map[string]interface{}{"app": map[string]interface{}{"port": 8080}},
+	}, actualFacts)
+
+	actualFacts, err = FactsFileProvider{Path: jsonFactsPath}.GetTemplateVariables()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{OSFamily: "debian"}, actualFacts)
+
+	_, err = FactsFileProvider{Path: listFactsPath}.GetTemplateVariables()
+	assert.EqualError(t, err, "facts file '"+listFactsPath+"' should contain a map of facts")
+
+	_, err = FactsFileProvider{Path: filepath.Join(factsDir, "missing.yaml")}.GetTemplateVariables()
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+// +build linux
+
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func detectCPUModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return unknownFactVal
+	}
+	defer CloseResourceSecure("/proc/cpuinfo", f)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lineParts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(lineParts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(lineParts[0])
+		if key == "model name" || key == "Model" || key == "cpu model" {
+			return strings.TrimSpace(lineParts[1])
+		}
+	}
+
+	return unknownFactVal
+}
+
+func detectVirtual(isContainerized bool) string {
+	if isContainerized {
+		return detectContainerType()
+	}
+
+	return detectVirtualByHardwareName(
+		readTrimmedFile("/sys/class/dmi/id/sys_vendor"),
+		readTrimmedFile("/sys/class/dmi/id/product_name"),
+		readTrimmedFile("/sys/hypervisor/type"),
+	)
+}
+
+func detectContainerType() string {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+
+	if containerEnv := readTrimmedFile("/run/systemd/container"); containerEnv != "" {
+		return containerEnv
+	}
+
+	cgroups := readTrimmedFile("/proc/1/cgroup")
+	for _, containerType := range []string{"docker", "kubepods", "lxc", "containerd"} {
+		if strings.Contains(cgroups, containerType) {
+			return containerType
+		}
+	}
+
+	return "container"
+}
+
+func detectInitSystem() string {
+	initExe, err := os.Readlink("/proc/1/exe")
+	if err == nil && strings.Contains(filepath.Base(initExe), "systemd") {
+		return "systemd"
+	}
+
+	initName := readTrimmedFile("/proc/1/comm")
+	if initName == "systemd" {
+		return "systemd"
+	}
+
+	if initName != "init" {
+		if initName == "" {
+			return unknownFactVal
+		}
+		return initName
+	}
+
+	if _, err := os.Stat("/run/openrc"); err == nil {
+		return "openrc"
+	}
+
+	if _, err := os.Stat("/sbin/initctl"); err == nil {
+		return "upstart"
+	}
+
+	return "sysvinit"
+}
@@ -0,0 +1,31 @@
+// +build windows
+
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func detectCPUModel() string {
+	cpuModel := os.Getenv("PROCESSOR_IDENTIFIER")
+	if cpuModel == "" {
+		return unknownFactVal
+	}
+
+	return cpuModel
+}
+
+func detectVirtual(isContainerized bool) string {
+	output, err := exec.Command("wmic", "computersystem", "get", "manufacturer,model").Output()
+	if err != nil {
+		return unknownFactVal
+	}
+
+	return detectVirtualByHardwareName(strings.Split(string(output), "\n")...)
+}
+
+func detectInitSystem() string {
+	return "windows"
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectVirtualByHardwareName(t *testing.T) {
+	testCases := []struct {
+		hardwareNames []string
+		expectedType  string
+	}{
+		{
+			hardwareNames: []string{"innotek GmbH", "VirtualBox"},
+			expectedType:  "virtualbox",
+		},
+		{
+			hardwareNames: []string{"VMware, Inc.", "VMware Virtual Platform"},
+			expectedType:  "vmware",
+		},
+		{
+			hardwareNames: []string{"QEMU", "Standard PC (Q35 + ICH9, 2009)"},
+			expectedType:  "kvm",
+		},
+		{
+			hardwareNames: []string{"", "Virtual Machine"},
+			expectedType:  "hyperv",
+		},
+		{
+			hardwareNames: []string{"Dell Inc.", "XPS 13 9380"},
+			expectedType:  "physical",
+		},
+		{
+			hardwareNames: []string{},
+			expectedType:  "physical",
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expectedType, detectVirtualByHardwareName(testCase.hardwareNames...), testCase.hardwareNames)
+	}
+}
+
+func TestCollectSystemFacts(t *testing.T) {
+	facts, err := CollectSystemFacts()
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+
+	for _, factName := range []string{
+		Hostname,
+		FQDN,
+		IPAddresses,
+		IPInterfaces,
+		MACAddresses,
+		CPUCount,
+		CPUModel,
+		MemTotal,
+		KernelVersion,
+		BootTime,
+		Timezone,
+		Virtual,
+		Containerized,
+		InitSystem,
+	} {
+		assert.Contains(t, facts, factName)
+	}
+
+	assert.Greater(t, facts[CPUCount], 0)
+}
+
+func TestLazySystemFacts(t *testing.T) {
+	facts, err := CollectSystemFacts()
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+
+	lazyFacts := LazySystemFacts()
+	assert.Len(t, lazyFacts, len(facts))
+
+	for factName, factValue := range facts {
+		lazyFact, ok := lazyFacts[factName]
+		if !assert.True(t, ok, factName) {
+			continue
+		}
+
+		lazyFactValue, err := lazyFact()
+		assert.NoError(t, err)
+		// boot time is computed from the uptime, so it can differ by a second between the calls
+		if factName != BootTime {
+			assert.Equal(t, factValue, lazyFactValue, factName)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// LocalFacts is a map of custom facts from the facts directory, keyed by file name without extension,
+	// e.g. the contents of /etc/tacoscript/facts.d/app.yaml are available as .taco_local.app
+	LocalFacts = "taco_local"
+
+	localFactsExecTimeout = time.Second * 30
+)
+
+// CollectLocalFacts reads custom facts from static yaml/json files and executables which print json to stdout,
+// a missing facts directory is not an error
+func CollectLocalFacts(factsDir string) (map[string]interface{}, error) {
+	localFacts := map[string]interface{}{}
+
+	if factsDir == "" {
+		return localFacts, nil
+	}
+
+	files, err := ioutil.ReadDir(factsDir)
+	if os.IsNotExist(err) {
+		logrus.Debugf("facts directory '%s' doesn't exist, will skip local facts", factsDir)
+		return localFacts, nil
+	}
+	if err != nil {
+		return localFacts, err
+	}
+
+	errs := &Errors{}
+	for _, fileInfo := range files {
+		if fileInfo.IsDir() || strings.HasPrefix(fileInfo.Name(), ".") {
+			continue
+		}
+
+		filePath := filepath.Join(factsDir, fileInfo.Name())
+		ext := filepath.Ext(fileInfo.Name())
+		factName := strings.TrimSuffix(fileInfo.Name(), ext)
+
+		var factValue interface{}
+		switch {
+		case ext == ".yaml" || ext == ".yml":
+			factValue, err = readYAMLFactFile(filePath)
+		case ext == ".json":
+			factValue, err = readJSONFactFile(filePath)
+		case isExecutableFile(fileInfo):
+			factValue, err = runFactExecutable(filePath)
+		default:
+			logrus.Debugf("will skip '%s' in facts directory since it's neither a yaml/json file nor an executable", filePath)
+			continue
+		}
+
+		if err != nil {
+			errs.Add(fmt.Errorf("failed to read facts from '%s': %w", filePath, err))
+			continue
+		}
+
+		if _, ok := localFacts[factName]; ok {
+			errs.Add(fmt.Errorf("duplicate local fact '%s' in '%s'", factName, filePath))
+			continue
+		}
+
+		logrus.Debugf("loaded local fact '%s' from '%s'", factName, filePath)
+		localFacts[factName] = factValue
+	}
+
+	return localFacts, errs.ToError()
+}
+
+func readYAMLFactFile(filePath string) (interface{}, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var val interface{}
+	err = yaml.Unmarshal(data, &val)
+	if err != nil {
+		return nil, err
+	}
+
+	return NormalizeYAMLValue(val), nil
+}
+
+func readJSONFactFile(filePath string) (interface{}, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var val interface{}
+	err = json.Unmarshal(data, &val)
+
+	return val, err
+}
+
+func runFactExecutable(filePath string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), localFactsExecTimeout)
+	defer cancel()
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, filePath)
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%w, stderr: %s", err, strings.TrimSpace(stderrBuf.String()))
+	}
+
+	var val interface{}
+	err = json.Unmarshal(stdoutBuf.Bytes(), &val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json output: %w", err)
+	}
+
+	return val, nil
+}
+
+// NormalizeYAMLValue converts yaml maps to map[string]interface{} so they can be marshaled to json
+func NormalizeYAMLValue(val interface{}) interface{} {
+	switch typedVal := val.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(typedVal))
+		for key, itemVal := range typedVal {
+			res[fmt.Sprint(key)] = NormalizeYAMLValue(itemVal)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, 0, len(typedVal))
+		for _, itemVal := range typedVal {
+			res = append(res, NormalizeYAMLValue(itemVal))
+		}
+		return res
+	default:
+		return val
+	}
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectLocalFacts(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Files          map[string]string
+		Executables    map[string]string
+		ExpectedFacts  map[string]interface{}
+		ExpectedErrMsg string
+		ErrFile        string
+	}{
+		{
+			Name: "static yaml and json files",
+			Files: map[string]string{
+				"app.yaml":  "version: 1.2.3\nports:\n  - 80\n  - 443\ndb:\n  host: localhost\n",
+				"team.json": `{"owner": "ops", "oncall": true}`,
+			},
+			ExpectedFacts: map[string]interface{}{
+				"app": map[string]interface{}{
+					"version": "1.2.3",
+					"ports":   []interface{}{80, 443},
+					"db":      map[string]interface{}{"host": "localhost"},
+				},
+				"team": map[string]interface{}{
+					"owner":  "ops",
+					"oncall": true,
+				},
+			},
+		},
+		{
+			Name: "ignored files",
+			Files: map[string]string{
+				".hidden.yaml": "some: value",
+				"README.txt":   "not a fact",
+			},
+			ExpectedFacts: map[string]interface{}{},
+		},
+		{
+			Name: "invalid json",
+			Files: map[string]string{
+				"broken.json": "{",
+			},
+			ExpectedErrMsg: "failed to read facts from '%s': unexpected end of JSON input",
+			ErrFile:        "broken.json",
+		},
+		{
+			Name: "duplicate fact names",
+			Files: map[string]string{
+				"app.json": `{"version": "1"}`,
+				"app.yml":  "version: 2",
+			},
+			ExpectedErrMsg: "duplicate local fact 'app' in '%s'",
+			ErrFile:        "app.yml",
+		},
+		{
+			Name: "executable",
+			Executables: map[string]string{
+				"rack": "#!/bin/sh\necho '{\"row\": 3, \"unit\": \"a1\"}'\n",
+			},
+			ExpectedFacts: map[string]interface{}{
+				"rack": map[string]interface{}{
+					"row":  float64(3),
+					"unit": "a1",
+				},
+			},
+		},
+		{
+			Name: "executable with invalid output",
+			Executables: map[string]string{
+				"rack": "#!/bin/sh\necho 'row=3'\n",
+			},
+			ExpectedErrMsg: "failed to read facts from '%s': invalid json output: invalid character 'r' looking for beginning of value",
+			ErrFile:        "rack",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			if len(tc.Executables) > 0 && runtime.GOOS == "windows" {
+				t.Skip("shell script facts are not supported under windows")
+			}
+
+			factsDir, err := ioutil.TempDir("", "tacoscript-facts")
+			assert.NoError(t, err)
+			defer os.RemoveAll(factsDir)
+
+			for fileName, contents := range tc.Files {
+				assert.NoError(t, ioutil.WriteFile(filepath.Join(factsDir, fileName), []byte(contents), 0600))
+			}
+			for fileName, contents := range tc.Executables {
+				assert.NoError(t, ioutil.WriteFile(filepath.Join(factsDir, fileName), []byte(contents), 0700))
+			}
+
+			actualFacts, err := CollectLocalFacts(factsDir)
+			if tc.ExpectedErrMsg != "" {
+				assert.EqualError(t, err, fmt.Sprintf(tc.ExpectedErrMsg, filepath.Join(factsDir, tc.ErrFile)))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ExpectedFacts, actualFacts)
+		})
+	}
+}
+
+func TestCollectLocalFactsMissingDir(t *testing.T) {
+	actualFacts, err := CollectLocalFacts(filepath.Join(os.TempDir(), "tacoscript-missing-facts-dir"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{}, actualFacts)
+}
+
+func TestOSDataProviderSkipsLocalFacts(t *testing.T) {
+	factsDir, err := ioutil.TempDir("", "tacoscript-facts")
+	assert.NoError(t, err)
+	defer os.RemoveAll(factsDir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(factsDir, "broken.json"), []byte("{"), 0600))
+
+	variables, err := OSDataProvider{LocalFactsDir: factsDir, SkipSystemFacts: true, SkipLocalFacts: true}.GetTemplateVariables()
+	assert.NoError(t, err)
+	assert.NotContains(t, variables, LocalFacts)
+	assert.NotContains(t, variables, Hostname)
+
+	_, err = OSDataProvider{LocalFactsDir: factsDir, SkipSystemFacts: true}.GetTemplateVariables()
+	assert.Error(t, err)
+}
@@ -14,12 +14,50 @@ const (
 	OSName       = "taco_os_name"      // mac os x, ubuntu, centos linux, debian gnu/linux, alpine linux, windows server 2019 standard
 	OSVersion    = "taco_os_version"   // 10.15.7, 20.04.1 LTS (Focal Fossa), 8 (Core), 10 (buster), '', 10.0
 	Architecture = "taco_architecture" // x86_64
+
+	InstalledPackages = "taco_pkgs" // map of installed package name to its version, e.g. nginx: 1.18.0-0ubuntu1
 )
 
 type OSDataProvider struct {
+	LocalFactsDir string
+	// SkipSystemFacts is true if the system facts are collected on demand, see LazySystemFacts
+	SkipSystemFacts bool
+	// SkipLocalFacts is true if the custom facts from LocalFactsDir are collected on demand
+	SkipLocalFacts bool
 }
 
+// GetTemplateVariables gives OS identification variables together with the system facts and
+// the custom facts from LocalFactsDir which are namespaced under the LocalFacts key
 func (odp OSDataProvider) GetTemplateVariables() (map[string]interface{}, error) {
+	variables, err := odp.GetOSVariables()
+	if err != nil {
+		return variables, err
+	}
+
+	if !odp.SkipSystemFacts {
+		facts, err := CollectSystemFacts()
+		if err != nil {
+			return variables, err
+		}
+
+		for factName, factValue := range facts {
+			variables[factName] = factValue
+		}
+	}
+
+	if !odp.SkipLocalFacts {
+		localFacts, err := CollectLocalFacts(odp.LocalFactsDir)
+		if err != nil {
+			return variables, err
+		}
+		variables[LocalFacts] = localFacts
+	}
+
+	return variables, nil
+}
+
+// GetOSVariables gives only the OS identification variables which are cheap to collect
+func (odp OSDataProvider) GetOSVariables() (map[string]interface{}, error) {
 	h, err := sysinfo.Host()
 	if err != nil {
 		return map[string]interface{}{}, err
@@ -9,6 +9,13 @@ import (
 	"strings"
 )
 
+// DefaultLocalFactsDir is the directory where custom facts are read from by default
+const DefaultLocalFactsDir = "/etc/tacoscript/facts.d"
+
+func isExecutableFile(fileInfo os.FileInfo) bool {
+	return fileInfo.Mode().IsRegular() && fileInfo.Mode().Perm()&0111 != 0
+}
+
 func ParseLocationOS(rawLocation string) string {
 	if !strings.HasPrefix(rawLocation, "file:") {
 		return rawLocation
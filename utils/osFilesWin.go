@@ -4,11 +4,24 @@ package utils
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// DefaultLocalFactsDir is the directory where custom facts are read from by default
+var DefaultLocalFactsDir = filepath.Join(os.Getenv("ProgramData"), "tacoscript", "facts.d")
+
+func isExecutableFile(fileInfo os.FileInfo) bool {
+	switch strings.ToLower(filepath.Ext(fileInfo.Name())) {
+	case ".exe", ".bat", ".cmd":
+		return true
+	default:
+		return false
+	}
+}
+
 func ParseLocationOS(rawLocation string) string {
 	if !strings.HasPrefix(rawLocation, "file:") {
 		return rawLocation
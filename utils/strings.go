@@ -13,3 +13,38 @@ func Truncate(data interface{}) string {
 	}
 	return value
 }
+
+// LevenshteinDistance gives the number of single character edits which are needed to change one string to another
+func LevenshteinDistance(a, b string) int {
+	aRunes, bRunes := []rune(a), []rune(b)
+	prevRow := make([]int, len(bRunes)+1)
+	for j := range prevRow {
+		prevRow[j] = j
+	}
+
+	for i := 1; i <= len(aRunes); i++ {
+		curRow := make([]int, len(bRunes)+1)
+		curRow[0] = i
+		for j := 1; j <= len(bRunes); j++ {
+			substitutionCost := 1
+			if aRunes[i-1] == bRunes[j-1] {
+				substitutionCost = 0
+			}
+			curRow[j] = minInt(prevRow[j]+1, curRow[j-1]+1, prevRow[j-1]+substitutionCost)
+		}
+		prevRow = curRow
+	}
+
+	return prevRow[len(bRunes)]
+}
+
+func minInt(first int, rest ...int) int {
+	res := first
+	for _, val := range rest {
+		if val < res {
+			res = val
+		}
+	}
+
+	return res
+}